@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,7 +18,9 @@ import (
 	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/base/log"
 	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/cond"
 	"github.com/jojo-jie/otelgorm"
+	"go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -27,9 +30,10 @@ var (
 	flagEnv = flag.String("env", "", "set exec environment eg: dev,test,prod")
 )
 
+// 默认应用实例只能运行一次，需要运行多个应用时使用 NewInstance 创建
 var (
 	appInstanceOnce    int32
-	errAppInstanceOnce = errors.New("the same app type can only be registered once")
+	errAppInstanceOnce = errors.New("the default app instance can only be run once")
 )
 
 func appInstanceOnceValidate() error {
@@ -40,7 +44,7 @@ func appInstanceOnceValidate() error {
 	return nil
 }
 
-func initApplication(application *stark.Application) error {
+func (a *Instance) initApplication(application *stark.Application) error {
 	// 验证应用数据
 	err := validateApplication(application)
 	if err != nil {
@@ -54,18 +58,33 @@ func initApplication(application *stark.Application) error {
 	initRuntimeEnv(application)
 
 	// 注入应用配置信息
-	injectApplicationConfig(application)
+	a.injectApplicationConfig(application)
+
+	// 注册框架状态
+	a.Object(a.Runtime)
+
+	// 注册日志级别切换信号处理器
+	a.Object(new(logLevelSignalHandler)).Export((*ioc.AppEvent)(nil)).
+		On(cond.OnProperty("logging.debug-signal.enabled", cond.HavingValue("true"), cond.MatchIfMissing()))
 
 	// 服务发现适配器初始化
 	if application.Discovery != nil {
-		err = NewDiscoveryAdapter(application.Discovery).Init()
+		err = NewDiscoveryAdapter(a.App, application.Discovery).Init()
+		if err != nil {
+			return err
+		}
+	}
+
+	// 配置中心适配器初始化
+	if application.ConfigCenter != nil {
+		err = NewConfigCenterAdapter(a.App, application.ConfigCenter).Init()
 		if err != nil {
 			return err
 		}
 	}
 
 	// 安装组件
-	err = setupCommonVars(application)
+	err = a.setupCommonVars(application)
 	if err != nil {
 		return err
 	}
@@ -77,6 +96,12 @@ func initApplication(application *stark.Application) error {
 			return fmt.Errorf("application.SetupVars err: %v", err)
 		}
 	}
+	if application.Setup != nil {
+		err = application.Setup(a.App)
+		if err != nil {
+			return fmt.Errorf("application.Setup err: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -101,9 +126,9 @@ func initRuntimeEnv(application *stark.Application) {
 }
 
 // setupCommonVars setup application global vars.
-func setupCommonVars(application *stark.Application) error {
+func (a *Instance) setupCommonVars(application *stark.Application) error {
 	// 安装数据库组件
-	err := setupDatabase(application)
+	err := a.setupDatabase(application)
 	if err != nil {
 		return err
 	}
@@ -112,7 +137,7 @@ func setupCommonVars(application *stark.Application) error {
 }
 
 // 安装各种数据库组件
-func setupDatabase(application *stark.Application) error {
+func (a *Instance) setupDatabase(application *stark.Application) error {
 	if len(application.DbConns) == 0 {
 		return nil
 	}
@@ -121,12 +146,12 @@ func setupDatabase(application *stark.Application) error {
 	for _, v := range application.DbConns {
 		switch v.Type {
 		case stark.DbTypeMyql:
-			err = setupMysql(application, v)
+			err = a.setupMysql(application, v)
 		case stark.DbTypeRedis:
-			err = setupRedis(v)
+			err = a.setupRedis(v)
 		}
 		if err != nil {
-			return fmt.Errorf("安装数据库组件异常:%+v", err)
+			return fmt.Errorf("安装数据库组件异常:%w", err)
 		}
 	}
 
@@ -134,23 +159,39 @@ func setupDatabase(application *stark.Application) error {
 }
 
 // 安装mysql
-func setupMysql(application *stark.Application, info stark.DbConnInfo) error {
+func (a *Instance) setupMysql(application *stark.Application, info stark.DbConnInfo) error {
 	ctx := context.Background()
 
+	// 启动失败统一在最外层报告，这里只返回错误
+	db, sqlDB, err := a.openMysql(application, info)
+	if err != nil {
+		return &DbConnectError{Info: info, Err: err}
+	}
+
+	a.Object(db).Name(info.Name).Destroy(func(db *gorm.DB) {
+		err = sqlDB.Close()
+		if err != nil {
+			log.Errorf(ctx, "关闭%s数据库连接异常:%+v", stark.DbTypeText[info.Type], err)
+		}
+	})
+	return nil
+}
+
+// 连接mysql并设置连接池和链路追踪
+func (a *Instance) openMysql(application *stark.Application, info stark.DbConnInfo) (*gorm.DB, *sql.DB, error) {
 	gormConf := &gorm.Config{}
 	if application.IsDebug {
 		gormConf.Logger = logger.Default.LogMode(logger.Info)
 	}
+	// gorm.Open 会 Ping 数据库，连接失败时在这里返回
 	db, err := gorm.Open(mysql.Open(info.Url), gormConf)
 	if err != nil {
-		log.Errorf(ctx, "连接%s数据库异常:%+v", stark.DbTypeText[info.Type], err)
-		return err
+		return nil, nil, err
 	}
 	// 设置数据库连接池
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Errorf(ctx, "设置%s数据库连接池异常:%+v", stark.DbTypeText[info.Type], err)
-		return err
+		return nil, nil, fmt.Errorf("设置数据库连接池异常:%w", err)
 	}
 
 	// SetMaxIdleConns 设置空闲连接池中连接的最大数量
@@ -181,26 +222,19 @@ func setupMysql(application *stark.Application, info stark.DbConnInfo) error {
 	}
 	sqlDB.SetConnMaxLifetime(connMaxLifetime)
 
-	if stark.IsEnableTrace {
-		plugin := otelgorm.NewPlugin(otelgorm.WithServiceName("gorm"))
+	if a.Runtime.IsEnableTrace {
+		plugin := otelgorm.NewPlugin(otelgorm.WithServiceName("gorm"), otelgorm.WithTracerProvider(a.Runtime.TracerProvider))
 		err = db.Use(plugin)
 		if err != nil {
-			log.Errorf(ctx, "%s数据库设置链路追踪异常:%+v", stark.DbTypeText[info.Type], err)
-			return err
+			sqlDB.Close()
+			return nil, nil, fmt.Errorf("设置链路追踪异常:%w", err)
 		}
 	}
-
-	ioc.Object(db).Name(info.Name).Destroy(func(db *gorm.DB) {
-		err = sqlDB.Close()
-		if err != nil {
-			log.Errorf(ctx, "关闭%s数据库连接异常:%+v", stark.DbTypeText[info.Type], err)
-		}
-	})
-	return nil
+	return db, sqlDB, nil
 }
 
 // 安装redis
-func setupRedis(info stark.DbConnInfo) error {
+func (a *Instance) setupRedis(info stark.DbConnInfo) error {
 	var username, password string
 	var db int
 	var dialTimeout, readTimeout, writeTimeout, idleTimeout int
@@ -235,10 +269,10 @@ func setupRedis(info stark.DbConnInfo) error {
 		WriteTimeout: time.Duration(writeTimeout) * time.Second,
 		IdleTimeout:  time.Duration(idleTimeout) * time.Second,
 	})
-	if stark.IsEnableTrace {
+	if a.Runtime.IsEnableTrace {
 		client.AddHook(redisotel.TracingHook{})
 	}
-	ioc.Object(client)
+	a.Object(client)
 	return nil
 }
 
@@ -252,42 +286,83 @@ func showAppVersion(app *stark.Application) {
 }
 
 // 配置http服务
-func configHttpServer(config *stark.ServerConfig) error {
-	ctx := context.Background()
-
+func (a *Instance) configHttpServer(config *stark.ServerConfig) error {
 	// 注入多路复用器
-	ioc.Provide(NewServeMux)
+	a.Provide(NewServeMux)
 
-	l, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
-	if err != nil {
-		log.Errorf(ctx, "监听服务端口异常:%+v port:%d", err, config.Port)
-		return err
+	l := a.Listener
+	if l == nil {
+		var err error
+		l, err = net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
+		if err != nil {
+			return fmt.Errorf("监听服务端口%d异常:%w", config.Port, err)
+		}
 	}
-	if config.Port == 0 {
-		config.Port = l.Addr().(*net.TCPAddr).Port
+	if addr, ok := l.Addr().(*net.TCPAddr); ok && config.Port == 0 {
+		config.Port = addr.Port
 	}
-	ioc.Object(l)
+	a.Object(l).Name("httpListener").Export((*net.Listener)(nil))
 
 	server := &http.Server{
 		Handler:      nil,
 		ReadTimeout:  time.Duration(config.ReadTimeout) * time.Millisecond,
 		WriteTimeout: time.Duration(config.WriteTimeout) * time.Millisecond,
 	}
-	ioc.Object(server)
+	a.Object(server)
 
 	// 注入http启动器
-	ioc.Provide(NewHttpStarter).Name("httpStarter").Order(10000)
+	a.Provide(NewHttpStarter).Name("httpStarter").Order(10000)
 	return nil
 }
 
+// 配置管理服务，配置了management.port属性时启用
+func (a *Instance) configManagementServer(config *stark.ServerConfig) {
+	if config.DisablePprof {
+		a.Property("application.pprof.enabled", false)
+	}
+	if m := config.Management; m != nil {
+		a.Property("management.port", m.Port)
+		if m.DisablePprof {
+			a.Property("management.pprof.enabled", false)
+		}
+		if m.Username != "" {
+			a.Property("management.auth.username", m.Username)
+			a.Property("management.auth.password", m.Password)
+		}
+		if len(m.AllowIps) > 0 {
+			a.Property("management.allow-ips", m.AllowIps)
+		}
+	}
+
+	enabled := cond.OnProperty("management.port")
+	a.Provide(NewManagementServer).Name("managementServer").On(enabled)
+	a.Object(new(pprofEndpoint)).Export((*ManagementEndpoint)(nil)).
+		On(cond.OnProperty("management.port").OnProperty("management.pprof.enabled", cond.HavingValue("true"), cond.MatchIfMissing()))
+	a.Object(new(healthEndpoint)).Export((*ManagementEndpoint)(nil)).On(enabled)
+	a.Object(new(metricsEndpoint)).Export((*ManagementEndpoint)(nil)).On(enabled)
+	a.Object(new(beansEndpoint)).Export((*ManagementEndpoint)(nil)).On(enabled)
+	a.Object(new(loggersEndpoint)).Export((*ManagementEndpoint)(nil)).On(enabled)
+	a.Object(new(loggersReloadEndpoint)).Export((*ManagementEndpoint)(nil)).On(enabled)
+}
+
 // 注入应用配置参数
-func injectApplicationConfig(app *stark.Application) {
-	ioc.Property("application.name", app.Name)
-	ioc.Property("application.type", int32(app.Type))
+func (a *Instance) injectApplicationConfig(app *stark.Application) {
+	a.Property("application.name", app.Name)
+	a.Property("application.type", int32(app.Type))
 
 	// 注入链路追踪配置
 	if app.TraceUrl != "" {
-		ioc.Property("trace.url", app.TraceUrl)
-		stark.IsEnableTrace = true
+		a.Property("trace.url", app.TraceUrl)
+		provider, err := newTracerProvider(app.Name, app.TraceUrl)
+		if err != nil {
+			log.Errorf(context.Background(), "应用 %s 初始化链路追踪异常:%+v", app.Name, err)
+			return
+		}
+		a.Runtime.EnableTrace(provider)
+		a.Object(provider).Destroy(func(provider *trace.TracerProvider) {
+			if err := provider.Shutdown(context.Background()); err != nil {
+				log.Errorf(context.Background(), "关闭链路追踪异常:%+v", err)
+			}
+		})
 	}
 }
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/config/consul"
+	"github.com/huazai2008101/stark/config/etcd"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+type ConfigCenterAdapter struct {
+	app      *ioc.App
+	url      string
+	prefix   string
+	format   string
+	strategy stark.DiscoveryStrategy
+}
+
+func NewConfigCenterAdapter(app *ioc.App, conf *stark.ConfigCenterConfig) *ConfigCenterAdapter {
+	return &ConfigCenterAdapter{
+		app:      app,
+		url:      conf.Url,
+		prefix:   conf.Prefix,
+		format:   conf.Format,
+		strategy: conf.Strategy,
+	}
+}
+
+func (s *ConfigCenterAdapter) Init() error {
+	switch s.strategy {
+	case stark.ConsulDiscoveryStrategy:
+		s.injectProperty("config.consul")
+		s.app.AddPropertySource(consul.NewConsulPropertySource())
+	case stark.EtcdDiscoveryStrategy:
+		s.injectProperty("config.etcd")
+		s.app.AddPropertySource(etcd.NewEtcdPropertySource())
+	default:
+		return fmt.Errorf("不支持的配置中心类型:%d", s.strategy)
+	}
+	return nil
+}
+
+func (s *ConfigCenterAdapter) injectProperty(prefix string) {
+	// 注入配置属性
+	if s.url != "" {
+		s.app.Property(prefix+".url", s.url)
+	}
+	if s.prefix != "" {
+		s.app.Property(prefix+".prefix", s.prefix)
+	}
+	if s.format != "" {
+		s.app.Property(prefix+".format", s.format)
+	}
+}
@@ -8,16 +8,18 @@ import (
 )
 
 type DiscoveryAdapter struct {
+	app       *ioc.App
 	url       string
 	namespace string
 	strategy  stark.DiscoveryStrategy
 }
 
-func NewDiscoveryAdapter(conf *stark.DiscoveryConfig) *DiscoveryAdapter {
+func NewDiscoveryAdapter(app *ioc.App, conf *stark.DiscoveryConfig) *DiscoveryAdapter {
 	if conf == nil {
 		conf = &stark.DiscoveryConfig{}
 	}
 	return &DiscoveryAdapter{
+		app:       app,
 		url:       conf.Url,
 		namespace: conf.Namespace,
 		strategy:  conf.Strategy,
@@ -30,9 +32,9 @@ func (s *DiscoveryAdapter) Init() error {
 	}
 	switch stark.DiscoveryStrategy(s.strategy) {
 	case stark.ConsulDiscoveryStrategy:
-		ioc.Provide(consul.NewConsulServiceDiscovery)
+		s.app.Provide(consul.NewConsulServiceDiscovery)
 	default:
-		ioc.Provide(etcd.NewEtcdServiceDiscovery)
+		s.app.Provide(etcd.NewEtcdServiceDiscovery)
 	}
 
 	s.injectProperty()
@@ -41,8 +43,8 @@ func (s *DiscoveryAdapter) Init() error {
 
 func (s *DiscoveryAdapter) injectProperty() {
 	// 注入配置属性
-	ioc.Property("discovery.url", s.url)
+	s.app.Property("discovery.url", s.url)
 	if s.namespace != "" {
-		ioc.Property("discovery.namespace", s.namespace)
+		s.app.Property("discovery.namespace", s.namespace)
 	}
 }
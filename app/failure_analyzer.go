@@ -0,0 +1,39 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+func init() {
+	ioc.RegisterFailureAnalyzer(ioc.FailureAnalyzerFunc(analyzeDbConnect))
+}
+
+// DbConnectError 连接数据库异常
+type DbConnectError struct {
+	Info stark.DbConnInfo
+	Err  error
+}
+
+func (e *DbConnectError) Error() string {
+	return fmt.Sprintf("连接%s数据库%q异常:%v", stark.DbTypeText[e.Info.Type], e.Info.Name, e.Err)
+}
+
+func (e *DbConnectError) Unwrap() error {
+	return e.Err
+}
+
+func analyzeDbConnect(err error) *ioc.FailureAnalysis {
+	var e *DbConnectError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	return &ioc.FailureAnalysis{
+		Kind:        "数据库连接失败",
+		Description: e.Error(),
+		Action:      fmt.Sprintf("检查 DbConns 中 %q 的连接地址、账号密码，以及%s服务是否可以访问", e.Info.Name, stark.DbTypeText[e.Info.Type]),
+	}
+}
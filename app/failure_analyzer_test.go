@@ -0,0 +1,37 @@
+package app_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/app"
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+func TestAnalyzeDbConnect(t *testing.T) {
+	err := &app.DbConnectError{
+		Info: stark.DbConnInfo{Name: "order", Type: stark.DbTypeMyql, Url: "root:pwd@tcp(127.0.0.1:1)/order"},
+		Err:  errors.New("connection refused"),
+	}
+	// 和 setupDatabase 一样包装后仍然可以分析
+	r := ioc.AnalyzeFailure(fmt.Errorf("安装数据库组件异常:%w", err))
+	assert.Equal(t, r.Kind, "数据库连接失败")
+	assert.Equal(t, r.Description, `连接MySQL数据库"order"异常:connection refused`)
+	assert.Equal(t, r.Action, `检查 DbConns 中 "order" 的连接地址、账号密码，以及MySQL服务是否可以访问`)
+}
+
+func TestInstance_DbConnectError(t *testing.T) {
+	info := stark.DbConnInfo{Name: "order", Type: stark.DbTypeMyql, Url: "root:pwd@tcp(127.0.0.1:1)/order?timeout=1s"}
+	err := app.NewInstance().InitWeb(&stark.WebApplication{
+		Application:  &stark.Application{Name: "order", DbConns: []stark.DbConnInfo{info}},
+		ServerConfig: &stark.ServerConfig{Strategy: stark.GinFrameworkStrategy},
+	})
+	// 连接失败时返回 DbConnectError ，可以被启动失败分析识别
+	var e *app.DbConnectError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, e.Info.Name, "order")
+	assert.Equal(t, ioc.AnalyzeFailure(err).Kind, "数据库连接失败")
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // 安装grpc服务
-func setupGrpcServer() {
-	ioc.Object(new(grpcModule.GrpcServer)).Name("grpcServer")
-	ioc.Provide(grpcStarter.NewGrpcStarter).Name("grpcStarter")
+func (a *Instance) setupGrpcServer() {
+	a.Object(new(grpcModule.GrpcServer)).Name("grpcServer")
+	a.Provide(grpcStarter.NewGrpcStarter).Name("grpcStarter").Export((*ioc.SmartLifecycle)(nil))
 }
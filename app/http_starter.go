@@ -14,10 +14,11 @@ import (
 type HttpStarter struct {
 	name      string                     `value:"${application.name}"`
 	port      int                        `value:"${application.port}"`
-	listener  *net.TCPListener           `autowire:""`
+	listener  net.Listener               `autowire:"httpListener"`
 	server    *http.Server               `autowire:""`
 	mux       *ServeMux                  `autowire:""`
 	discovery discovery.ServiceDiscovery `autowire:"?"`
+	runtime   *stark.Runtime             `autowire:""`
 }
 
 func NewHttpStarter() ioc.AppEvent {
@@ -28,13 +29,13 @@ func (s *HttpStarter) OnAppStart(ctx ioc.Context) {
 	s.mux.Init()
 	s.server.Handler = s.mux
 	log.Infof(ctx.Context(), "%s 正在启动服务 端口号:%d", s.name, s.port)
-	ioc.Go(func(ctx context.Context) {
+	ctx.Go(func(ctx context.Context) {
 		s.server.Serve(s.listener)
 	})
 
 	// 如果有服务发现机制则进行注册服务
 	if s.discovery != nil {
-		stark.DiscoverySchemeUrl = s.discovery.SchemeUrl()
+		s.runtime.SetDiscoverySchemeUrl(s.discovery.SchemeUrl())
 		err := s.discovery.Register()
 		if err != nil {
 			log.Errorf(ctx.Context(), "%s 注册服务异常:%+v", s.name, err)
@@ -43,7 +44,11 @@ func (s *HttpStarter) OnAppStart(ctx ioc.Context) {
 	}
 }
 
+// OnAppStop 优雅停止http服务，ctx 结束时强制关闭还没有处理完成的连接
 func (s *HttpStarter) OnAppStop(ctx context.Context) {
-	s.server.Close()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Warnf(ctx, "%s 停止http服务超时，强制关闭:%v", s.name, err)
+		s.server.Close()
+	}
 	s.listener.Close()
 }
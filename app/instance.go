@@ -0,0 +1,37 @@
+package app
+
+import (
+	"net"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+// Instance 应用实例，持有应用自己的 ioc.App 和框架状态，同一进程中可以创建多个实例
+// 分别运行，例如集成测试或者多租户网关。日志配置是进程级的，多个实例共用同一套日志
+// 输出，各个实例的 logging. 属性应当相同或者只在一个实例上配置。
+type Instance struct {
+	*ioc.App
+	// 框架状态，会注册为应用的bean
+	Runtime *stark.Runtime
+	// http和grpc服务的监听器，为空时监听 ServerConfig.Port 端口，测试时可以使用
+	// ioctest 的内存监听器
+	Listener net.Listener
+}
+
+// NewInstance 创建一个独立的应用实例
+func NewInstance() *Instance {
+	return &Instance{
+		App:     ioc.NewApp(),
+		Runtime: &stark.Runtime{},
+	}
+}
+
+// DefaultInstance 返回使用 ioc 包级 App 和 stark.DefaultRuntime 的默认应用实例，
+// 通过 ioc 包级函数注册的组件都属于该实例。
+func DefaultInstance() *Instance {
+	return &Instance{
+		App:     ioc.DefaultApp(),
+		Runtime: stark.DefaultRuntime,
+	}
+}
@@ -0,0 +1,96 @@
+package app_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/app"
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/ioctest"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// pingRouter 在应用名称的路由分组下注册 /ping
+type pingRouter struct {
+	Group *gin.RouterGroup `autowire:""`
+	Reply string           `value:"${ping.reply}"`
+}
+
+func (r *pingRouter) OnInit(ctx ioc.Context) error {
+	r.Group.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, r.Reply)
+	})
+	return nil
+}
+
+// startWeb 在内存监听器上启动 stark web 应用
+func startWeb(t *testing.T, name string) *ioctest.Listener {
+	inst := app.NewInstance()
+	a := ioctest.From(t, inst.App)
+	l := a.Listen()
+	inst.Listener = l
+	err := inst.InitWeb(&stark.WebApplication{
+		Application: &stark.Application{
+			Name: name,
+			Setup: func(app *ioc.App) error {
+				app.Property("ping.reply", "pong from "+name)
+				app.Object(new(pingRouter))
+				return nil
+			},
+		},
+		ServerConfig: &stark.ServerConfig{Strategy: stark.GinFrameworkStrategy},
+	})
+	assert.Nil(t, err)
+	a.Start()
+	return l
+}
+
+func get(t *testing.T, l *ioctest.Listener, url string) string {
+	r, err := l.Client().Get(url)
+	assert.Nil(t, err)
+	defer r.Body.Close()
+	b, err := ioutil.ReadAll(r.Body)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestInstance_InMemory(t *testing.T) {
+	// 同一个测试程序中运行两个应用
+	order := startWeb(t, "order")
+	stock := startWeb(t, "stock")
+
+	assert.Equal(t, get(t, order, "http://stark/order/ping"), "pong from order")
+	assert.Equal(t, get(t, stock, "http://stark/stock/ping"), "pong from stock")
+
+	// grpc 请求通过同一个监听器分发
+	resp, err := grpc_health_v1.NewHealthClient(order.DialGRPC()).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+}
+
+func TestInstance_TraceProvider(t *testing.T) {
+	inst := app.NewInstance()
+	a := ioctest.From(t, inst.App)
+	inst.Listener = a.Listen()
+	err := inst.InitWeb(&stark.WebApplication{
+		Application: &stark.Application{
+			Name:     "order",
+			TraceUrl: "http://127.0.0.1:14268/api/traces",
+		},
+		ServerConfig: &stark.ServerConfig{Strategy: stark.GinFrameworkStrategy},
+	})
+	assert.Nil(t, err)
+	a.Start()
+
+	// 链路追踪只对当前实例生效，不修改默认实例和 otel 的全局设置
+	assert.True(t, inst.Runtime.IsEnableTrace)
+	assert.True(t, inst.Runtime.TracerProvider != nil)
+	assert.True(t, otel.GetTracerProvider() != inst.Runtime.TracerProvider)
+	assert.False(t, stark.IsEnableTrace)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+// 日志级别信号处理器，收到切换信号(SIGUSR1)时根日志在DEBUG级别和原级别之间来回切换
+type logLevelSignalHandler struct {
+	mutex   sync.Mutex
+	origin  log.Level
+	toggled bool
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+func (h *logLevelSignalHandler) OnAppStart(ctx ioc.Context) {
+	if len(debugToggleSignals) == 0 {
+		return
+	}
+	h.signals = make(chan os.Signal, 1)
+	h.done = make(chan struct{})
+	signal.Notify(h.signals, debugToggleSignals...)
+	signals, done := h.signals, h.done
+	ctx.Go(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-signals:
+				h.toggle(ctx)
+			}
+		}
+	})
+}
+
+// OnAppStop 停止接收切换信号，结束信号处理协程
+func (h *logLevelSignalHandler) OnAppStop(ctx context.Context) {
+	if h.signals == nil {
+		return
+	}
+	signal.Stop(h.signals)
+	close(h.done)
+}
+
+func (h *logLevelSignalHandler) toggle(ctx context.Context) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	root := log.GetLogger(log.RootLoggerName)
+	if h.toggled {
+		root.SetLevel(h.origin)
+		h.toggled = false
+		log.Infof(ctx, "收到切换信号 根日志级别恢复为 %s", h.origin)
+		return
+	}
+	h.origin = root.Level()
+	h.toggled = true
+	root.SetLevel(log.DebugLevel)
+	log.Infof(ctx, "收到切换信号 根日志级别切换为 %s", log.DebugLevel)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"os"
+	"syscall"
+)
+
+// 切换根日志DEBUG级别的信号
+var debugToggleSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,57 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+// waitLevel 等待根日志级别变为 level ，超时后结束测试
+func waitLevel(t *testing.T, level log.Level) {
+	t.Helper()
+	root := log.GetLogger(log.RootLoggerName)
+	deadline := time.Now().Add(5 * time.Second)
+	for root.Level() != level {
+		if time.Now().After(deadline) {
+			t.Fatalf("root level is %s, want %s", root.Level(), level)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLogLevelSignalHandler(t *testing.T) {
+	root := log.GetLogger(log.RootLoggerName)
+	origin := root.Level()
+	defer root.SetLevel(origin)
+	root.SetLevel(log.WarnLevel)
+
+	// 测试结束后进程仍然需要忽略 SIGUSR1 ，避免信号处理停止后进程被信号终止
+	ignore := make(chan os.Signal, 1)
+	signal.Notify(ignore, syscall.SIGUSR1)
+	defer signal.Stop(ignore)
+
+	a := ioc.NewApp()
+	a.Object(new(logLevelSignalHandler)).Export((*ioc.AppEvent)(nil))
+	assert.Nil(t, a.Start())
+
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	waitLevel(t, log.DebugLevel)
+
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	waitLevel(t, log.WarnLevel)
+
+	// 停止后不再切换日志级别
+	a.Stop()
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	<-ignore
+	assert.Equal(t, root.Level(), log.WarnLevel)
+}
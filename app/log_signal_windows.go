@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package app
+
+import "os"
+
+// windows不支持SIGUSR1信号
+var debugToggleSignals []os.Signal
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+// ManagementEndpoint 管理端点接口，实现该接口并导出的bean会挂载到管理服务上
+type ManagementEndpoint interface {
+	// 端点路径，以/开头，以/结尾时匹配该路径下的所有请求
+	Path() string
+	http.Handler
+}
+
+// ManagementServer 管理服务，使用独立端口提供pprof、健康检查、指标等运维端点，
+// 避免在业务端口上暴露这些能力
+type ManagementServer struct {
+	name      string               `value:"${application.name}"`
+	port      int                  `value:"${management.port}"`
+	username  string               `value:"${management.auth.username:=}"`
+	password  string               `value:"${management.auth.password:=}"`
+	allowIps  []string             `value:"${management.allow-ips:=}"`
+	endpoints []ManagementEndpoint `autowire:"*?"`
+	allowNets []*net.IPNet
+	listener  net.Listener
+	server    *http.Server
+}
+
+func NewManagementServer() ioc.AppEvent {
+	return &ManagementServer{}
+}
+
+func (s *ManagementServer) OnInit(ctx ioc.Context) error {
+	for _, v := range s.allowIps {
+		ipNet, err := parseAllowIp(v)
+		if err != nil {
+			return fmt.Errorf("%s 管理服务IP白名单配置异常:%w", s.name, err)
+		}
+		s.allowNets = append(s.allowNets, ipNet)
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("%s 监听管理端口%d异常:%w", s.name, s.port, err)
+	}
+	s.listener = l
+
+	mux := http.NewServeMux()
+	for _, v := range s.endpoints {
+		mux.Handle(v.Path(), v)
+	}
+	s.server = &http.Server{Handler: s.protect(mux)}
+	return nil
+}
+
+func (s *ManagementServer) OnAppStart(ctx ioc.Context) {
+	log.Infof(ctx.Context(), "%s 正在启动管理服务 端口号:%d", s.name, s.port)
+	ctx.Go(func(ctx context.Context) {
+		s.server.Serve(s.listener)
+	})
+}
+
+// OnAppStop 优雅停止管理服务，ctx 结束时强制关闭还没有处理完成的连接
+func (s *ManagementServer) OnAppStop(ctx context.Context) {
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Warnf(ctx, "%s 停止管理服务超时，强制关闭:%v", s.name, err)
+		s.server.Close()
+	}
+	s.listener.Close()
+}
+
+// protect 对管理端点进行IP白名单和basic认证保护
+func (s *ManagementServer) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAllowed(r) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("403 forbidden!"))
+			return
+		}
+		if s.username != "" {
+			username, password, ok := r.BasicAuth()
+			if !ok || !secureEqual(username, s.username) || !secureEqual(password, s.password) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="management"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("401 unauthorized!"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAllowed 判断请求来源是否在IP白名单内，白名单为空时不限制
+func (s *ManagementServer) isAllowed(r *http.Request) bool {
+	if len(s.allowNets) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, v := range s.allowNets {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAllowIp 解析IP或CIDR格式的白名单配置
+func parseAllowIp(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip %q", s)
+	}
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/base/cast"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+var publishMetricsOnce sync.Once
+
+// 输出json格式的响应
+func writeJson(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// pprof端点
+type pprofEndpoint struct{}
+
+func (e *pprofEndpoint) Path() string {
+	return "/debug/pprof/"
+}
+
+func (e *pprofEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	servePprof(w, r)
+}
+
+// 健康检查端点
+type healthEndpoint struct {
+	name string `value:"${application.name}"`
+}
+
+func (e *healthEndpoint) Path() string {
+	return "/health"
+}
+
+func (e *healthEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"name":   e.name,
+		"status": "UP",
+	})
+}
+
+// 运行指标端点，基于expvar输出内存、goroutine等运行时指标
+type metricsEndpoint struct{}
+
+func (e *metricsEndpoint) Path() string {
+	return "/metrics"
+}
+
+func (e *metricsEndpoint) OnInit(ctx ioc.Context) error {
+	publishMetricsOnce.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() interface{} {
+			return runtime.NumGoroutine()
+		}))
+	})
+	return nil
+}
+
+func (e *metricsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	expvar.Handler().ServeHTTP(w, r)
+}
+
+// bean列表端点，返回bean的元数据以及依赖关系，format=dot时返回Graphviz DOT格式的依赖关系图
+type beansEndpoint struct {
+	ctx ioc.Context `autowire:""`
+}
+
+func (e *beansEndpoint) Path() string {
+	return "/beans"
+}
+
+func (e *beansEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	graph := e.ctx.BeanGraph()
+	if r.FormValue("format") == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(graph.DOT()))
+		return
+	}
+	writeJson(w, http.StatusOK, graph.Beans)
+}
+
+// 日志级别端点，GET查询所有日志级别，POST修改指定日志的级别，携带ttl参数时到期后自动恢复
+type loggersEndpoint struct{}
+
+func (e *loggersEndpoint) Path() string {
+	return "/loggers"
+}
+
+func (e *loggersEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		type loggerInfo struct {
+			Name     string `json:"name"`
+			Level    string `json:"level"`
+			RevertAt string `json:"revertAt,omitempty"`
+		}
+		loggers := log.Loggers()
+		list := make([]loggerInfo, 0, len(loggers))
+		for _, l := range loggers {
+			info := loggerInfo{Name: l.Name(), Level: l.Level().String()}
+			if t := l.RevertAt(); !t.IsZero() {
+				info.RevertAt = t.Format(stark.ResponseTimeLayout)
+			}
+			list = append(list, info)
+		}
+		writeJson(w, http.StatusOK, list)
+	case http.MethodPost, http.MethodPut:
+		name := r.FormValue("name")
+		if name == "" {
+			name = log.RootLoggerName
+		}
+		level := log.StringToLevel(r.FormValue("level"))
+		if level == log.NoneLevel {
+			writeJson(w, http.StatusBadRequest, map[string]interface{}{
+				"message": "日志级别错误",
+			})
+			return
+		}
+		var ttl time.Duration
+		if s := r.FormValue("ttl"); s != "" {
+			var err error
+			ttl, err = cast.ToDurationE(s)
+			if err != nil || ttl < 0 {
+				writeJson(w, http.StatusBadRequest, map[string]interface{}{
+					"message": "ttl格式错误",
+				})
+				return
+			}
+		}
+		log.GetLogger(name).SetLevelWithTTL(level, ttl)
+		log.Infof(r.Context(), "日志 %s 级别已修改为 %s ttl:%v", name, level, ttl)
+		writeJson(w, http.StatusOK, map[string]interface{}{
+			"name":  name,
+			"level": level.String(),
+			"ttl":   ttl.String(),
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// 日志配置重新加载端点，从logging.config指定的xml文件重新加载日志配置
+type loggersReloadEndpoint struct {
+	configFile string `value:"${logging.config:=}"`
+}
+
+func (e *loggersReloadEndpoint) Path() string {
+	return "/loggers/reload"
+}
+
+func (e *loggersReloadEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if e.configFile == "" {
+		writeJson(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "未配置logging.config",
+		})
+		return
+	}
+	err := log.LoadFile(e.configFile)
+	if err != nil {
+		log.Errorf(r.Context(), "重新加载日志配置异常:%+v file:%s", err, e.configFile)
+		writeJson(w, http.StatusInternalServerError, map[string]interface{}{
+			"message": err.Error(),
+		})
+		return
+	}
+	log.Infof(r.Context(), "日志配置已重新加载 file:%s", e.configFile)
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"file": e.configFile,
+	})
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/ioctest"
+)
+
+func TestParseAllowIp(t *testing.T) {
+
+	ipNet, err := parseAllowIp(" 127.0.0.1 ")
+	assert.Nil(t, err)
+	assert.Equal(t, ipNet.String(), "127.0.0.1/32")
+
+	ipNet, err = parseAllowIp("::1")
+	assert.Nil(t, err)
+	assert.Equal(t, ipNet.String(), "::1/128")
+
+	ipNet, err = parseAllowIp("10.1.2.3/8")
+	assert.Nil(t, err)
+	assert.Equal(t, ipNet.String(), "10.0.0.0/8")
+
+	_, err = parseAllowIp("10.0.0")
+	assert.Error(t, err, `invalid ip "10.0.0"`)
+
+	_, err = parseAllowIp("10.0.0.0/33")
+	assert.Error(t, err, "invalid CIDR address")
+}
+
+func TestManagementServer_Protect(t *testing.T) {
+
+	newServer := func(username, password string, allowIps ...string) http.Handler {
+		s := &ManagementServer{username: username, password: password}
+		for _, v := range allowIps {
+			ipNet, err := parseAllowIp(v)
+			assert.Nil(t, err)
+			s.allowNets = append(s.allowNets, ipNet)
+		}
+		return s.protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+	}
+
+	serve := func(h http.Handler, remoteAddr string, auth ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/health", nil)
+		r.RemoteAddr = remoteAddr
+		if len(auth) > 0 {
+			r.SetBasicAuth(auth[0], auth[1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("no protect", func(t *testing.T) {
+		w := serve(newServer("", ""), "192.168.1.1:1234")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), "ok")
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		h := newServer("admin", "123456")
+		w := serve(h, "127.0.0.1:1234")
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+		assert.Equal(t, w.Header().Get("WWW-Authenticate"), `Basic realm="management"`)
+		w = serve(h, "127.0.0.1:1234", "admin", "654321")
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+		w = serve(h, "127.0.0.1:1234", "root", "123456")
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+		w = serve(h, "127.0.0.1:1234", "admin", "123456")
+		assert.Equal(t, w.Code, http.StatusOK)
+	})
+
+	t.Run("allow ips", func(t *testing.T) {
+		h := newServer("", "", "127.0.0.1", "10.0.0.0/8", "::1")
+		assert.Equal(t, serve(h, "127.0.0.1:1234").Code, http.StatusOK)
+		assert.Equal(t, serve(h, "127.0.0.2:1234").Code, http.StatusForbidden)
+		assert.Equal(t, serve(h, "10.20.30.40:1234").Code, http.StatusOK)
+		assert.Equal(t, serve(h, "11.0.0.1:1234").Code, http.StatusForbidden)
+		assert.Equal(t, serve(h, "[::1]:1234").Code, http.StatusOK)
+		assert.Equal(t, serve(h, "10.0.0.1").Code, http.StatusOK)
+		assert.Equal(t, serve(h, "unknown").Code, http.StatusForbidden)
+	})
+
+	t.Run("allow ips and basic auth", func(t *testing.T) {
+		h := newServer("admin", "123456", "10.0.0.0/8")
+		// 白名单之外的请求即使认证正确也拒绝
+		assert.Equal(t, serve(h, "11.0.0.1:1234", "admin", "123456").Code, http.StatusForbidden)
+		assert.Equal(t, serve(h, "10.0.0.1:1234").Code, http.StatusUnauthorized)
+		assert.Equal(t, serve(h, "10.0.0.1:1234", "admin", "123456").Code, http.StatusOK)
+	})
+}
+
+// startManagement 启动只包含管理服务的应用，返回管理服务的 http.Handler
+func startManagement(t *testing.T, config *stark.ManagementConfig) http.Handler {
+	inst := NewInstance()
+	a := ioctest.From(t, inst.App)
+	a.Property("application.name", "management-test")
+	inst.configManagementServer(&stark.ServerConfig{Management: config})
+	a.Start()
+	var e ioc.AppEvent
+	a.Get(&e, "managementServer")
+	return e.(*ManagementServer).server.Handler
+}
+
+func get(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestManagementServer_Endpoints(t *testing.T) {
+
+	h := startManagement(t, &stark.ManagementConfig{Port: 0})
+
+	t.Run("health", func(t *testing.T) {
+		w := get(h, http.MethodGet, "/health")
+		assert.Equal(t, w.Code, http.StatusOK)
+		var m map[string]interface{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+		assert.Equal(t, m, map[string]interface{}{"name": "management-test", "status": "UP"})
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		// 配置了管理端口时默认开启pprof
+		w := get(h, http.MethodGet, "/debug/pprof/")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.True(t, strings.Contains(w.Body.String(), "goroutine"))
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		w := get(h, http.MethodGet, "/metrics")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.True(t, strings.Contains(w.Body.String(), `"goroutines"`))
+	})
+
+	t.Run("beans", func(t *testing.T) {
+		w := get(h, http.MethodGet, "/beans")
+		assert.Equal(t, w.Code, http.StatusOK)
+		var beans []map[string]interface{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &beans))
+		var ids []string
+		for _, b := range beans {
+			ids = append(ids, b["id"].(string))
+		}
+		assert.True(t, strings.Contains(strings.Join(ids, ","), "managementServer"))
+
+		w = get(h, http.MethodGet, "/beans?format=dot")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Header().Get("Content-Type"), "text/vnd.graphviz; charset=utf-8")
+		assert.True(t, strings.HasPrefix(w.Body.String(), "digraph"))
+	})
+
+	t.Run("loggers", func(t *testing.T) {
+		name := "management-test-logger"
+		l := log.GetLogger(name)
+		level := l.Level()
+		defer l.SetLevel(level)
+
+		w := get(h, http.MethodPost, "/loggers?"+url.Values{"name": {name}, "level": {"debug"}, "ttl": {"1h"}}.Encode())
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, l.Level(), log.DebugLevel)
+		assert.False(t, l.RevertAt().IsZero())
+
+		w = get(h, http.MethodGet, "/loggers")
+		assert.Equal(t, w.Code, http.StatusOK)
+		var list []map[string]interface{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+		found := false
+		for _, v := range list {
+			if v["name"] == name {
+				found = true
+				assert.Equal(t, v["level"], log.DebugLevel.String())
+				assert.True(t, v["revertAt"] != nil)
+			}
+		}
+		assert.True(t, found)
+
+		w = get(h, http.MethodPost, "/loggers?name="+name+"&level=unknown")
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		w = get(h, http.MethodPost, "/loggers?name="+name+"&level=info&ttl=-1s")
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		w = get(h, http.MethodDelete, "/loggers")
+		assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+	})
+
+	t.Run("loggers reload", func(t *testing.T) {
+		w := get(h, http.MethodGet, "/loggers/reload")
+		assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+		w = get(h, http.MethodPost, "/loggers/reload")
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	})
+}
+
+func TestManagementServer_DisablePprof(t *testing.T) {
+	h := startManagement(t, &stark.ManagementConfig{Port: 0, DisablePprof: true})
+	assert.Equal(t, get(h, http.MethodGet, "/debug/pprof/").Code, http.StatusNotFound)
+	assert.Equal(t, get(h, http.MethodGet, "/health").Code, http.StatusOK)
+}
@@ -23,6 +23,8 @@ type ServeMux struct {
 	gin     *gin.Engine            `autowire:"?"`
 	grpc    *grpcModule.GrpcServer `autowire:"?"`
 	handler http.Handler
+	// 是否在服务端口上启用pprof
+	enablePprof bool `value:"${application.pprof.enabled:=true}"`
 }
 
 func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +59,10 @@ func (s *ServeMux) Init() {
 		})
 	}
 
+	if !s.enablePprof {
+		return
+	}
+
 	originHandler := s.handler
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasPrefix(r.RequestURI, s.rootPath()+"/debug/pprof/") {
@@ -75,6 +81,11 @@ func (s *ServeMux) rootPath() string {
 func (s *ServeMux) pprofHandle(w http.ResponseWriter, r *http.Request) {
 	r.URL.Path = strings.TrimPrefix(r.URL.Path, s.rootPath())
 	r.RequestURI = strings.TrimPrefix(r.RequestURI, s.rootPath())
+	servePprof(w, r)
+}
+
+// servePprof 根据请求路径分发到对应的pprof处理函数
+func servePprof(w http.ResponseWriter, r *http.Request) {
 	uri := r.URL.Path
 	if uri == "/debug/pprof/cmdline" {
 		pprof.Cmdline(w, r)
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 
 	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/base/log"
@@ -9,7 +10,7 @@ import (
 	grpcModule "github.com/huazai2008101/stark/module/grpc"
 )
 
-// RunWebApplication runs http and grpc application.
+// RunWebApplication runs http and grpc application with the default instance.
 func RunWebApplication(application *stark.WebApplication) {
 	ctx := context.Background()
 
@@ -23,65 +24,73 @@ func RunWebApplication(application *stark.WebApplication) {
 		return
 	}
 
-	application.Type = stark.AppTypeWeb
-	stark.WebInstance = application
+	// 启动失败已经由 RunWeb 报告
+	_ = DefaultInstance().RunWeb(application)
+}
 
-	err = runWeb(application)
+// RunWeb runs http and grpc application, blocks until the application exits.
+func (a *Instance) RunWeb(application *stark.WebApplication) error {
+	err := a.InitWeb(application)
 	if err != nil {
-		log.Errorf(ctx, "运行%s服务异常:%+v", stark.AppTypeMap[application.Type], err)
+		// 启动失败时打印分析报告，Run 的失败由 ioc.App 自己报告
+		ioc.ReportFailure(context.Background(), err)
+		return err
 	}
+	return a.Run()
 }
 
-// runWeb runs http and grpc application.
-func runWeb(app *stark.WebApplication) error {
-	var err error
+// InitWeb 在应用实例上注册http和grpc应用的组件，之后可以调用 Run 或者 Start 启动应用
+func (a *Instance) InitWeb(app *stark.WebApplication) error {
+	if app == nil || app.Application == nil {
+		return errors.New("webApplication is nil or application is nil")
+	}
+	app.Type = stark.AppTypeWeb
+	a.Runtime.SetWebInstance(app)
 
 	// 1. init application
-	err = initApplication(app.Application)
+	err := a.initApplication(app.Application)
 	if err != nil {
 		return err
 	}
 
 	// 2 init http and grpc vars
-	err = setupWebVars(app)
+	err = a.setupWebVars(app)
 	if err != nil {
 		return err
 	}
 
 	// 配置http服务
-	err = configHttpServer(app.ServerConfig)
+	err = a.configHttpServer(app.ServerConfig)
 	if err != nil {
 		return err
 	}
 
+	// 配置管理服务
+	a.configManagementServer(app.ServerConfig)
+
 	// 注入http和grpc配置参数
-	injectWebConfig(app)
+	a.injectWebConfig(app)
 
 	// 初始化框架适配器
-	err = NewWebFrameworkAdapter(app.ServerConfig).Init()
-	if err != nil {
-		return err
-	}
-
-	return ioc.Run()
+	return NewWebFrameworkAdapter(a.App, app.ServerConfig).Init()
 }
 
 // 注入web配置参数
-func injectWebConfig(app *stark.WebApplication) {
-	ioc.Property("application.port", app.Port)
-	ioc.Property("application.framework-strategy", int32(app.Strategy))
+func (a *Instance) injectWebConfig(app *stark.WebApplication) {
+	a.Property("application.port", app.Port)
+	a.Property("application.framework-strategy", int32(app.Strategy))
 }
 
 // setupWebVars ...
-func setupWebVars(app *stark.WebApplication) error {
+func (a *Instance) setupWebVars(app *stark.WebApplication) error {
 	serverOptions := &grpcModule.ServerOptions{}
 	serverOptions.Options = append(serverOptions.Options, app.GrpcServerOptions...)
 
 	if len(serverOptions.Options) > 0 {
-		ioc.Object(serverOptions)
+		a.Object(serverOptions)
 	}
 
 	// 安装grpc服务
-	setupGrpcServer()
+	a.setupGrpcServer()
 	return nil
 }
@@ -11,17 +11,19 @@ import (
 )
 
 type WebFrameworkAdapter struct {
+	app           *ioc.App
 	strategy      stark.FrameworkStrategy
 	enableSwagger bool
 }
 
-func NewWebFrameworkAdapter(conf *stark.ServerConfig) *WebFrameworkAdapter {
+func NewWebFrameworkAdapter(app *ioc.App, conf *stark.ServerConfig) *WebFrameworkAdapter {
 	if conf == nil {
 		conf = &stark.ServerConfig{
 			Strategy: stark.GinFrameworkStrategy,
 		}
 	}
 	return &WebFrameworkAdapter{
+		app:           app,
 		strategy:      conf.Strategy,
 		enableSwagger: conf.EnableSwagger,
 	}
@@ -39,28 +41,28 @@ func (s *WebFrameworkAdapter) Init() error {
 
 func (s *WebFrameworkAdapter) initGin() {
 	engine := gin.New()
-	ioc.Object(engine)
-	ioc.OnProperty("application.name", func(val string) {
-		ioc.Object(engine.Group(val))
+	s.app.Object(engine)
+	s.app.OnProperty("application.name", func(val string) {
+		s.app.Object(engine.Group(val))
 	})
-	ioc.Provide(ginStarter.NewGinStarter).Name("ginStarter")
+	s.app.Provide(ginStarter.NewGinStarter).Name("ginStarter")
 
 	// 判断是否启用swagger文档功能
 	if s.enableSwagger {
-		ioc.Object(new(ginStarter.SwaggerRouter)).Export((*web.RouteInitializer)(nil))
+		s.app.Object(new(ginStarter.SwaggerRouter)).Export((*web.RouteInitializer)(nil))
 	}
 }
 
 func (s *WebFrameworkAdapter) initEcho() {
 	engine := echo.New()
-	ioc.Object(engine)
-	ioc.OnProperty("application.name", func(val string) {
-		ioc.Object(engine.Group(val))
+	s.app.Object(engine)
+	s.app.OnProperty("application.name", func(val string) {
+		s.app.Object(engine.Group(val))
 	})
-	ioc.Provide(echoStarter.NewEchoStarter).Name("echoStarter")
+	s.app.Provide(echoStarter.NewEchoStarter).Name("echoStarter")
 
 	// 判断是否启用swagger文档功能
 	if s.enableSwagger {
-		ioc.Object(new(echoStarter.SwaggerRouter)).Export((*web.RouteInitializer)(nil))
+		s.app.Object(new(echoStarter.SwaggerRouter)).Export((*web.RouteInitializer)(nil))
 	}
 }
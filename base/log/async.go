@@ -0,0 +1,268 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	RegisterAppenderFactory("AsyncAppender", new(AsyncAppenderFactory))
+}
+
+// 缓冲区满时的处理策略。
+const (
+	AsyncPolicyBlock          = "block"          // 阻塞调用方直到缓冲区有空位
+	AsyncPolicyDropOldest     = "dropOldest"     // 丢弃最早的日志
+	AsyncPolicyDropBelowLevel = "dropBelowLevel" // 丢弃不高于 dropLevel 的日志，其他日志阻塞
+)
+
+type AsyncAppenderFactory struct{}
+
+func (f *AsyncAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(AsyncAppenderConfig)
+}
+
+func (f *AsyncAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewAsyncAppender(config.(*AsyncAppenderConfig))
+}
+
+// AsyncAppenderConfig 异步输出配置，引用的 Appender 需要先于 AsyncAppender 声明，例如：
+//
+//	<AsyncAppender name="async" bufferSize="4096" policy="dropBelowLevel" dropLevel="info">
+//	    <AppenderRef ref="file"/>
+//	</AsyncAppender>
+type AsyncAppenderConfig struct {
+	AppenderFilters
+	Name string `xml:"name,attr"`
+
+	// BufferSize 缓冲区能容纳的日志条数，默认为 1024 。
+	BufferSize int `xml:"bufferSize,attr"`
+
+	// Policy 缓冲区满时的处理策略，默认为 block 。
+	Policy string `xml:"policy,attr"`
+
+	// DropLevel 策略为 dropBelowLevel 时可以丢弃的最高级别，默认为 info 。
+	DropLevel string `xml:"dropLevel,attr"`
+
+	AppenderRefs []struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"AppenderRef"`
+
+	appenders []Appender
+}
+
+func (c *AsyncAppenderConfig) GetName() string {
+	return c.Name
+}
+
+func (c *AsyncAppenderConfig) GetAppenderRefs() []string {
+	var refs []string
+	for _, ref := range c.AppenderRefs {
+		refs = append(refs, ref.Ref)
+	}
+	return refs
+}
+
+func (c *AsyncAppenderConfig) SetAppenders(appenders []Appender) {
+	c.appenders = appenders
+}
+
+// AsyncAppender 将日志放入有界的环形缓冲区，由后台 goroutine 写入引用的 Appender ，
+// 避免慢速的输出目标增加调用方的耗时。
+type AsyncAppender struct {
+	config    *AsyncAppenderConfig
+	appenders []Appender
+	dropLevel Level
+
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	buf    []*Message
+	head   int
+	count  int
+	busy   bool // 后台 goroutine 正在输出日志
+	closed bool
+	done   chan struct{}
+
+	dropped    uint64 // 累计丢弃的日志条数
+	unreported uint64 // 尚未输出提示的丢弃条数
+}
+
+func NewAsyncAppender(config *AsyncAppenderConfig, appenders ...Appender) (*AsyncAppender, error) {
+	if len(appenders) == 0 {
+		appenders = config.appenders
+	}
+	if len(appenders) == 0 {
+		return nil, fmt.Errorf("no appender ref for AsyncAppender `%s`", config.Name)
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1024
+	}
+	switch {
+	case config.Policy == "", strings.EqualFold(config.Policy, AsyncPolicyBlock):
+		config.Policy = AsyncPolicyBlock
+	case strings.EqualFold(config.Policy, AsyncPolicyDropOldest):
+		config.Policy = AsyncPolicyDropOldest
+	case strings.EqualFold(config.Policy, AsyncPolicyDropBelowLevel):
+		config.Policy = AsyncPolicyDropBelowLevel
+	default:
+		return nil, fmt.Errorf("error policy `%s` for AsyncAppender `%s`", config.Policy, config.Name)
+	}
+	if config.DropLevel == "" {
+		config.DropLevel = InfoLevel.String()
+	}
+	dropLevel := StringToLevel(config.DropLevel)
+	if dropLevel == NoneLevel {
+		return nil, fmt.Errorf("error dropLevel `%s` for AsyncAppender `%s`", config.DropLevel, config.Name)
+	}
+	a := &AsyncAppender{
+		config:    config,
+		appenders: appenders,
+		dropLevel: dropLevel,
+		buf:       make([]*Message, config.BufferSize),
+		done:      make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mutex)
+	go a.run()
+	return a, nil
+}
+
+// Dropped 返回累计丢弃的日志条数。
+func (a *AsyncAppender) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Buffered 返回缓冲区中等待输出的日志条数。
+func (a *AsyncAppender) Buffered() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.count
+}
+
+func (a *AsyncAppender) Append(msg *Message) {
+	a.mutex.Lock()
+	for !a.closed && a.count == len(a.buf) {
+		switch {
+		case a.config.Policy == AsyncPolicyDropOldest:
+			a.buf[a.head] = nil
+			a.head = (a.head + 1) % len(a.buf)
+			a.count--
+			a.drop()
+		case a.config.Policy == AsyncPolicyDropBelowLevel && msg.Level() <= a.dropLevel:
+			a.mutex.Unlock()
+			a.drop()
+			return
+		default:
+			a.cond.Wait()
+		}
+	}
+	if a.closed {
+		a.mutex.Unlock()
+		a.appendSync(msg)
+		return
+	}
+	a.buf[(a.head+a.count)%len(a.buf)] = msg
+	a.count++
+	a.cond.Broadcast()
+	a.mutex.Unlock()
+}
+
+func (a *AsyncAppender) drop() {
+	atomic.AddUint64(&a.dropped, 1)
+	atomic.AddUint64(&a.unreported, 1)
+}
+
+func (a *AsyncAppender) appendSync(msg *Message) {
+	for _, appender := range a.appenders {
+		appender.Append(msg)
+	}
+}
+
+// run 在后台 goroutine 中批量取出缓冲区中的日志并输出。
+func (a *AsyncAppender) run() {
+	defer close(a.done)
+	batch := make([]*Message, 0, len(a.buf))
+	for {
+		a.mutex.Lock()
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			a.mutex.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; a.count > 0; a.count-- {
+			batch = append(batch, a.buf[a.head])
+			a.buf[a.head] = nil
+			a.head = (a.head + 1) % len(a.buf)
+		}
+		a.busy = true
+		a.cond.Broadcast()
+		a.mutex.Unlock()
+
+		a.reportDropped()
+		for _, msg := range batch {
+			a.appendSync(msg)
+		}
+
+		a.mutex.Lock()
+		a.busy = false
+		a.cond.Broadcast()
+		a.mutex.Unlock()
+	}
+}
+
+// reportDropped 输出一条提示丢弃了多少日志的 WARN 日志。
+func (a *AsyncAppender) reportDropped() {
+	n := atomic.SwapUint64(&a.unreported, 0)
+	if n == 0 {
+		return
+	}
+	msg := NewMessageBuilder().
+		WithLevel(WarnLevel).
+		WithTime(time.Now()).
+		WithLoggerName(a.config.Name).
+		WithArgs([]interface{}{fmt.Sprintf("AsyncAppender `%s` dropped %d messages", a.config.Name, n)}).
+		Build()
+	a.appendSync(msg)
+}
+
+// Flush 等待缓冲区中的日志全部输出。
+func (a *AsyncAppender) Flush() {
+	a.mutex.Lock()
+	for a.count > 0 || a.busy {
+		a.cond.Wait()
+	}
+	a.mutex.Unlock()
+	a.reportDropped()
+}
+
+// Close 输出缓冲区中的日志并停止后台 goroutine ，之后的日志直接同步输出。
+func (a *AsyncAppender) Close() error {
+	a.mutex.Lock()
+	a.closed = true
+	a.cond.Broadcast()
+	a.mutex.Unlock()
+	<-a.done
+	a.reportDropped()
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+// blockingAppender 在 release 关闭之前阻塞输出。
+type blockingAppender struct {
+	mutex   sync.Mutex
+	release chan struct{}
+	msgs    []string
+}
+
+func (a *blockingAppender) Append(msg *log.Message) {
+	<-a.release
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.msgs = append(a.msgs, fmt.Sprint(msg.Args()...))
+}
+
+func (a *blockingAppender) Messages() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]string{}, a.msgs...)
+}
+
+func newMessage(level log.Level, s string) *log.Message {
+	return log.NewMessageBuilder().WithLevel(level).WithArgs([]interface{}{s}).Build()
+}
+
+func TestAsyncAppender(t *testing.T) {
+
+	t.Run("block", func(t *testing.T) {
+		target := &blockingAppender{release: make(chan struct{})}
+		close(target.release)
+		a, err := log.NewAsyncAppender(&log.AsyncAppenderConfig{Name: "async", BufferSize: 4}, target)
+		assert.Nil(t, err)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					a.Append(newMessage(log.InfoLevel, "x"))
+				}
+			}()
+		}
+		wg.Wait()
+		a.Flush()
+		assert.Equal(t, len(target.Messages()), 100)
+		assert.Equal(t, a.Dropped(), uint64(0))
+		assert.Nil(t, a.Close())
+	})
+
+	t.Run("dropOldest", func(t *testing.T) {
+		target := &blockingAppender{release: make(chan struct{})}
+		a, err := log.NewAsyncAppender(&log.AsyncAppenderConfig{
+			Name:       "async",
+			BufferSize: 2,
+			Policy:     "dropOldest",
+		}, target)
+		assert.Nil(t, err)
+
+		// 第一条日志被后台 goroutine 取出后阻塞，缓冲区只保留最后两条日志。
+		a.Append(newMessage(log.InfoLevel, "0"))
+		for a.Buffered() > 0 {
+		}
+		for i := 1; i <= 5; i++ {
+			a.Append(newMessage(log.InfoLevel, fmt.Sprint(i)))
+		}
+		assert.Equal(t, a.Dropped(), uint64(3))
+		close(target.release)
+		assert.Nil(t, a.Close())
+		assert.Equal(t, target.Messages(), []string{
+			"0",
+			"AsyncAppender `async` dropped 3 messages",
+			"4",
+			"5",
+		})
+	})
+
+	t.Run("dropBelowLevel", func(t *testing.T) {
+		target := &blockingAppender{release: make(chan struct{})}
+		a, err := log.NewAsyncAppender(&log.AsyncAppenderConfig{
+			Name:       "async",
+			BufferSize: 2,
+			Policy:     "dropBelowLevel",
+			DropLevel:  "info",
+		}, target)
+		assert.Nil(t, err)
+
+		a.Append(newMessage(log.InfoLevel, "0"))
+		for a.Buffered() > 0 {
+		}
+		a.Append(newMessage(log.InfoLevel, "1"))
+		a.Append(newMessage(log.InfoLevel, "2"))
+		a.Append(newMessage(log.DebugLevel, "3"))
+		a.Append(newMessage(log.InfoLevel, "4"))
+		assert.Equal(t, a.Dropped(), uint64(2))
+
+		done := make(chan struct{})
+		go func() {
+			a.Append(newMessage(log.ErrorLevel, "5"))
+			close(done)
+		}()
+		close(target.release)
+		<-done
+		a.Flush()
+		assert.Equal(t, target.Messages(), []string{
+			"0",
+			"AsyncAppender `async` dropped 2 messages",
+			"1",
+			"2",
+			"5",
+		})
+		assert.Nil(t, a.Close())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := log.NewAsyncAppender(&log.AsyncAppenderConfig{Name: "async"})
+		assert.Error(t, err, "no appender ref for AsyncAppender `async`")
+		_, err = log.NewAsyncAppender(&log.AsyncAppenderConfig{Name: "async", Policy: "abc"}, log.NewConsoleAppender(nil))
+		assert.Error(t, err, "error policy `abc` for AsyncAppender `async`")
+	})
+}
+
+func TestAsyncAppender_Load(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s"/>
+				<AsyncAppender name="async" bufferSize="16">
+					<AppenderRef ref="file"/>
+				</AsyncAppender>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="async"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName))
+	assert.Nil(t, err)
+
+	for i := 0; i < 100; i++ {
+		log.GetLogger(log.RootLoggerName).Info("hello async")
+	}
+	log.Flush()
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Count(string(b), "hello async"), 100)
+}
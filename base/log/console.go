@@ -19,6 +19,7 @@ package log
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/huazai2008101/stark/base/cast"
@@ -40,8 +41,11 @@ func (f *ConsoleAppenderFactory) NewAppender(config AppenderConfig) (Appender, e
 	return NewConsoleAppender(config.(*ConsoleAppenderConfig)), nil
 }
 
+// ConsoleAppenderConfig 控制台输出配置，未配置 Layout 时使用带颜色的文本格式。
 type ConsoleAppenderConfig struct {
-	Name string `xml:"name,attr"`
+	AppenderFilters
+	Name   string        `xml:"name,attr"`
+	Layout LayoutElement `xml:",any"`
 }
 
 func (c *ConsoleAppenderConfig) GetName() string {
@@ -57,6 +61,15 @@ func NewConsoleAppender(config *ConsoleAppenderConfig) *ConsoleAppender {
 }
 
 func (c *ConsoleAppender) Append(msg *Message) {
+	if c.config != nil && c.config.Layout.Layout != nil {
+		b, err := c.config.Layout.Layout.ToBytes(msg)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ConsoleAppender layout error: %v\n", err)
+			return
+		}
+		_, _ = os.Stdout.Write(b)
+		return
+	}
 	level := msg.Level()
 	strLevel := strings.ToUpper(level.String())
 	if level >= ErrorLevel {
@@ -72,5 +85,5 @@ func (c *ConsoleAppender) Append(msg *Message) {
 	}
 	strTime := msg.Time().Format("2006-01-02T15:04:05.000")
 	fileLine := util.Contract(fmt.Sprintf("%s:%d", msg.File(), msg.Line()), 48)
-	_, _ = fmt.Printf("[%s][%s][%s] %s\n", strLevel, strTime, fileLine, buf.String())
+	_, _ = fmt.Printf("[%s][%s][%s] %s%s\n", strLevel, strTime, fileLine, buf.String(), fieldsText(msg.Fields()))
 }
@@ -25,10 +25,18 @@ type Entry interface {
 	Context() context.Context
 }
 
+// FieldsEntry 由携带结构化字段的 Entry 实现，没有实现该接口的 Entry 输出的日志不包含
+// 结构化字段。
+type FieldsEntry interface {
+	Entry
+	Fields() []Field
+}
+
 type BaseEntry struct {
 	logger *Logger
 	skip   int
 	tag    string
+	fields []Field
 }
 
 func (e *BaseEntry) Logger() *Logger {
@@ -47,6 +55,10 @@ func (e *BaseEntry) Context() context.Context {
 	return nil
 }
 
+func (e *BaseEntry) Fields() []Field {
+	return e.fields
+}
+
 func (e BaseEntry) WithSkip(n int) BaseEntry {
 	e.skip = n
 	return e
@@ -63,9 +75,21 @@ func (e BaseEntry) WithContext(ctx context.Context) CtxEntry {
 		skip:   e.skip,
 		tag:    e.tag,
 		ctx:    ctx,
+		fields: e.fields,
 	}
 }
 
+// WithFields 创建包含结构化字段的 Entry 。
+func (e BaseEntry) WithFields(fields ...Field) BaseEntry {
+	e.fields = appendFields(e.fields, fields)
+	return e
+}
+
+// With 创建包含结构化字段的 Entry 。
+func (e BaseEntry) With(key string, value interface{}) BaseEntry {
+	return e.WithFields(Any(key, value))
+}
+
 // Trace 输出 TRACE 级别的日志。
 func (e BaseEntry) Trace(args ...interface{}) {
 	printf(TraceLevel, &e, "", args)
@@ -141,6 +165,7 @@ type CtxEntry struct {
 	skip   int
 	tag    string
 	ctx    context.Context
+	fields []Field
 }
 
 func (e *CtxEntry) Logger() *Logger {
@@ -159,6 +184,10 @@ func (e *CtxEntry) Context() context.Context {
 	return e.ctx
 }
 
+func (e *CtxEntry) Fields() []Field {
+	return e.fields
+}
+
 func (e CtxEntry) WithSkip(n int) CtxEntry {
 	e.skip = n
 	return e
@@ -169,6 +198,17 @@ func (e CtxEntry) WithTag(tag string) CtxEntry {
 	return e
 }
 
+// WithFields 创建包含结构化字段的 Entry 。
+func (e CtxEntry) WithFields(fields ...Field) CtxEntry {
+	e.fields = appendFields(e.fields, fields)
+	return e
+}
+
+// With 创建包含结构化字段的 Entry 。
+func (e CtxEntry) With(key string, value interface{}) CtxEntry {
+	return e.WithFields(Any(key, value))
+}
+
 // Trace 输出 TRACE 级别的日志。
 func (e CtxEntry) Trace(args ...interface{}) {
 	printf(TraceLevel, &e, "", args)
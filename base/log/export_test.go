@@ -0,0 +1,28 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import "time"
+
+// SetAfterFunc 替换自动恢复日志级别使用的定时器，返回恢复原定时器的函数。
+func SetAfterFunc(fn func(d time.Duration, f func()) interface{ Stop() bool }) (reset func()) {
+	old := afterFunc
+	afterFunc = func(d time.Duration, f func()) revertTimer {
+		return fn(d, f)
+	}
+	return func() { afterFunc = old }
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field 定义日志的结构化字段。
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String 创建字符串类型的字段。
+func String(key string, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Int 创建 int 类型的字段。
+func Int(key string, val int) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Int64 创建 int64 类型的字段。
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Bool 创建 bool 类型的字段。
+func Bool(key string, val bool) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Float64 创建 float64 类型的字段。
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Duration 创建 time.Duration 类型的字段，输出为 1.5s 这样的格式。
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Err 创建 key 为 error 的字段，err 为 nil 时输出 <nil> 。
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Any 创建任意类型的字段。
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, Value: val}
+}
+
+// appendFields 返回合并后的新切片，避免多个 Entry 共享同一个底层数组。
+func appendFields(fields []Field, more []Field) []Field {
+	if len(more) == 0 {
+		return fields
+	}
+	r := make([]Field, 0, len(fields)+len(more))
+	r = append(r, fields...)
+	return append(r, more...)
+}
+
+// fieldJSONValue 返回字段在 JSON 中的值，无法序列化的值使用字符串形式。
+func fieldJSONValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, string, bool, int, int64, float64:
+		return x
+	case error:
+		return x.Error()
+	case time.Duration:
+		return x.String()
+	case fmt.Stringer:
+		return x.String()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// fieldText 返回字段在文本格式中的值，包含空白、引号或等号时加上引号。
+func fieldText(v interface{}) string {
+	var s string
+	switch x := v.(type) {
+	case nil:
+		s = "<nil>"
+	case string:
+		s = x
+	case error:
+		s = x.Error()
+	default:
+		s = fmt.Sprint(x)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// fieldsText 以 key=value 的形式输出字段，每个字段前有一个空格。
+func fieldsText(fields []Field) string {
+	var buf strings.Builder
+	for _, f := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(fieldText(f.Value))
+	}
+	return buf.String()
+}
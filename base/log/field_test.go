@@ -0,0 +1,96 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+// legacyEntry 只实现了原有方法的 Entry ，增加结构化字段后仍然可以编译
+type legacyEntry struct{}
+
+func (e *legacyEntry) Logger() *log.Logger      { return nil }
+func (e *legacyEntry) Skip() int                { return 0 }
+func (e *legacyEntry) Tag() string              { return "" }
+func (e *legacyEntry) Context() context.Context { return nil }
+
+var (
+	_ log.Entry       = (*legacyEntry)(nil)
+	_ log.FieldsEntry = (*log.BaseEntry)(nil)
+	_ log.FieldsEntry = (*log.CtxEntry)(nil)
+)
+
+func TestFields(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	jsonFileName := filepath.Join(t.TempDir(), "app.json")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s"/>
+				<FileAppender name="json" fileName="%s">
+					<JSONLayout/>
+				</FileAppender>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="file"/>
+					<AppenderRef ref="json"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName, jsonFileName))
+	assert.Nil(t, err)
+
+	entry := log.WithFields(log.String("user", "jim"), log.Int("age", 3))
+	entry.With("msg", "a b").WithFields(
+		log.Duration("cost", 1500*time.Millisecond),
+		log.Err(errors.New("timeout")),
+	).Info("hello")
+	entry.Info("world")
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.True(t, strings.HasSuffix(lines[0], `hello user=jim age=3 msg="a b" cost=1.5s error=timeout`))
+	assert.True(t, strings.HasSuffix(lines[1], `world user=jim age=3`))
+
+	b, err = ioutil.ReadFile(jsonFileName)
+	assert.Nil(t, err)
+	lines = strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, len(lines), 2)
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &m))
+	assert.Equal(t, m["msg"], "hello")
+	assert.Equal(t, m["field.msg"], "a b")
+	assert.Equal(t, m["user"], "jim")
+	assert.Equal(t, m["age"], float64(3))
+	assert.Equal(t, m["cost"], "1.5s")
+	assert.Equal(t, m["error"], "timeout")
+}
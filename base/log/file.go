@@ -15,3 +15,466 @@
  */
 
 package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterAppenderFactory("FileAppender", new(FileAppenderFactory))
+}
+
+type FileAppenderFactory struct{}
+
+func (f *FileAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(FileAppenderConfig)
+}
+
+func (f *FileAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewFileAppender(config.(*FileAppenderConfig))
+}
+
+// FileAppenderConfig 文件输出配置，例如：
+// <FileAppender name="file" fileName="logs/app.log" filePattern="logs/app-%d-%i.log"
+// maxSize="100MB" daily="true" maxBackups="10" compress="true" maxAge="7d"/>
+type FileAppenderConfig struct {
+	AppenderFilters
+	Name string `xml:"name,attr"`
+
+	// FileName 当前写入的日志文件。
+	FileName string `xml:"fileName,attr"`
+
+	// FilePattern 滚动后的文件名，%d 替换为日期，%i 替换为当天的序号，默认为
+	// 在 FileName 的扩展名前插入 -%d-%i 。
+	FilePattern string `xml:"filePattern,attr"`
+
+	// MaxSize 单个文件的最大长度，支持 KB、MB、GB 单位，为空时不按大小滚动。
+	MaxSize string `xml:"maxSize,attr"`
+
+	// Daily 是否每天滚动一次。
+	Daily bool `xml:"daily,attr"`
+
+	// MaxBackups 最多保留的滚动文件数量，为 0 时不限制。
+	MaxBackups int `xml:"maxBackups,attr"`
+
+	// Compress 是否使用 gzip 压缩滚动后的文件。
+	Compress bool `xml:"compress,attr"`
+
+	// MaxAge 滚动文件的最长保留时间，支持 d 单位，为空时不限制。
+	MaxAge string `xml:"maxAge,attr"`
+
+	// Layout 日志格式，以子元素的形式配置，默认为 TextLayout 。
+	Layout LayoutElement `xml:",any"`
+}
+
+func (c *FileAppenderConfig) GetName() string {
+	return c.Name
+}
+
+// FileAppender 将日志写入文件，支持按大小和按天滚动，滚动后的文件在后台进行压缩
+// 和清理，不会长时间阻塞写日志的调用方。
+type FileAppender struct {
+	config  *FileAppenderConfig
+	layout  Layout
+	maxSize int64
+	maxAge  time.Duration
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	rollTime time.Time // 下一次按天滚动的时间
+
+	// 每天已经使用的最大滚动文件序号，打开文件时扫描一次目录，之后在内存中递增。
+	backupIndex map[string]int
+
+	millCh   chan struct{}
+	millOnce sync.Once
+	millWg   sync.WaitGroup
+	closed   bool
+}
+
+func NewFileAppender(config *FileAppenderConfig) (*FileAppender, error) {
+	if config.FileName == "" {
+		return nil, fmt.Errorf("fileName of FileAppender `%s` is empty", config.Name)
+	}
+	maxSize, err := ParseByteSize(config.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := parseMaxAge(config.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	if config.FilePattern == "" {
+		ext := filepath.Ext(config.FileName)
+		config.FilePattern = strings.TrimSuffix(config.FileName, ext) + "-%d-%i" + ext
+	} else if !strings.Contains(config.FilePattern, "%i") {
+		return nil, fmt.Errorf("filePattern of FileAppender `%s` must contain %%i", config.Name)
+	}
+	layout := config.Layout.Layout
+	if layout == nil {
+		layout = NewTextLayout(new(TextLayoutConfig))
+	}
+	return &FileAppender{
+		config:  config,
+		layout:  layout,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		millCh:  make(chan struct{}, 1),
+	}, nil
+}
+
+func (c *FileAppender) Append(msg *Message) {
+	b, err := c.layout.ToBytes(msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FileAppender layout error: %v\n", err)
+		return
+	}
+	if _, err = c.Write(b); err != nil {
+		fmt.Fprintf(os.Stderr, "FileAppender write error: %v\n", err)
+	}
+}
+
+// Write 写入日志内容，必要时先进行文件滚动。
+func (c *FileAppender) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+
+	if c.file == nil {
+		if err := c.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now()
+	if (c.config.Daily && !now.Before(c.rollTime)) ||
+		(c.maxSize > 0 && c.size > 0 && c.size+int64(len(p)) > c.maxSize) {
+		if err := c.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.file.Write(p)
+	c.size += int64(n)
+	return n, err
+}
+
+// Close 关闭当前文件，并等待后台的压缩和清理任务结束。
+func (c *FileAppender) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	var err error
+	if c.file != nil {
+		err = c.file.Close()
+		c.file = nil
+	}
+	c.mutex.Unlock()
+	close(c.millCh)
+	c.millWg.Wait()
+	return err
+}
+
+// openExisting 打开已经存在的日志文件继续写入，按天滚动时以文件的修改时间作为基准。
+func (c *FileAppender) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(c.config.FileName), 0755); err != nil {
+		return err
+	}
+	base := time.Now()
+	if info, err := os.Stat(c.config.FileName); err == nil {
+		c.size = info.Size()
+		base = info.ModTime()
+	}
+	f, err := os.OpenFile(c.config.FileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	c.file = f
+	c.rollTime = nextDay(base)
+	return c.scanBackups()
+}
+
+// scanBackups 扫描已经存在的滚动文件，记录每天使用的最大序号。
+func (c *FileAppender) scanBackups() error {
+	c.backupIndex = make(map[string]int)
+	re := backupRegexp(filepath.Base(c.config.FilePattern))
+	dateIndex, numIndex := re.SubexpIndex("d"), re.SubexpIndex("i")
+	backups, err := c.listBackups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		m := re.FindStringSubmatch(filepath.Base(b.name))
+		if m == nil {
+			continue
+		}
+		var date string
+		if dateIndex > 0 {
+			date = m[dateIndex]
+		}
+		if n, err := strconv.Atoi(m[numIndex]); err == nil && n > c.backupIndex[date] {
+			c.backupIndex[date] = n
+		}
+	}
+	return nil
+}
+
+// backupRegexp 返回匹配滚动文件名的正则表达式，第一个 %d 和 %i 分别捕获为 d 和 i 。
+func backupRegexp(pattern string) *regexp.Regexp {
+	s := regexp.QuoteMeta(pattern)
+	s = strings.Replace(s, "%d", `(?P<d>\d{4}-\d{2}-\d{2})`, 1)
+	s = strings.ReplaceAll(s, "%d", `\d{4}-\d{2}-\d{2}`)
+	s = strings.Replace(s, "%i", `(?P<i>\d+)`, 1)
+	s = strings.ReplaceAll(s, "%i", `\d+`)
+	return regexp.MustCompile("^" + s + `(?:\.gz)?$`)
+}
+
+// rotate 将当前文件重命名为滚动文件然后重新打开，压缩和清理交给后台处理。
+func (c *FileAppender) rotate(now time.Time) error {
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	c.file = nil
+
+	date := now
+	if c.config.Daily && !now.Before(c.rollTime) {
+		date = c.rollTime.Add(-time.Second) // 按天滚动的文件归属于前一天
+	}
+	backup := c.backupName(date)
+	if err := os.Rename(c.config.FileName, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(c.config.FileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	c.file = f
+	c.size = 0
+	c.rollTime = nextDay(now)
+	c.startMill()
+	return nil
+}
+
+// backupName 返回 date 当天下一个可用的滚动文件名。
+func (c *FileAppender) backupName(date time.Time) string {
+	var day string
+	if strings.Contains(c.config.FilePattern, "%d") {
+		day = date.Format("2006-01-02")
+		for k := range c.backupIndex {
+			if k < day {
+				delete(c.backupIndex, k) // 之前日期的序号不会再使用
+			}
+		}
+	}
+	pattern := strings.ReplaceAll(c.config.FilePattern, "%d", day)
+	for {
+		c.backupIndex[day]++
+		name := strings.ReplaceAll(pattern, "%i", strconv.Itoa(c.backupIndex[day]))
+		// 打开文件时已经扫描过滚动文件，只有其他进程创建了同名文件时才需要继续查找
+		if !fileExists(name) && !fileExists(name+".gz") {
+			return name
+		}
+	}
+}
+
+// startMill 通知后台协程压缩和清理滚动文件，多次通知会被合并。
+func (c *FileAppender) startMill() {
+	c.millOnce.Do(func() {
+		c.millWg.Add(1)
+		go func() {
+			defer c.millWg.Done()
+			for range c.millCh {
+				if err := c.mill(); err != nil {
+					fmt.Fprintf(os.Stderr, "FileAppender mill error: %v\n", err)
+				}
+			}
+		}()
+	})
+	select {
+	case c.millCh <- struct{}{}:
+	default:
+	}
+}
+
+type backupFile struct {
+	name    string
+	modTime time.Time
+}
+
+// mill 压缩未压缩的滚动文件，删除超出数量和过期的滚动文件。
+func (c *FileAppender) mill() error {
+	backups, err := c.listBackups()
+	if err != nil {
+		return err
+	}
+
+	var remove, keep []backupFile
+	for i, b := range backups {
+		if c.config.MaxBackups > 0 && i >= c.config.MaxBackups {
+			remove = append(remove, b)
+			continue
+		}
+		if c.maxAge > 0 && time.Since(b.modTime) > c.maxAge {
+			remove = append(remove, b)
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	for _, b := range remove {
+		if err = os.Remove(b.name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if c.config.Compress {
+		for _, b := range keep {
+			if strings.HasSuffix(b.name, ".gz") {
+				continue
+			}
+			if err = compressFile(b.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listBackups 返回所有滚动文件，按修改时间从新到旧排序。
+func (c *FileAppender) listBackups() ([]backupFile, error) {
+	glob := strings.ReplaceAll(c.config.FilePattern, "%d", "*")
+	glob = strings.ReplaceAll(glob, "%i", "*")
+	var backups []backupFile
+	for _, pattern := range []string{glob, glob + ".gz"} {
+		names, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if name == c.config.FileName {
+				continue
+			}
+			info, err := os.Stat(name)
+			if err != nil {
+				continue
+			}
+			backups = append(backups, backupFile{name: name, modTime: info.ModTime()})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	return backups, nil
+}
+
+// compressFile 使用 gzip 压缩文件，成功后删除原文件。
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(name + ".gz")
+		}
+	}()
+
+	w := gzip.NewWriter(dst)
+	if _, err = io.Copy(w, src); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	// 保留原文件的修改时间，保证按时间清理的准确性。
+	if err = os.Chtimes(name+".gz", info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func nextDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// ParseByteSize 解析带 KB、MB、GB 单位的长度，不带单位时表示字节数，空字符串返回 0。
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, u.suffix) {
+			unit = u.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error size `%s`", s)
+	}
+	return n * unit, nil
+}
+
+// parseMaxAge 解析保留时间，除了 time.Duration 支持的单位之外还支持 d 表示天。
+func parseMaxAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("error max age `%s`", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+func TestFileAppender(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.log")
+
+	a, err := log.NewFileAppender(&log.FileAppenderConfig{
+		Name:       "file",
+		FileName:   fileName,
+		MaxSize:    "1KB",
+		MaxBackups: 2,
+		Compress:   true,
+	})
+	assert.Nil(t, err)
+
+	line := strings.Repeat("x", 99) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := a.Write([]byte(line))
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, a.Close())
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.True(t, len(b) > 0 && len(b) <= 1024)
+
+	backups, err := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(backups), 2)
+
+	plain, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(plain), 0)
+}
+
+func TestFileAppender_FilePattern(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.log")
+
+	// 不包含 %i 时同一天的滚动文件无法区分
+	_, err := log.NewFileAppender(&log.FileAppenderConfig{
+		Name:        "file",
+		FileName:    fileName,
+		FilePattern: filepath.Join(dir, "app-%d.log"),
+		MaxSize:     "1KB",
+	})
+	assert.Error(t, err, "must contain %i")
+
+	// 已经存在的滚动文件不会被覆盖
+	exist := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(exist, []byte("old"), 0644))
+	a, err := log.NewFileAppender(&log.FileAppenderConfig{
+		Name:        "file",
+		FileName:    fileName,
+		FilePattern: filepath.Join(dir, "app-%i.log"),
+		MaxSize:     "1KB",
+	})
+	assert.Nil(t, err)
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 15; i++ {
+		_, err = a.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, a.Close())
+
+	b, err := ioutil.ReadFile(exist)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "old")
+	_, err = ioutil.ReadFile(filepath.Join(dir, "app-2.log"))
+	assert.Nil(t, err)
+}
+
+func TestFileAppender_BackupIndex(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.log")
+
+	// 打开文件时扫描已有的滚动文件，之后的序号在当天最大序号的基础上递增
+	today := time.Now().Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	for _, name := range []string{
+		"app-" + today + "-5.log.gz",
+		"app-" + today + "-2.log",
+		"app-" + yesterday + "-9.log",
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("old"), 0644))
+	}
+
+	a, err := log.NewFileAppender(&log.FileAppenderConfig{
+		Name:     "file",
+		FileName: fileName,
+		MaxSize:  "100B",
+	})
+	assert.Nil(t, err)
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 3; i++ {
+		_, err = a.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, a.Close())
+
+	for _, i := range []string{"6", "7"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "app-"+today+"-"+i+".log"))
+		assert.Nil(t, err)
+		assert.Equal(t, string(b), line)
+	}
+}
+
+func TestFileAppender_Load(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s" maxSize="10MB" daily="true" maxAge="7d"/>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="file"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName))
+	assert.Nil(t, err)
+
+	l := log.GetLogger(log.RootLoggerName)
+	l.Info("hello ", "file")
+	time.Sleep(10 * time.Millisecond)
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(b), "hello file"))
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, expect := range map[string]int64{
+		"":      0,
+		"512":   512,
+		"1KB":   1 << 10,
+		"10mb":  10 << 20,
+		"2G":    2 << 30,
+		" 3 MB": 3 << 20,
+	} {
+		n, err := log.ParseByteSize(s)
+		assert.Nil(t, err)
+		assert.Equal(t, n, expect)
+	}
+	_, err := log.ParseByteSize("abc")
+	assert.Error(t, err, "error size")
+}
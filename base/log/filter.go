@@ -0,0 +1,316 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/huazai2008101/stark/base/cast"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	filterFactories = map[string]FilterFactory{}
+	contextKeys     sync.Map
+)
+
+// RegisterContextKey 注册 context 值的名称，ContextFilter 和 JSONLayout 使用 name 查
+// 找时通过 ctx.Value(key) 获取值，key 一般是业务包中定义的非导出类型的值。
+func RegisterContextKey(name string, key interface{}) {
+	contextKeys.Store(name, key)
+}
+
+func init() {
+	RegisterFilterFactory("LevelRangeFilter", new(LevelRangeFilterFactory))
+	RegisterFilterFactory("TagFilter", new(TagFilterFactory))
+	RegisterFilterFactory("RegexFilter", new(RegexFilterFactory))
+	RegisterFilterFactory("ContextFilter", new(ContextFilterFactory))
+}
+
+// Filter 定义日志过滤器，返回 false 时不输出该条日志。
+type Filter interface {
+	Filter(msg *Message) bool
+}
+
+// FilterFactory 定义 Filter 工厂。
+type FilterFactory interface {
+	NewFilterConfig() interface{}
+	NewFilter(config interface{}) (Filter, error)
+}
+
+// RegisterFilterFactory 注册 Filter 工厂。
+func RegisterFilterFactory(filter string, factory FilterFactory) {
+	filterFactories[filter] = factory
+}
+
+// Filters 解析 <Filters> 元素下的过滤器，日志需要通过所有的过滤器才会输出。
+type Filters []Filter
+
+func (f *Filters) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			factory, ok := filterFactories[t.Name.Local]
+			if !ok {
+				return fmt.Errorf("no filter factory `%s` found", t.Name.Local)
+			}
+			config := factory.NewFilterConfig()
+			if err = d.DecodeElement(config, &t); err != nil {
+				return err
+			}
+			filter, err := factory.NewFilter(config)
+			if err != nil {
+				return err
+			}
+			*f = append(*f, filter)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (f Filters) Filter(msg *Message) bool {
+	for _, filter := range f {
+		if !filter.Filter(msg) {
+			return false
+		}
+	}
+	return true
+}
+
+// AppenderFilters 嵌入到 Appender 的配置中，用于为 Appender 配置过滤器，例如：
+//
+//	<FileAppender name="error" fileName="logs/error.log">
+//	    <Filters>
+//	        <LevelRangeFilter minLevel="error"/>
+//	    </Filters>
+//	</FileAppender>
+type AppenderFilters struct {
+	Filters Filters `xml:"Filters"`
+}
+
+func (c *AppenderFilters) GetFilters() Filters {
+	return c.Filters
+}
+
+// FilterAppender 只将通过过滤器的日志输出到 Appender 。
+type FilterAppender struct {
+	appender Appender
+	filters  Filters
+}
+
+func NewFilterAppender(appender Appender, filters Filters) *FilterAppender {
+	return &FilterAppender{appender: appender, filters: filters}
+}
+
+func (a *FilterAppender) Append(msg *Message) {
+	if a.filters.Filter(msg) {
+		a.appender.Append(msg)
+	}
+}
+
+type LevelRangeFilterFactory struct{}
+
+func (f *LevelRangeFilterFactory) NewFilterConfig() interface{} {
+	return new(LevelRangeFilterConfig)
+}
+
+func (f *LevelRangeFilterFactory) NewFilter(config interface{}) (Filter, error) {
+	return NewLevelRangeFilter(config.(*LevelRangeFilterConfig))
+}
+
+type LevelRangeFilterConfig struct {
+	MinLevel string `xml:"minLevel,attr"` // 为空时不限制
+	MaxLevel string `xml:"maxLevel,attr"` // 为空时不限制
+}
+
+// LevelRangeFilter 只输出级别在 [minLevel,maxLevel] 范围内的日志。
+type LevelRangeFilter struct {
+	min Level
+	max Level
+}
+
+func NewLevelRangeFilter(config *LevelRangeFilterConfig) (*LevelRangeFilter, error) {
+	f := &LevelRangeFilter{min: TraceLevel, max: FatalLevel}
+	if config.MinLevel != "" {
+		if f.min = StringToLevel(config.MinLevel); f.min == NoneLevel {
+			return nil, fmt.Errorf("error minLevel `%s` for LevelRangeFilter", config.MinLevel)
+		}
+	}
+	if config.MaxLevel != "" {
+		if f.max = StringToLevel(config.MaxLevel); f.max == NoneLevel {
+			return nil, fmt.Errorf("error maxLevel `%s` for LevelRangeFilter", config.MaxLevel)
+		}
+	}
+	return f, nil
+}
+
+func (f *LevelRangeFilter) Filter(msg *Message) bool {
+	return msg.Level() >= f.min && msg.Level() <= f.max
+}
+
+type TagFilterFactory struct{}
+
+func (f *TagFilterFactory) NewFilterConfig() interface{} {
+	return new(TagFilterConfig)
+}
+
+func (f *TagFilterFactory) NewFilter(config interface{}) (Filter, error) {
+	return NewTagFilter(config.(*TagFilterConfig))
+}
+
+type TagFilterConfig struct {
+	Tags    string `xml:"tags,attr"`    // 逗号分隔的 tag 列表，以 * 结尾时按前缀匹配
+	Exclude bool   `xml:"exclude,attr"` // 为 true 时不输出匹配的日志
+}
+
+// TagFilter 按 Message.Tag() 过滤日志。
+type TagFilter struct {
+	tags    []string
+	exclude bool
+}
+
+func NewTagFilter(config *TagFilterConfig) (*TagFilter, error) {
+	f := &TagFilter{exclude: config.Exclude}
+	for _, s := range strings.Split(config.Tags, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			f.tags = append(f.tags, s)
+		}
+	}
+	if len(f.tags) == 0 {
+		return nil, fmt.Errorf("tags of TagFilter is empty")
+	}
+	return f, nil
+}
+
+func (f *TagFilter) Filter(msg *Message) bool {
+	return f.match(msg.Tag()) != f.exclude
+}
+
+func (f *TagFilter) match(tag string) bool {
+	for _, s := range f.tags {
+		if strings.HasSuffix(s, "*") {
+			if strings.HasPrefix(tag, s[:len(s)-1]) {
+				return true
+			}
+		} else if tag == s {
+			return true
+		}
+	}
+	return false
+}
+
+type RegexFilterFactory struct{}
+
+func (f *RegexFilterFactory) NewFilterConfig() interface{} {
+	return new(RegexFilterConfig)
+}
+
+func (f *RegexFilterFactory) NewFilter(config interface{}) (Filter, error) {
+	return NewRegexFilter(config.(*RegexFilterConfig))
+}
+
+type RegexFilterConfig struct {
+	Regex   string `xml:"regex,attr"`
+	Exclude bool   `xml:"exclude,attr"` // 为 true 时不输出匹配的日志
+}
+
+// RegexFilter 使用正则表达式匹配日志内容。
+type RegexFilter struct {
+	regex   *regexp.Regexp
+	exclude bool
+}
+
+func NewRegexFilter(config *RegexFilterConfig) (*RegexFilter, error) {
+	r, err := regexp.Compile(config.Regex)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexFilter{regex: r, exclude: config.Exclude}, nil
+}
+
+func (f *RegexFilter) Filter(msg *Message) bool {
+	return f.regex.MatchString(messageText(msg)) != f.exclude
+}
+
+type ContextFilterFactory struct{}
+
+func (f *ContextFilterFactory) NewFilterConfig() interface{} {
+	return new(ContextFilterConfig)
+}
+
+func (f *ContextFilterFactory) NewFilter(config interface{}) (Filter, error) {
+	return NewContextFilter(config.(*ContextFilterConfig))
+}
+
+type ContextFilterConfig struct {
+	Key     string `xml:"key,attr"`
+	Value   string `xml:"value,attr"`   // 为空时只要求 key 存在
+	Exclude bool   `xml:"exclude,attr"` // 为 true 时不输出匹配的日志
+}
+
+// ContextFilter 按 Message.Context() 中的值过滤日志，依次查找通过 RegisterContextKey
+// 注册的 context 值以及 grpc 的 incoming 、outgoing metadata 。
+type ContextFilter struct {
+	config *ContextFilterConfig
+}
+
+func NewContextFilter(config *ContextFilterConfig) (*ContextFilter, error) {
+	if config.Key == "" {
+		return nil, fmt.Errorf("key of ContextFilter is empty")
+	}
+	return &ContextFilter{config: config}, nil
+}
+
+func (f *ContextFilter) Filter(msg *Message) bool {
+	v, ok := contextValue(msg.Context(), f.config.Key)
+	match := ok && (f.config.Value == "" || v == f.config.Value)
+	return match != f.config.Exclude
+}
+
+// contextValue 依次从 RegisterContextKey 注册的 context 值以及 grpc 的 incoming 、
+// outgoing metadata 中查找 key 对应的值。
+func contextValue(ctx context.Context, key string) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if k, ok := contextKeys.Load(key); ok {
+		if v := ctx.Value(k); v != nil {
+			return cast.ToString(v), true
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0], true
+		}
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0], true
+		}
+	}
+	return "", false
+}
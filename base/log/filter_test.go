@@ -0,0 +1,149 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFilters(t *testing.T) {
+
+	msg := func(level log.Level, tag string, ctx context.Context, s string) *log.Message {
+		return log.NewMessageBuilder().
+			WithLevel(level).
+			WithTag(tag).
+			WithContext(ctx).
+			WithArgs([]interface{}{s}).
+			Build()
+	}
+
+	t.Run("level", func(t *testing.T) {
+		f, err := log.NewLevelRangeFilter(&log.LevelRangeFilterConfig{MinLevel: "info", MaxLevel: "warn"})
+		assert.Nil(t, err)
+		assert.False(t, f.Filter(msg(log.DebugLevel, "", nil, "")))
+		assert.True(t, f.Filter(msg(log.InfoLevel, "", nil, "")))
+		assert.True(t, f.Filter(msg(log.WarnLevel, "", nil, "")))
+		assert.False(t, f.Filter(msg(log.ErrorLevel, "", nil, "")))
+		_, err = log.NewLevelRangeFilter(&log.LevelRangeFilterConfig{MinLevel: "abc"})
+		assert.Error(t, err, "error minLevel `abc` for LevelRangeFilter")
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		f, err := log.NewTagFilter(&log.TagFilterConfig{Tags: "__in, __out,_com_*"})
+		assert.Nil(t, err)
+		assert.True(t, f.Filter(msg(log.InfoLevel, "__in", nil, "")))
+		assert.True(t, f.Filter(msg(log.InfoLevel, "_com_request_in", nil, "")))
+		assert.False(t, f.Filter(msg(log.InfoLevel, "__inner", nil, "")))
+		assert.False(t, f.Filter(msg(log.InfoLevel, "", nil, "")))
+		f, err = log.NewTagFilter(&log.TagFilterConfig{Tags: "__in", Exclude: true})
+		assert.Nil(t, err)
+		assert.False(t, f.Filter(msg(log.InfoLevel, "__in", nil, "")))
+		assert.True(t, f.Filter(msg(log.InfoLevel, "", nil, "")))
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		f, err := log.NewRegexFilter(&log.RegexFilterConfig{Regex: `^health`, Exclude: true})
+		assert.Nil(t, err)
+		assert.False(t, f.Filter(msg(log.InfoLevel, "", nil, "health check")))
+		assert.True(t, f.Filter(msg(log.InfoLevel, "", nil, "order created")))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		f, err := log.NewContextFilter(&log.ContextFilterConfig{Key: "x-debug", Value: "1"})
+		assert.Nil(t, err)
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-debug", "1"))
+		assert.True(t, f.Filter(msg(log.InfoLevel, "", ctx, "")))
+		assert.False(t, f.Filter(msg(log.InfoLevel, "", nil, "")))
+
+		// 通过注册的 context 键查找
+		log.RegisterContextKey("x-debug", debugKey{})
+		ctx = context.WithValue(context.Background(), debugKey{}, 1)
+		assert.True(t, f.Filter(msg(log.InfoLevel, "", ctx, "")))
+		ctx = context.WithValue(context.Background(), debugKey{}, "0")
+		assert.False(t, f.Filter(msg(log.InfoLevel, "", ctx, "")))
+		// 未注册的字符串键不会被查找
+		ctx = context.WithValue(context.Background(), stringKey("x-debug"), "1")
+		assert.False(t, f.Filter(msg(log.InfoLevel, "", ctx, "")))
+	})
+}
+
+type debugKey struct{}
+
+type stringKey string
+
+func TestFilters_Load(t *testing.T) {
+	dir := t.TempDir()
+	appFile := filepath.Join(dir, "app.log")
+	errorFile := filepath.Join(dir, "error.log")
+	trafficFile := filepath.Join(dir, "traffic.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="app" fileName="%s"/>
+				<FileAppender name="error" fileName="%s">
+					<Filters>
+						<LevelRangeFilter minLevel="error"/>
+					</Filters>
+				</FileAppender>
+				<FileAppender name="traffic" fileName="%s"/>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="app">
+						<Filters>
+							<TagFilter tags="__in,__out" exclude="true"/>
+						</Filters>
+					</AppenderRef>
+					<AppenderRef ref="error"/>
+					<AppenderRef ref="traffic">
+						<Filters>
+							<TagFilter tags="__in,__out"/>
+						</Filters>
+					</AppenderRef>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, appFile, errorFile, trafficFile))
+	assert.Nil(t, err)
+
+	root := log.GetLogger(log.RootLoggerName)
+	root.Info("info")
+	root.Error("error")
+	root.WithTag("__in").Info("request")
+	root.WithTag("__out").Info("response")
+
+	read := func(fileName string) string {
+		b, err := ioutil.ReadFile(fileName)
+		assert.Nil(t, err)
+		return string(b)
+	}
+	assert.Equal(t, strings.Count(read(appFile), "\n"), 2)
+	assert.Equal(t, strings.Count(read(errorFile), "\n"), 1)
+	assert.True(t, strings.Contains(read(errorFile), "error"))
+	assert.Equal(t, strings.Count(read(trafficFile), "\n"), 2)
+	assert.True(t, strings.Contains(read(trafficFile), "__in request"))
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	RegisterAppenderFactory("HttpAppender", new(HttpAppenderFactory))
+}
+
+type HttpAppenderFactory struct{}
+
+func (f *HttpAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(HttpAppenderConfig)
+}
+
+func (f *HttpAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewHttpAppender(config.(*HttpAppenderConfig))
+}
+
+// HttpAppenderConfig HTTP 输出配置，例如：
+//
+//	<HttpAppender name="http" url="http://127.0.0.1:8080/logs" batchSize="100" flushInterval="1s">
+//	    <Header name="Authorization" value="Bearer xxx"/>
+//	</HttpAppender>
+type HttpAppenderConfig struct {
+	AppenderFilters
+	Name string `xml:"name,attr"`
+
+	// Url 接收日志的地址。
+	Url string `xml:"url,attr"`
+
+	// ContentType 默认为 application/x-ndjson 。
+	ContentType string `xml:"contentType,attr"`
+
+	// BatchSize 每次请求最多发送的日志条数，默认为 100 。
+	BatchSize int `xml:"batchSize,attr"`
+
+	// FlushInterval 不足一批时发送的间隔，默认为 1s 。
+	FlushInterval string `xml:"flushInterval,attr"`
+
+	// BufferSize 等待发送的日志条数上限，超过时丢弃新的日志，默认为 4096 。
+	BufferSize int `xml:"bufferSize,attr"`
+
+	// MaxRetries 请求失败或者返回 429、5xx 时的重试次数，默认为 3 ，小于 0 时不重试。
+	MaxRetries int `xml:"maxRetries,attr"`
+
+	// RetryDelay 第一次重试的间隔，之后每次加倍，默认为 500ms 。
+	RetryDelay string `xml:"retryDelay,attr"`
+
+	// Timeout 请求的超时时间，默认为 5s 。
+	Timeout string `xml:"timeout,attr"`
+
+	Headers []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"Header"`
+
+	// Layout 日志格式，以子元素的形式配置，默认为 JSONLayout 。
+	Layout LayoutElement `xml:",any"`
+}
+
+func (c *HttpAppenderConfig) GetName() string {
+	return c.Name
+}
+
+// HttpAppender 在后台 goroutine 中将日志分批 POST 到指定的地址，请求体为多条日志
+// 直接拼接的内容，使用 JSONLayout 时即为 ndjson 格式。
+type HttpAppender struct {
+	config        *HttpAppenderConfig
+	layout        Layout
+	client        *http.Client
+	flushInterval time.Duration
+	retryDelay    time.Duration
+
+	ch        chan []byte
+	flushCh   chan chan struct{}
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	dropped uint64
+}
+
+func NewHttpAppender(config *HttpAppenderConfig) (*HttpAppender, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("url of HttpAppender `%s` is empty", config.Name)
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/x-ndjson"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 4096
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	flushInterval, err := parseDuration(config.FlushInterval, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	retryDelay, err := parseDuration(config.RetryDelay, 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseDuration(config.Timeout, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	layout := config.Layout.Layout
+	if layout == nil {
+		layout = NewJSONLayout(new(JSONLayoutConfig))
+	}
+	a := &HttpAppender{
+		config:        config,
+		layout:        layout,
+		client:        &http.Client{Timeout: timeout},
+		flushInterval: flushInterval,
+		retryDelay:    retryDelay,
+		ch:            make(chan []byte, config.BufferSize),
+		flushCh:       make(chan chan struct{}),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// Dropped 返回缓冲区已满或者发送失败而丢弃的日志条数。
+func (a *HttpAppender) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+func (a *HttpAppender) Append(msg *Message) {
+	b, err := a.layout.ToBytes(msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "HttpAppender layout error: %v\n", err)
+		return
+	}
+	select {
+	case <-a.quit:
+		atomic.AddUint64(&a.dropped, 1)
+		return
+	default:
+	}
+	select {
+	case a.ch <- b:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+func (a *HttpAppender) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+	var batch [][]byte
+	for {
+		select {
+		case b := <-a.ch:
+			batch = append(batch, b)
+			if len(batch) >= a.config.BatchSize {
+				a.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			a.send(batch)
+			batch = nil
+		case ack := <-a.flushCh:
+			a.send(a.drain(batch))
+			batch = nil
+			close(ack)
+		case <-a.quit:
+			a.send(a.drain(batch))
+			return
+		}
+	}
+}
+
+// drain 取出缓冲区中的全部日志，达到一批时立即发送，返回剩余不足一批的日志。
+func (a *HttpAppender) drain(batch [][]byte) [][]byte {
+	for {
+		select {
+		case b := <-a.ch:
+			batch = append(batch, b)
+			if len(batch) >= a.config.BatchSize {
+				a.send(batch)
+				batch = nil
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+// send 发送一批日志，失败时按照 retryDelay 加倍的间隔进行重试。
+func (a *HttpAppender) send(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	body := bytes.Join(batch, nil)
+	var err error
+	for i := 0; ; i++ {
+		var retry bool
+		if retry, err = a.post(body); err == nil {
+			return
+		}
+		if !retry || i >= a.config.MaxRetries {
+			break
+		}
+		time.Sleep(a.retryDelay << i)
+	}
+	atomic.AddUint64(&a.dropped, uint64(len(batch)))
+	fmt.Fprintf(os.Stderr, "HttpAppender `%s` dropped %d messages: %v\n", a.config.Name, len(batch), err)
+}
+
+// post 发送一次请求，返回失败时是否可以重试。
+func (a *HttpAppender) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, a.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", a.config.ContentType)
+	for _, h := range a.config.Headers {
+		req.Header.Set(h.Name, h.Value)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("http status %d", resp.StatusCode)
+}
+
+// Flush 等待缓冲区中的日志全部发送。
+func (a *HttpAppender) Flush() {
+	ack := make(chan struct{})
+	select {
+	case a.flushCh <- ack:
+		<-ack
+	case <-a.done:
+	}
+}
+
+// Close 发送缓冲区中的日志并停止后台 goroutine 。
+func (a *HttpAppender) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.quit)
+	})
+	<-a.done
+	return nil
+}
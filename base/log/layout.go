@@ -0,0 +1,202 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/huazai2008101/stark/base/cast"
+	"github.com/huazai2008101/stark/base/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIdKey 请求 ID 在 metadata 中的 key 。
+const RequestIdKey = "x-request-id"
+
+var layoutFactories = map[string]LayoutFactory{}
+
+func init() {
+	RegisterLayoutFactory("TextLayout", new(TextLayoutFactory))
+	RegisterLayoutFactory("JSONLayout", new(JSONLayoutFactory))
+}
+
+// Layout 定义日志消息的输出格式。
+type Layout interface {
+	ToBytes(msg *Message) ([]byte, error)
+}
+
+// LayoutFactory 定义 Layout 工厂。
+type LayoutFactory interface {
+	NewLayoutConfig() interface{}
+	NewLayout(config interface{}) (Layout, error)
+}
+
+// RegisterLayoutFactory 注册 Layout 工厂。
+func RegisterLayoutFactory(layout string, factory LayoutFactory) {
+	layoutFactories[layout] = factory
+}
+
+// LayoutElement 用于在 Appender 的配置中以子元素的形式声明 Layout，例如：
+// <FileAppender name="file" fileName="app.log"><JSONLayout/></FileAppender>
+type LayoutElement struct {
+	Layout Layout
+}
+
+func (e *LayoutElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	factory, ok := layoutFactories[start.Name.Local]
+	if !ok {
+		return fmt.Errorf("no layout factory `%s` found", start.Name.Local)
+	}
+	config := factory.NewLayoutConfig()
+	if err := d.DecodeElement(config, &start); err != nil {
+		return err
+	}
+	layout, err := factory.NewLayout(config)
+	if err != nil {
+		return err
+	}
+	e.Layout = layout
+	return nil
+}
+
+// messageText 将消息参数拼接成字符串。
+func messageText(msg *Message) string {
+	var buf bytes.Buffer
+	for _, a := range msg.Args() {
+		buf.WriteString(cast.ToString(a))
+	}
+	return buf.String()
+}
+
+// ContextIds 从 ctx 中获取链路追踪的 trace id、span id 以及请求 ID 。
+func ContextIds(ctx context.Context) (traceId, spanId, requestId string) {
+	if ctx == nil {
+		return
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		traceId = sc.TraceID().String()
+		spanId = sc.SpanID().String()
+	}
+	requestId, _ = contextValue(ctx, RequestIdKey)
+	return
+}
+
+type TextLayoutFactory struct{}
+
+func (f *TextLayoutFactory) NewLayoutConfig() interface{} {
+	return new(TextLayoutConfig)
+}
+
+func (f *TextLayoutFactory) NewLayout(config interface{}) (Layout, error) {
+	return NewTextLayout(config.(*TextLayoutConfig)), nil
+}
+
+type TextLayoutConfig struct{}
+
+// TextLayout 纯文本格式，[LEVEL][time][file:line] tag message 。
+type TextLayout struct {
+	config *TextLayoutConfig
+}
+
+func NewTextLayout(config *TextLayoutConfig) *TextLayout {
+	return &TextLayout{config: config}
+}
+
+func (l *TextLayout) ToBytes(msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	strLevel := strings.ToUpper(msg.Level().String())
+	strTime := msg.Time().Format("2006-01-02T15:04:05.000")
+	fileLine := util.Contract(fmt.Sprintf("%s:%d", msg.File(), msg.Line()), 48)
+	fmt.Fprintf(&buf, "[%s][%s][%s] ", strLevel, strTime, fileLine)
+	if msg.Tag() != "" {
+		buf.WriteString(msg.Tag())
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(messageText(msg))
+	buf.WriteString(fieldsText(msg.Fields()))
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+type JSONLayoutFactory struct{}
+
+func (f *JSONLayoutFactory) NewLayoutConfig() interface{} {
+	return new(JSONLayoutConfig)
+}
+
+func (f *JSONLayoutFactory) NewLayout(config interface{}) (Layout, error) {
+	return NewJSONLayout(config.(*JSONLayoutConfig)), nil
+}
+
+type JSONLayoutConfig struct {
+	// TimeFormat 时间格式，默认为 2006-01-02T15:04:05.000Z07:00 。
+	TimeFormat string `xml:"timeFormat,attr"`
+}
+
+// JSONLayout 每条日志输出为一行 JSON，包含级别、时间、日志名称、标签、文件行号、
+// 消息内容，以及从 Message.Context() 中获取的 trace id、span id 和请求 ID ，
+// 结构化字段直接输出为 JSON 的属性，与内置属性重名时加上 field. 前缀。
+type JSONLayout struct {
+	config *JSONLayoutConfig
+}
+
+func NewJSONLayout(config *JSONLayoutConfig) *JSONLayout {
+	if config.TimeFormat == "" {
+		config.TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+	}
+	return &JSONLayout{config: config}
+}
+
+func (l *JSONLayout) ToBytes(msg *Message) ([]byte, error) {
+	m := map[string]interface{}{
+		"level": msg.Level().String(),
+		"time":  msg.Time().Format(l.config.TimeFormat),
+		"file":  fmt.Sprintf("%s:%d", msg.File(), msg.Line()),
+		"msg":   messageText(msg),
+	}
+	if s := msg.LoggerName(); s != "" {
+		m["logger"] = s
+	}
+	if s := msg.Tag(); s != "" {
+		m["tag"] = s
+	}
+	traceId, spanId, requestId := ContextIds(msg.Context())
+	if traceId != "" {
+		m["traceId"] = traceId
+		m["spanId"] = spanId
+	}
+	if requestId != "" {
+		m["requestId"] = requestId
+	}
+	for _, f := range msg.Fields() {
+		key := f.Key
+		if _, ok := m[key]; ok {
+			key = "field." + key
+		}
+		m[key] = fieldJSONValue(f.Value)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
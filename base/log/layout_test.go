@@ -0,0 +1,111 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestJSONLayout(t *testing.T) {
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(log.RequestIdKey, "req-1"))
+
+	msg := log.NewMessageBuilder().
+		WithLevel(log.InfoLevel).
+		WithTime(time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)).
+		WithContext(ctx).
+		WithLoggerName("biz").
+		WithTag("_com_request_in").
+		WithFile("file.go").
+		WithLine(10).
+		WithArgs([]interface{}{"hello ", 3}).
+		Build()
+
+	b, err := log.NewJSONLayout(&log.JSONLayoutConfig{}).ToBytes(msg)
+	assert.Nil(t, err)
+
+	var m map[string]string
+	assert.Nil(t, json.Unmarshal(b, &m))
+	assert.Equal(t, m, map[string]string{
+		"level":     "info",
+		"time":      "2022-06-01T08:00:00.000Z",
+		"logger":    "biz",
+		"tag":       "_com_request_in",
+		"file":      "file.go:10",
+		"msg":       "hello 3",
+		"traceId":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanId":    "00f067aa0ba902b7",
+		"requestId": "req-1",
+	})
+}
+
+func TestJSONLayout_Load(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s">
+					<JSONLayout/>
+				</FileAppender>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="file"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName))
+	assert.Nil(t, err)
+
+	log.GetLogger(log.RootLoggerName).Info("hello ", "json")
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	var m map[string]string
+	assert.Nil(t, json.Unmarshal(b, &m))
+	assert.Equal(t, m["logger"], log.RootLoggerName)
+	assert.Equal(t, m["msg"], "hello json")
+
+	err = log.Load(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<ConsoleAppender name="console">
+					<UnknownLayout/>
+				</ConsoleAppender>
+			</Appenders>
+		</Configuration>
+	`)
+	assert.Error(t, err, "no layout factory `UnknownLayout` found")
+}
@@ -21,8 +21,11 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 )
 
 const RootLoggerName = "Root"
@@ -30,13 +33,38 @@ const RootLoggerName = "Root"
 var (
 	rootLogger        = GetLogger(RootLoggerName)
 	usingLoggers      = map[string]*Logger{}
+	usingLoggersMutex sync.RWMutex
 	appenderFactories = map[string]AppenderFactory{}
+
+	loadedAppenders      []Appender
+	loadedAppendersMutex sync.Mutex
+
+	// appendMutex 输出日志时持有读锁，Load 持有写锁等待使用旧配置的输出结束。
+	appendMutex sync.RWMutex
 )
 
 type AppenderConfig interface {
 	GetName() string
 }
 
+// AppenderRefConfig 由引用其他 Appender 的配置实现，例如 AsyncAppender ，Load 在创建
+// Appender 之前将引用的 Appender 传给 SetAppenders 。
+type AppenderRefConfig interface {
+	AppenderConfig
+	GetAppenderRefs() []string
+	SetAppenders(appenders []Appender)
+}
+
+// FilterConfig 由支持过滤器的 Appender 配置实现，一般通过嵌入 AppenderFilters 实现。
+type FilterConfig interface {
+	GetFilters() Filters
+}
+
+// flusher 由缓存日志的 Appender 实现。
+type flusher interface {
+	Flush()
+}
+
 // AppenderFactory 定义 Appender 工厂。
 type AppenderFactory interface {
 	NewAppenderConfig() AppenderConfig
@@ -60,22 +88,52 @@ func GetLogger(name ...string) *Logger {
 			name = append(name, RootLoggerName)
 		}
 	}
+	usingLoggersMutex.Lock()
+	defer usingLoggersMutex.Unlock()
 	l, ok := usingLoggers[name[0]]
 	if ok {
 		return l
 	}
-	l = NewLogger(name[0], &LoggerConfig{
-		Level: InfoLevel,
-		Appenders: []Appender{
-			NewRpPetAppender(nil),
-		},
-	})
+	// 没有单独配置的 Logger 使用 Root 的配置。
+	if root, ok := usingLoggers[RootLoggerName]; ok {
+		l = NewLogger(name[0], root.config())
+	} else {
+		l = NewLogger(name[0], &LoggerConfig{
+			Level: InfoLevel,
+			Appenders: []Appender{
+				NewRpPetAppender(nil),
+			},
+		})
+	}
 	usingLoggers[l.name] = l
 	return l
 }
 
-// Load 加载日志配置文件。
-func Load(configFile string) error {
+// Loggers 返回所有正在使用的 *Logger 对象，按名称排序。
+func Loggers() []*Logger {
+	usingLoggersMutex.RLock()
+	defer usingLoggersMutex.RUnlock()
+	loggers := make([]*Logger, 0, len(usingLoggers))
+	for _, l := range usingLoggers {
+		loggers = append(loggers, l)
+	}
+	sort.Slice(loggers, func(i, j int) bool {
+		return loggers[i].name < loggers[j].name
+	})
+	return loggers
+}
+
+// LoadFile 从文件加载日志配置。
+func LoadFile(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return Load(string(b))
+}
+
+// Load 加载日志配置文件，加载失败时关闭已经创建的 Appender ，不影响正在使用的配置。
+func Load(configFile string) (err error) {
 
 	const (
 		EnterConfiguration = 1
@@ -89,6 +147,17 @@ func Load(configFile string) error {
 	state := 0
 	configLoggers := map[string]*Logger{}
 	configAppenders := map[string]Appender{}
+	var newAppenders []Appender
+	defer func() {
+		if err != nil {
+			for _, l := range configLoggers {
+				if c := l.config(); c.Sampler != nil {
+					c.Sampler.Stop()
+				}
+			}
+			closeAppenders(newAppenders)
+		}
+	}()
 	d := xml.NewDecoder(strings.NewReader(configFile))
 	for {
 		token, err := d.Token()
@@ -121,11 +190,26 @@ func Load(configFile string) error {
 				if err != nil {
 					return err
 				}
+				if c, ok := config.(AppenderRefConfig); ok {
+					var appenders []Appender
+					for _, ref := range c.GetAppenderRefs() {
+						v, ok := configAppenders[ref]
+						if !ok {
+							return fmt.Errorf("no appender ref `%s` found", ref)
+						}
+						appenders = append(appenders, v)
+					}
+					c.SetAppenders(appenders)
+				}
 				var appender Appender
 				appender, err = factory.NewAppender(config)
 				if err != nil {
 					return err
 				}
+				newAppenders = append(newAppenders, appender)
+				if c, ok := config.(FilterConfig); ok && len(c.GetFilters()) > 0 {
+					appender = NewFilterAppender(appender, c.GetFilters())
+				}
 				configAppenders[config.GetName()] = appender
 				continue
 			}
@@ -134,8 +218,10 @@ func Load(configFile string) error {
 					Name         string `xml:"name,attr"`
 					Level        string `xml:"level,attr"`
 					AppenderRefs []struct {
-						Ref string `xml:"ref,attr"`
+						Ref     string  `xml:"ref,attr"`
+						Filters Filters `xml:"Filters"`
 					} `xml:"AppenderRef"`
+					Sampling *SamplingConfig `xml:"Sampling"`
 				}
 				err = d.DecodeElement(&config, &t)
 				if err != nil {
@@ -154,11 +240,26 @@ func Load(configFile string) error {
 					if !ok {
 						return fmt.Errorf("no appender ref `%s` found", ref.Ref)
 					}
+					if len(ref.Filters) > 0 {
+						v = NewFilterAppender(v, ref.Filters)
+					}
 					appenders = append(appenders, v)
 				}
+				var sampler *Sampler
+				if config.Sampling != nil {
+					sampler, err = NewSampler(config.Sampling)
+					if err != nil {
+						return err
+					}
+					name := config.Name
+					sampler.OnSummary(func(msgs []*Message) {
+						appendSummaries(name, appenders, msgs)
+					})
+				}
 				l := NewLogger(config.Name, &LoggerConfig{
 					Level:     level,
 					Appenders: appenders,
+					Sampler:   sampler,
 				})
 				configLoggers[config.Name] = l
 			}
@@ -182,16 +283,89 @@ func Load(configFile string) error {
 		return fmt.Errorf("no logger `%s` found", RootLoggerName)
 	}
 
+	usingLoggersMutex.Lock()
+	oldConfigs := samplingConfigs()
+	for name, l := range configLoggers {
+		if _, ok := usingLoggers[name]; !ok {
+			usingLoggers[name] = l
+		}
+	}
 	for name, usingLogger := range usingLoggers {
+		usingLogger.mutex.Lock()
+		usingLogger.stopRevert()
 		if l, ok := configLoggers[name]; ok {
 			usingLogger.value.Store(l.value.Load())
 		} else {
 			usingLogger.value.Store(root.value.Load())
 		}
+		usingLogger.mutex.Unlock()
+	}
+	usingLoggersMutex.Unlock()
+
+	loadedAppendersMutex.Lock()
+	oldAppenders := loadedAppenders
+	loadedAppenders = newAppenders
+	loadedAppendersMutex.Unlock()
+
+	// 等待仍在使用旧配置输出的日志结束，之后的日志都会使用新的配置。
+	appendMutex.Lock()
+	appendMutex.Unlock()
+
+	// 停止旧配置中 Logger 的采样，在关闭 Appender 之前输出尚未输出的汇总日志。
+	for _, c := range oldConfigs {
+		appendSummaries("", c.Appenders, c.Sampler.Stop())
 	}
+
+	closeAppenders(oldAppenders)
 	return nil
 }
 
+// closeAppenders 按声明的逆序关闭 Appender ，引用方先于被引用的 Appender 关闭，释
+// 放文件、网络连接以及后台 goroutine 。
+func closeAppenders(appenders []Appender) {
+	for i := len(appenders) - 1; i >= 0; i-- {
+		if c, ok := appenders[i].(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+// Flush 将缓存的日志全部输出，应用退出前调用。
+func Flush() {
+	usingLoggersMutex.RLock()
+	configs := samplingConfigs()
+	usingLoggersMutex.RUnlock()
+	for _, c := range configs {
+		appendSummaries("", c.Appenders, c.Sampler.Flush())
+	}
+
+	loadedAppendersMutex.Lock()
+	appenders := loadedAppenders
+	loadedAppendersMutex.Unlock()
+	// 按声明的逆序输出，引用方先于被引用的 Appender 输出。
+	for i := len(appenders) - 1; i >= 0; i-- {
+		if f, ok := appenders[i].(flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// samplingConfigs 返回正在使用的启用了采样的 Logger 配置，多个 Logger 共享同一个
+// Sampler 时只返回一个，调用时需要持有 usingLoggersMutex 。
+func samplingConfigs() []*LoggerConfig {
+	var configs []*LoggerConfig
+	samplers := map[*Sampler]bool{}
+	for _, l := range usingLoggers {
+		c := l.config()
+		if c.Sampler == nil || samplers[c.Sampler] {
+			continue
+		}
+		samplers[c.Sampler] = true
+		configs = append(configs, c)
+	}
+	return configs
+}
+
 // SetLevel 设置日志输出等级。
 func SetLevel(level Level) {
 	rootLogger.SetLevel(level)
@@ -212,6 +386,16 @@ func WithContext(ctx context.Context) CtxEntry {
 	return rootLogger.WithContext(ctx)
 }
 
+// WithFields 创建包含结构化字段的 Entry 。
+func WithFields(fields ...Field) BaseEntry {
+	return rootLogger.WithFields(fields...)
+}
+
+// With 创建包含结构化字段的 Entry 。
+func With(key string, value interface{}) BaseEntry {
+	return rootLogger.With(key, value)
+}
+
 // Trace 输出 TRACE 级别的日志。
 func Trace(ctx context.Context, args ...interface{}) {
 	rootLogger.WithContext(ctx).WithSkip(1).Trace(args...)
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+func init() {
+	log.RegisterAppenderFactory("RecordAppender", new(recordAppenderFactory))
+}
+
+var (
+	recordAppendersMutex sync.Mutex
+	recordAppenders      = map[string]*recordAppender{}
+)
+
+func getRecordAppender(name string) *recordAppender {
+	recordAppendersMutex.Lock()
+	defer recordAppendersMutex.Unlock()
+	return recordAppenders[name]
+}
+
+type recordAppenderFactory struct{}
+
+type recordAppenderConfig struct {
+	Name string `xml:"name,attr"`
+}
+
+func (c *recordAppenderConfig) GetName() string {
+	return c.Name
+}
+
+func (f *recordAppenderFactory) NewAppenderConfig() log.AppenderConfig {
+	return new(recordAppenderConfig)
+}
+
+func (f *recordAppenderFactory) NewAppender(config log.AppenderConfig) (log.Appender, error) {
+	a := &recordAppender{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	recordAppendersMutex.Lock()
+	recordAppenders[config.GetName()] = a
+	recordAppendersMutex.Unlock()
+	return a, nil
+}
+
+// recordAppender 记录输出和关闭，block 为 true 时输出会阻塞到 release 关闭
+type recordAppender struct {
+	mutex   sync.Mutex
+	block   bool
+	entered chan struct{}
+	release chan struct{}
+	appends int
+	closed  bool
+}
+
+func (a *recordAppender) Append(msg *log.Message) {
+	a.mutex.Lock()
+	block := a.block
+	a.mutex.Unlock()
+	if block {
+		a.entered <- struct{}{}
+		<-a.release
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		panic("append after close")
+	}
+	a.appends++
+}
+
+func (a *recordAppender) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.closed = true
+	return nil
+}
+
+func (a *recordAppender) isClosed() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.closed
+}
+
+const recordConfig = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<Configuration>
+		<Appenders>
+			<RecordAppender name="%s"/>
+		</Appenders>
+		<Loggers>
+			<Root level="info">
+				<AppenderRef ref="%s"/>
+			</Root>
+		</Loggers>
+	</Configuration>
+`
+
+func loadRecord(name string) error {
+	return log.Load(fmt.Sprintf(recordConfig, name, name))
+}
+
+func TestLoad_CloseOnError(t *testing.T) {
+	assert.Nil(t, loadRecord("using"))
+	using := getRecordAppender("using")
+
+	// 加载失败时关闭已经创建的 Appender ，正在使用的配置不受影响
+	err := log.Load(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<RecordAppender name="created"/>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="unknown"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`)
+	assert.Error(t, err, "no appender ref `unknown` found")
+	assert.True(t, getRecordAppender("created").isClosed())
+	assert.False(t, using.isClosed())
+
+	log.GetLogger(log.RootLoggerName).Info("hello")
+	assert.Equal(t, using.appends, 1)
+}
+
+func TestLoad_DrainBeforeClose(t *testing.T) {
+	assert.Nil(t, loadRecord("old"))
+	old := getRecordAppender("old")
+	old.block = true
+
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		log.GetLogger(log.RootLoggerName).Info("in-flight")
+	}()
+	<-old.entered
+
+	loaded := make(chan error)
+	go func() {
+		loaded <- loadRecord("new")
+	}()
+
+	// 正在使用旧配置输出的日志结束之前不会关闭旧的 Appender
+	select {
+	case err := <-loaded:
+		t.Fatalf("load returned before in-flight append finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.False(t, old.isClosed())
+
+	close(old.release)
+	<-logged
+	assert.Nil(t, <-loaded)
+	assert.True(t, old.isClosed())
+	assert.Equal(t, old.appends, 1)
+
+	log.GetLogger(log.RootLoggerName).Info("after load")
+	assert.Equal(t, getRecordAppender("new").appends, 1)
+}
@@ -18,6 +18,8 @@ package log
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/huazai2008101/stark/base/atomic"
 )
@@ -26,11 +28,28 @@ type Logger struct {
 	name  string
 	entry BaseEntry
 	value atomic.Value
+
+	// 临时调整日志级别后自动恢复使用
+	mutex       sync.Mutex
+	revert      revertTimer
+	revertAt    time.Time
+	revertLevel Level
+}
+
+// revertTimer 临时调整日志级别后自动恢复的定时器。
+type revertTimer interface {
+	Stop() bool
+}
+
+// afterFunc 创建自动恢复的定时器，测试时替换为手动触发的定时器。
+var afterFunc = func(d time.Duration, f func()) revertTimer {
+	return time.AfterFunc(d, f)
 }
 
 type LoggerConfig struct {
 	Level     Level
 	Appenders []Appender
+	Sampler   *Sampler // 为 nil 时不采样
 }
 
 func NewLogger(name string, config *LoggerConfig) *Logger {
@@ -51,11 +70,69 @@ func (l *Logger) config() *LoggerConfig {
 	return v.(*LoggerConfig)
 }
 
+// Level 返回日志输出等级。
+func (l *Logger) Level() Level {
+	return l.config().Level
+}
+
+// SetLevel 设置日志输出等级，会取消尚未到期的自动恢复。
 func (l *Logger) SetLevel(level Level) {
-	l.value.Store(&LoggerConfig{
-		Level:     level,
-		Appenders: l.config().Appenders,
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.stopRevert()
+	l.storeLevel(level)
+}
+
+// SetLevelWithTTL 设置日志输出等级，ttl 大于 0 时到期后自动恢复为首次调整前的等级，
+// 在到期前再次调整会重新计时。
+func (l *Logger) SetLevelWithTTL(level Level, ttl time.Duration) {
+	if ttl <= 0 {
+		l.SetLevel(level)
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	origin := l.config().Level
+	if l.revert != nil {
+		l.revert.Stop()
+		origin = l.revertLevel
+	}
+	l.storeLevel(level)
+	l.revertLevel = origin
+	l.revertAt = time.Now().Add(ttl)
+	var timer revertTimer
+	timer = afterFunc(ttl, func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if l.revert != timer {
+			return
+		}
+		l.storeLevel(origin)
+		l.revert = nil
+		l.revertAt = time.Time{}
 	})
+	l.revert = timer
+}
+
+// RevertAt 返回临时调整的日志级别自动恢复的时间，没有临时调整时返回零值。
+func (l *Logger) RevertAt() time.Time {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.revertAt
+}
+
+func (l *Logger) stopRevert() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+		l.revertAt = time.Time{}
+	}
+}
+
+func (l *Logger) storeLevel(level Level) {
+	c := *l.config()
+	c.Level = level
+	l.value.Store(&c)
 }
 
 // WithSkip 创建包含 skip 信息的 Entry 。
@@ -73,6 +150,16 @@ func (l *Logger) WithContext(ctx context.Context) CtxEntry {
 	return l.entry.WithContext(ctx)
 }
 
+// WithFields 创建包含结构化字段的 Entry 。
+func (l *Logger) WithFields(fields ...Field) BaseEntry {
+	return l.entry.WithFields(fields...)
+}
+
+// With 创建包含结构化字段的 Entry 。
+func (l *Logger) With(key string, value interface{}) BaseEntry {
+	return l.entry.With(key, value)
+}
+
 // Trace 输出 TRACE 级别的日志。
 func (l *Logger) Trace(args ...interface{}) {
 	printf(TraceLevel, &l.entry, "", args)
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+// fakeTimer 手动触发的定时器
+type fakeTimer struct {
+	d       time.Duration
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+func TestLogger_SetLevelWithTTL(t *testing.T) {
+	var timers []*fakeTimer
+	reset := log.SetAfterFunc(func(d time.Duration, f func()) interface{ Stop() bool } {
+		timer := &fakeTimer{d: d, f: f}
+		timers = append(timers, timer)
+		return timer
+	})
+	defer reset()
+
+	l := log.NewLogger("ttl", &log.LoggerConfig{Level: log.InfoLevel})
+
+	l.SetLevelWithTTL(log.DebugLevel, time.Minute)
+	assert.Equal(t, l.Level(), log.DebugLevel)
+	assert.False(t, l.RevertAt().IsZero())
+	assert.Equal(t, len(timers), 1)
+	assert.Equal(t, timers[0].d, time.Minute)
+
+	// 到期前再次调整会重新计时，恢复的仍然是首次调整前的级别
+	l.SetLevelWithTTL(log.TraceLevel, time.Hour)
+	assert.Equal(t, l.Level(), log.TraceLevel)
+	assert.Equal(t, len(timers), 2)
+	assert.True(t, timers[0].stopped)
+
+	// 已经取消的定时器即使触发也不会恢复级别
+	timers[0].f()
+	assert.Equal(t, l.Level(), log.TraceLevel)
+
+	timers[1].f()
+	assert.Equal(t, l.Level(), log.InfoLevel)
+	assert.True(t, l.RevertAt().IsZero())
+
+	// 直接设置级别会取消自动恢复
+	l.SetLevelWithTTL(log.DebugLevel, time.Minute)
+	l.SetLevel(log.WarnLevel)
+	assert.True(t, timers[2].stopped)
+	timers[2].f()
+	assert.Equal(t, l.Level(), log.WarnLevel)
+	assert.True(t, l.RevertAt().IsZero())
+
+	// ttl 不大于 0 时不会自动恢复
+	l.SetLevelWithTTL(log.ErrorLevel, 0)
+	assert.Equal(t, l.Level(), log.ErrorLevel)
+	assert.Equal(t, len(timers), 3)
+}
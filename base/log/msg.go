@@ -23,19 +23,30 @@ import (
 
 // Message 定义日志消息。
 type Message struct {
-	level Level
-	time  time.Time
-	ctx   context.Context
-	tag   string
-	file  string
-	line  int
-	args  []interface{}
+	level  Level
+	time   time.Time
+	ctx    context.Context
+	logger string
+	tag    string
+	file   string
+	line   int
+	args   []interface{}
+	fields []Field
 }
 
 func (msg *Message) Level() Level {
 	return msg.level
 }
 
+func (msg *Message) LoggerName() string {
+	return msg.logger
+}
+
+// Fields 返回日志的结构化字段。
+func (msg *Message) Fields() []Field {
+	return msg.fields
+}
+
 func (msg *Message) Tag() string {
 	return msg.tag
 }
@@ -61,13 +72,15 @@ func (msg *Message) Context() context.Context {
 }
 
 type MessageBuilder struct {
-	Level Level
-	Time  time.Time
-	Ctx   context.Context
-	Tag   string
-	File  string
-	Line  int
-	Args  []interface{}
+	Level      Level
+	Time       time.Time
+	Ctx        context.Context
+	LoggerName string
+	Tag        string
+	File       string
+	Line       int
+	Args       []interface{}
+	Fields     []Field
 }
 
 func NewMessageBuilder() *MessageBuilder {
@@ -79,6 +92,11 @@ func (b *MessageBuilder) WithLevel(level Level) *MessageBuilder {
 	return b
 }
 
+func (b *MessageBuilder) WithLoggerName(name string) *MessageBuilder {
+	b.LoggerName = name
+	return b
+}
+
 func (b *MessageBuilder) WithTag(tag string) *MessageBuilder {
 	b.Tag = tag
 	return b
@@ -104,6 +122,11 @@ func (b *MessageBuilder) WithArgs(args []interface{}) *MessageBuilder {
 	return b
 }
 
+func (b *MessageBuilder) WithFields(fields ...Field) *MessageBuilder {
+	b.Fields = append(b.Fields, fields...)
+	return b
+}
+
 func (b *MessageBuilder) WithContext(ctx context.Context) *MessageBuilder {
 	b.Ctx = ctx
 	return b
@@ -111,12 +134,14 @@ func (b *MessageBuilder) WithContext(ctx context.Context) *MessageBuilder {
 
 func (b *MessageBuilder) Build() *Message {
 	return &Message{
-		level: b.Level,
-		time:  b.Time,
-		ctx:   b.Ctx,
-		tag:   b.Tag,
-		file:  b.File,
-		line:  b.Line,
-		args:  b.Args,
+		level:  b.Level,
+		time:   b.Time,
+		ctx:    b.Ctx,
+		logger: b.LoggerName,
+		tag:    b.Tag,
+		file:   b.File,
+		line:   b.Line,
+		args:   b.Args,
+		fields: b.Fields,
 	}
 }
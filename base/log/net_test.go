@@ -0,0 +1,206 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+func netMessage(level log.Level, tag string, s string) *log.Message {
+	return log.NewMessageBuilder().
+		WithLevel(level).
+		WithTime(time.Now()).
+		WithTag(tag).
+		WithFile("a.go").
+		WithLine(10).
+		WithArgs([]interface{}{s}).
+		Build()
+}
+
+func TestSyslogAppender(t *testing.T) {
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		a, err := log.NewSyslogAppender(&log.SyslogAppenderConfig{
+			NetworkConfig: log.NetworkConfig{Address: conn.LocalAddr().String()},
+			Name:          "syslog",
+			Facility:      "local0",
+			AppName:       "stark",
+			Hostname:      "host",
+		})
+		assert.Nil(t, err)
+		defer a.Close()
+
+		a.Append(netMessage(log.ErrorLevel, "__in", "hello syslog"))
+
+		buf := make([]byte, 1024)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		// local0(16)*8 + err(3) = 131
+		assert.Matches(t, string(buf[:n]), `^<131>1 \S+ host stark \d+ __in - \[a\.go:10\] hello syslog$`)
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer l.Close()
+
+		a, err := log.NewSyslogAppender(&log.SyslogAppenderConfig{
+			NetworkConfig: log.NetworkConfig{Network: "tcp", Address: l.Addr().String()},
+			Name:          "syslog",
+		})
+		assert.Nil(t, err)
+		defer a.Close()
+
+		a.Append(netMessage(log.InfoLevel, "", "hello"))
+
+		conn, err := l.Accept()
+		assert.Nil(t, err)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var size int
+		_, err = fmt.Fscanf(r, "%d ", &size)
+		assert.Nil(t, err)
+		b := make([]byte, size)
+		_, err = r.Read(b)
+		assert.Nil(t, err)
+		// user(1)*8 + info(6) = 14
+		assert.Matches(t, string(b), `^<14>1 .* - - \[a\.go:10\] hello$`)
+	})
+}
+
+func TestSocketAppender(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	lines := make(chan string, 100)
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				s := bufio.NewScanner(conn)
+				for s.Scan() {
+					lines <- s.Text()
+				}
+			}()
+		}
+	}()
+
+	a, err := log.NewSocketAppender(&log.SocketAppenderConfig{
+		NetworkConfig: log.NetworkConfig{Address: l.Addr().String(), ReconnectDelay: "10ms"},
+		Name:          "socket",
+	})
+	assert.Nil(t, err)
+	defer a.Close()
+
+	a.Append(netMessage(log.InfoLevel, "", "first"))
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(<-lines), &m))
+	assert.Equal(t, m["msg"], "first")
+
+	// 服务端断开连接后自动重新连接。
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.Append(netMessage(log.InfoLevel, "", "again"))
+		select {
+		case line := <-lines:
+			assert.True(t, strings.Contains(line, `"msg":"again"`))
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reconnect timeout")
+		}
+	}
+}
+
+func TestHttpAppender(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		requests int
+		received []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, r.Header.Get("Content-Type"), "application/x-ndjson")
+		assert.Equal(t, r.Header.Get("Authorization"), "token")
+		b, _ := ioutil.ReadAll(r.Body)
+		received = append(received, strings.Split(strings.TrimSpace(string(b)), "\n")...)
+	}))
+	defer server.Close()
+
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<HttpAppender name="http" url="%s" batchSize="3" flushInterval="1h" retryDelay="1ms">
+					<Header name="Authorization" value="token"/>
+				</HttpAppender>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="http"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, server.URL))
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		log.GetLogger(log.RootLoggerName).Info("hello ", i)
+	}
+	log.Flush()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, requests, 3)
+	assert.Equal(t, len(received), 5)
+	for i, s := range received {
+		assert.True(t, regexp.MustCompile(fmt.Sprintf(`"msg":"hello %d"`, i)).MatchString(s))
+	}
+}
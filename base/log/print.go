@@ -53,14 +53,25 @@ func printf(level Level, e Entry, format string, args []interface{}) {
 	if format != "" {
 		args = []interface{}{fmt.Sprintf(format, args...)}
 	}
-	doPrint(config.Appenders, level, e, args)
+	// Load 替换配置后会等待正在输出的日志结束再关闭旧的 Appender ，因此需要在锁内
+	// 重新获取配置。
+	appendMutex.RLock()
+	defer appendMutex.RUnlock()
+	if config = e.Logger().config(); config == nil {
+		config = defaultLoggerConfig
+	}
+	doPrint(config, level, e, args)
 }
 
-func doPrint(appenders []Appender, level Level, e Entry, args []interface{}) {
+func doPrint(config *LoggerConfig, level Level, e Entry, args []interface{}) {
 	msg := new(Message)
 	msg.level = level
 	msg.args = args
+	msg.logger = e.Logger().Name()
 	msg.tag = e.Tag()
+	if fe, ok := e.(FieldsEntry); ok {
+		msg.fields = fe.Fields()
+	}
 	ctx := e.Context()
 	if ctx == nil {
 		ctx = defaultContext
@@ -68,7 +79,14 @@ func doPrint(appenders []Appender, level Level, e Entry, args []interface{}) {
 	msg.ctx = ctx
 	msg.time = time.Now()
 	msg.file, msg.line, _ = Caller(e.Skip()+3, true)
-	for _, appender := range appenders {
+	if config.Sampler != nil {
+		ok, summaries := config.Sampler.Sample(msg)
+		appendSummaries(msg.logger, config.Appenders, summaries)
+		if !ok {
+			return
+		}
+	}
+	for _, appender := range config.Appenders {
 		appender.Append(msg)
 	}
 }
@@ -0,0 +1,365 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var indexSuffix = regexp.MustCompile(`\[\d+]$`)
+
+// LoadProperties 从扁平的属性加载日志配置，只处理 logging. 开头的属性，例如：
+//
+//	logging.config=conf/log.xml                   # 使用 xml 配置，忽略其他属性
+//	logging.level.root=info                       # Root 的级别，默认为 info
+//	logging.level.<logger>=debug                  # 指定 Logger 的级别
+//	logging.appender-refs.<logger>=console,file   # Logger 引用的 Appender
+//	logging.appenders.<name>.type=FileAppender    # 默认为 name 首字母大写加 Appender
+//	logging.appenders.<name>.<attr>=value         # Appender 的属性，与 xml 的属性同名
+//	logging.appenders.<name>.refs=file            # AsyncAppender 等引用的 Appender
+//	logging.appenders.<name>.layout=JSONLayout    # 或者 layout.type 以及 layout.<attr>
+//	logging.appenders.<name>.filters.<Filter>.<attr>=value
+//	logging.appenders.<name>.headers.<name>=value # HttpAppender 的请求头
+//
+// 没有配置 Appender 时只修改 Logger 的级别，不会改变日志的输出目标。Root 没有配置
+// appender-refs 时引用所有没有被其他 Appender 引用的 Appender ，其他 Logger 没有
+// 配置 appender-refs 时使用 Root 的配置。
+func LoadProperties(p map[string]string) error {
+
+	if file := p["logging.config"]; file != "" {
+		return LoadFile(file)
+	}
+
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	levels := map[string]string{}
+	refs := map[string][]string{}
+	appenders := map[string]map[string]string{}
+	for _, k := range keys {
+		v := p[k]
+		switch {
+		case strings.HasPrefix(k, "logging.level."):
+			levels[loggerName(k[len("logging.level."):])] = v
+		case strings.HasPrefix(k, "logging.appender-refs."):
+			name := loggerName(indexSuffix.ReplaceAllString(k[len("logging.appender-refs."):], ""))
+			refs[name] = append(refs[name], splitList(v)...)
+		case strings.HasPrefix(k, "logging.appenders."):
+			s := k[len("logging.appenders."):]
+			i := strings.Index(s, ".")
+			if i <= 0 {
+				return fmt.Errorf("property %q want sub keys", k)
+			}
+			m, ok := appenders[s[:i]]
+			if !ok {
+				m = map[string]string{}
+				appenders[s[:i]] = m
+			}
+			m[s[i+1:]] = v
+		}
+	}
+
+	if len(appenders) == 0 {
+		if len(refs) > 0 {
+			return fmt.Errorf("no logging.appenders found")
+		}
+		for name, s := range levels {
+			level := StringToLevel(s)
+			if level == NoneLevel {
+				return fmt.Errorf("error level `%s` for logger `%s`", s, name)
+			}
+			GetLogger(name).SetLevel(level)
+		}
+		return nil
+	}
+
+	config, err := propertiesToXML(levels, refs, appenders)
+	if err != nil {
+		return err
+	}
+	return Load(config)
+}
+
+// loggerName 将属性中的 root 转换为 Root 。
+func loggerName(s string) string {
+	if strings.EqualFold(s, RootLoggerName) {
+		return RootLoggerName
+	}
+	return s
+}
+
+func splitList(s string) []string {
+	var r []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// propertiesAppender 由属性解析出来的 Appender 配置。
+type propertiesAppender struct {
+	name    string
+	typ     string
+	attrs   map[string]string
+	refs    []string
+	layout  string
+	lattrs  map[string]string
+	filters map[string]map[string]string
+	headers map[string]string
+}
+
+func parsePropertiesAppender(name string, m map[string]string) (*propertiesAppender, error) {
+	a := &propertiesAppender{
+		name:    name,
+		attrs:   map[string]string{},
+		lattrs:  map[string]string{},
+		filters: map[string]map[string]string{},
+		headers: map[string]string{},
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := m[k]
+		switch {
+		case k == "type":
+			a.typ = v
+		case k == "refs" || indexSuffix.ReplaceAllString(k, "") == "refs":
+			a.refs = append(a.refs, splitList(v)...)
+		case k == "layout" || k == "layout.type":
+			a.layout = v
+		case strings.HasPrefix(k, "layout."):
+			a.lattrs[k[len("layout."):]] = v
+		case strings.HasPrefix(k, "filters."):
+			s := k[len("filters."):]
+			i := strings.Index(s, ".")
+			if i <= 0 {
+				return nil, fmt.Errorf("property %q want filter attr", "logging.appenders."+name+"."+k)
+			}
+			f, ok := a.filters[s[:i]]
+			if !ok {
+				f = map[string]string{}
+				a.filters[s[:i]] = f
+			}
+			f[s[i+1:]] = v
+		case strings.HasPrefix(k, "headers."):
+			a.headers[k[len("headers."):]] = v
+		case strings.Contains(k, "."):
+			return nil, fmt.Errorf("unsupported property %q", "logging.appenders."+name+"."+k)
+		default:
+			a.attrs[k] = v
+		}
+	}
+	if a.typ == "" {
+		a.typ = strings.ToUpper(name[:1]) + name[1:] + "Appender"
+	}
+	if _, ok := appenderFactories[a.typ]; !ok {
+		return nil, fmt.Errorf("no appender factory `%s` found for appender `%s`", a.typ, name)
+	}
+	if len(a.lattrs) > 0 && a.layout == "" {
+		return nil, fmt.Errorf("no layout type for appender `%s`", name)
+	}
+	return a, nil
+}
+
+// sortAppenders 按照引用关系排序，被引用的 Appender 排在前面。
+func sortAppenders(appenders map[string]*propertiesAppender) ([]*propertiesAppender, error) {
+	names := make([]string, 0, len(appenders))
+	for name := range appenders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var r []*propertiesAppender
+	var visit func(name string) error
+	visit = func(name string) error {
+		a, ok := appenders[name]
+		if !ok {
+			return fmt.Errorf("no appender ref `%s` found", name)
+		}
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("found circular appender ref `%s`", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, ref := range a.refs {
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		r = append(r, a)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func propertiesToXML(levels map[string]string, refs map[string][]string, m map[string]map[string]string) (string, error) {
+
+	appenders := map[string]*propertiesAppender{}
+	for name, v := range m {
+		a, err := parsePropertiesAppender(name, v)
+		if err != nil {
+			return "", err
+		}
+		appenders[name] = a
+	}
+	sorted, err := sortAppenders(appenders)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<Configuration>\n<Appenders>\n")
+	referred := map[string]bool{}
+	for _, a := range sorted {
+		buf.WriteString("<" + a.typ)
+		writeAttrs(&buf, map[string]string{"name": a.name})
+		writeAttrs(&buf, a.attrs)
+		buf.WriteString(">\n")
+		for _, ref := range a.refs {
+			referred[ref] = true
+			writeElement(&buf, "AppenderRef", map[string]string{"ref": ref})
+		}
+		if a.layout != "" {
+			writeElement(&buf, a.layout, a.lattrs)
+		}
+		writeFilters(&buf, a.filters)
+		for _, name := range sortedKeys(a.headers) {
+			writeElement(&buf, "Header", map[string]string{"name": name, "value": a.headers[name]})
+		}
+		buf.WriteString("</" + a.typ + ">\n")
+	}
+	buf.WriteString("</Appenders>\n<Loggers>\n")
+
+	rootRefs, ok := refs[RootLoggerName]
+	if !ok {
+		for _, a := range sorted {
+			if !referred[a.name] {
+				rootRefs = append(rootRefs, a.name)
+			}
+		}
+	}
+
+	names := []string{RootLoggerName}
+	for name := range levels {
+		if name != RootLoggerName {
+			names = append(names, name)
+		}
+	}
+	for name := range refs {
+		if _, ok := levels[name]; !ok && name != RootLoggerName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	for _, name := range names {
+		level, ok := levels[name]
+		if !ok {
+			level, ok = levels[RootLoggerName]
+			if !ok {
+				level = InfoLevel.String()
+			}
+		}
+		loggerRefs, ok := refs[name]
+		if !ok {
+			loggerRefs = rootRefs
+		}
+		if name == RootLoggerName {
+			buf.WriteString("<Root")
+			writeAttrs(&buf, map[string]string{"level": level})
+		} else {
+			buf.WriteString("<Logger")
+			writeAttrs(&buf, map[string]string{"name": name, "level": level})
+		}
+		buf.WriteString(">\n")
+		for _, ref := range loggerRefs {
+			writeElement(&buf, "AppenderRef", map[string]string{"ref": ref})
+		}
+		if name == RootLoggerName {
+			buf.WriteString("</Root>\n")
+		} else {
+			buf.WriteString("</Logger>\n")
+		}
+	}
+	buf.WriteString("</Loggers>\n</Configuration>\n")
+	return buf.String(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeAttrs(buf *bytes.Buffer, attrs map[string]string) {
+	for _, k := range sortedKeys(attrs) {
+		buf.WriteString(" " + k + `="`)
+		_ = xml.EscapeText(buf, []byte(attrs[k]))
+		buf.WriteString(`"`)
+	}
+}
+
+func writeElement(buf *bytes.Buffer, name string, attrs map[string]string) {
+	buf.WriteString("<" + name)
+	writeAttrs(buf, attrs)
+	buf.WriteString("/>\n")
+}
+
+func writeFilters(buf *bytes.Buffer, filters map[string]map[string]string) {
+	if len(filters) == 0 {
+		return
+	}
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf.WriteString("<Filters>\n")
+	for _, name := range names {
+		writeElement(buf, name, filters[name])
+	}
+	buf.WriteString("</Filters>\n")
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+func TestLoadProperties(t *testing.T) {
+
+	t.Run("level", func(t *testing.T) {
+		l := log.GetLogger("properties-level")
+		err := log.LoadProperties(map[string]string{
+			"logging.level.properties-level": "warn",
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, l.Level(), log.WarnLevel)
+		err = log.LoadProperties(map[string]string{
+			"logging.level.properties-level": "abc",
+		})
+		assert.Error(t, err, "error level `abc` for logger `properties-level`")
+	})
+
+	t.Run("appenders", func(t *testing.T) {
+		dir := t.TempDir()
+		appFile := filepath.Join(dir, "app.log")
+		errorFile := filepath.Join(dir, "error.log")
+		err := log.LoadProperties(map[string]string{
+			"logging.level.root":                                        "info",
+			"logging.level.properties-debug":                            "debug",
+			"logging.appenders.file.fileName":                           appFile,
+			"logging.appenders.file.maxSize":                            "10MB",
+			"logging.appenders.file.layout":                             "JSONLayout",
+			"logging.appenders.async.refs[0]":                           "file",
+			"logging.appenders.error.type":                              "FileAppender",
+			"logging.appenders.error.fileName":                          errorFile,
+			"logging.appenders.error.filters.LevelRangeFilter.minLevel": "error",
+		})
+		assert.Nil(t, err)
+
+		root := log.GetLogger(log.RootLoggerName)
+		root.Debug("root debug")
+		root.Info("root info")
+		root.Error("root error")
+		l := log.GetLogger("properties-debug")
+		assert.Equal(t, l.Level(), log.DebugLevel)
+		l.Debug("logger debug")
+		log.Flush()
+
+		b, err := ioutil.ReadFile(appFile)
+		assert.Nil(t, err)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		assert.Equal(t, len(lines), 3)
+		var m map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(lines[2]), &m))
+		assert.Equal(t, m["logger"], "properties-debug")
+		assert.Equal(t, m["msg"], "logger debug")
+
+		b, err = ioutil.ReadFile(errorFile)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.Count(string(b), "\n"), 1)
+		assert.True(t, strings.Contains(string(b), "root error"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := log.LoadProperties(map[string]string{
+			"logging.appenders.abc.name": "abc",
+		})
+		assert.Error(t, err, "no appender factory `AbcAppender` found for appender `abc`")
+		err = log.LoadProperties(map[string]string{
+			"logging.appenders.a.type": "AsyncAppender",
+			"logging.appenders.a.refs": "b",
+			"logging.appenders.b.type": "AsyncAppender",
+			"logging.appenders.b.refs": "a",
+		})
+		assert.Error(t, err, "found circular appender ref `a`")
+	})
+}
@@ -19,6 +19,7 @@ package log
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/huazai2008101/stark/base/cast"
 	"github.com/huazai2008101/stark/base/util"
@@ -39,8 +40,11 @@ func (f *RpPetAppenderFactory) NewAppender(config AppenderConfig) (Appender, err
 	return NewRpPetAppender(config.(*RpPetAppenderConfig)), nil
 }
 
+// RpPetAppenderConfig glog 输出配置，配置 Layout 时使用 Layout 格式化日志内容。
 type RpPetAppenderConfig struct {
-	Name string `xml:"name,attr"`
+	AppenderFilters
+	Name   string        `xml:"name,attr"`
+	Layout LayoutElement `xml:",any"`
 }
 
 func (c *RpPetAppenderConfig) GetName() string {
@@ -63,14 +67,25 @@ func (c *RpPetAppender) Append(msg *Message) {
 	} else if level == WarnLevel {
 		logFn = glog.Warning
 	}
-	var buf bytes.Buffer
-	for _, a := range msg.Args() {
-		buf.WriteString(cast.ToString(a))
+	var text string
+	if c.config != nil && c.config.Layout.Layout != nil {
+		b, err := c.config.Layout.Layout.ToBytes(msg)
+		if err != nil {
+			text = fmt.Sprintf("layout error: %v", err)
+		} else {
+			text = strings.TrimSuffix(string(b), "\n")
+		}
+	} else {
+		var buf bytes.Buffer
+		for _, a := range msg.Args() {
+			buf.WriteString(cast.ToString(a))
+		}
+		fileLine := util.Contract(fmt.Sprintf("%s:%d", msg.File(), msg.Line()), 48)
+		text = fmt.Sprintf("[%s] %s %s%s", fileLine, msg.tag, buf.String(), fieldsText(msg.fields))
 	}
-	fileLine := util.Contract(fmt.Sprintf("%s:%d", msg.File(), msg.Line()), 48)
 	if msg.Context() != nil {
-		logFn(msg.Context(), fmt.Sprintf("[%s] %s %s", fileLine, msg.tag, buf.String()))
+		logFn(msg.Context(), text)
 	} else {
-		logFn(fmt.Sprintf("[%s] %s %s", fileLine, msg.tag, buf.String()))
+		logFn(text)
 	}
 }
@@ -0,0 +1,312 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterAppenderFactory("SamplingAppender", new(SamplingAppenderFactory))
+}
+
+// SamplingConfig 采样配置，按调用位置 (file:line) 分别计数，每个周期内前 First 条
+// 日志全部输出，之后每 Thereafter 条输出一条。
+type SamplingConfig struct {
+
+	// First 每个周期内全部输出的日志条数。
+	First int `xml:"first,attr"`
+
+	// Thereafter 超过 First 条之后每隔多少条输出一条，为 0 时全部丢弃。
+	Thereafter int `xml:"thereafter,attr"`
+
+	// Interval 计数周期，默认为 1s 。
+	Interval string `xml:"interval,attr"`
+
+	// SummaryInterval 输出丢弃汇总日志的周期，默认为 1m 。
+	SummaryInterval string `xml:"summaryInterval,attr"`
+}
+
+type sampleSite struct {
+	file       string
+	line       int
+	start      time.Time
+	count      int
+	suppressed uint64
+}
+
+// Sampler 按调用位置对日志进行采样，并定期汇总被丢弃的日志条数。汇总日志在有新的
+// 日志经过 Sampler 时输出，通过 OnSummary 设置回调后，调用位置不再有新的日志时也会
+// 由定时器按周期输出。
+type Sampler struct {
+	config          *SamplingConfig
+	interval        time.Duration
+	summaryInterval time.Duration
+
+	mutex       sync.Mutex
+	sites       map[string]*sampleSite
+	nextSummary time.Time
+	onSummary   func(msgs []*Message)
+	timer       *time.Timer
+	stopped     bool
+}
+
+func NewSampler(config *SamplingConfig) (*Sampler, error) {
+	if config.First < 0 || config.Thereafter < 0 {
+		return nil, fmt.Errorf("error sampling first `%d` thereafter `%d`", config.First, config.Thereafter)
+	}
+	interval, err := parseDuration(config.Interval, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	summaryInterval, err := parseDuration(config.SummaryInterval, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return &Sampler{
+		config:          config,
+		interval:        interval,
+		summaryInterval: summaryInterval,
+		sites:           map[string]*sampleSite{},
+	}, nil
+}
+
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("error duration `%s`", s)
+	}
+	return d, nil
+}
+
+// Sample 返回 msg 是否应该输出，以及到期需要输出的汇总日志。
+func (s *Sampler) Sample(msg *Message) (bool, []*Message) {
+	now := msg.Time()
+	key := fmt.Sprintf("%s:%d", msg.File(), msg.Line())
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	site, ok := s.sites[key]
+	if !ok {
+		site = &sampleSite{file: msg.File(), line: msg.Line(), start: now}
+		s.sites[key] = site
+	}
+	if now.Sub(site.start) >= s.interval {
+		site.start = now
+		site.count = 0
+	}
+	site.count++
+
+	n := site.count - s.config.First
+	allow := n <= 0 || (s.config.Thereafter > 0 && n%s.config.Thereafter == 0)
+	if !allow {
+		site.suppressed++
+		s.startTimer()
+	}
+
+	if s.nextSummary.IsZero() {
+		s.nextSummary = now.Add(s.summaryInterval)
+		return allow, nil
+	}
+	if now.Before(s.nextSummary) {
+		return allow, nil
+	}
+	s.nextSummary = now.Add(s.summaryInterval)
+	return allow, s.summary(now)
+}
+
+// OnSummary 设置定时输出汇总日志的回调。
+func (s *Sampler) OnSummary(fn func(msgs []*Message)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onSummary = fn
+}
+
+// startTimer 有日志被丢弃时启动定时器，到期后输出汇总日志，调用时需要持有锁。
+func (s *Sampler) startTimer() {
+	if s.onSummary == nil || s.timer != nil || s.stopped {
+		return
+	}
+	s.timer = time.AfterFunc(s.summaryInterval, func() {
+		s.mutex.Lock()
+		s.timer = nil
+		if s.stopped {
+			s.mutex.Unlock()
+			return
+		}
+		now := time.Now()
+		msgs := s.summary(now)
+		s.nextSummary = now.Add(s.summaryInterval)
+		fn := s.onSummary
+		s.mutex.Unlock()
+		if len(msgs) > 0 {
+			fn(msgs)
+		}
+	})
+}
+
+// Flush 立即返回尚未输出的汇总日志。
+func (s *Sampler) Flush() []*Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.summary(time.Now())
+}
+
+// Stop 停止定时器并返回尚未输出的汇总日志，之后不再定时输出。
+func (s *Sampler) Stop() []*Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return s.summary(time.Now())
+}
+
+// appendSummaries 将汇总日志输出到 appenders ，name 为空时保留汇总日志原有的 Logger 名称。
+func appendSummaries(name string, appenders []Appender, msgs []*Message) {
+	for _, m := range msgs {
+		if name != "" {
+			m.logger = name
+		}
+		for _, appender := range appenders {
+			appender.Append(m)
+		}
+	}
+}
+
+// summary 生成各个调用位置的丢弃汇总日志，同时清理长时间没有日志的调用位置。
+func (s *Sampler) summary(now time.Time) []*Message {
+	var msgs []*Message
+	for key, site := range s.sites {
+		if site.suppressed > 0 {
+			msgs = append(msgs, NewMessageBuilder().
+				WithLevel(WarnLevel).
+				WithTime(now).
+				WithFile(site.file).
+				WithLine(site.line).
+				WithTag("_sampling").
+				WithArgs([]interface{}{fmt.Sprintf("sampling suppressed %d messages in last %v", site.suppressed, s.summaryInterval)}).
+				Build())
+			site.suppressed = 0
+			continue
+		}
+		if now.Sub(site.start) >= s.interval {
+			delete(s.sites, key)
+		}
+	}
+	return msgs
+}
+
+type SamplingAppenderFactory struct{}
+
+func (f *SamplingAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(SamplingAppenderConfig)
+}
+
+func (f *SamplingAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewSamplingAppender(config.(*SamplingAppenderConfig))
+}
+
+// SamplingAppenderConfig 采样输出配置，引用的 Appender 需要先于 SamplingAppender 声明，例如：
+//
+//	<SamplingAppender name="sampling" first="10" thereafter="100" interval="1s">
+//	    <AppenderRef ref="file"/>
+//	</SamplingAppender>
+type SamplingAppenderConfig struct {
+	AppenderFilters
+	SamplingConfig
+	Name string `xml:"name,attr"`
+
+	AppenderRefs []struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"AppenderRef"`
+
+	appenders []Appender
+}
+
+func (c *SamplingAppenderConfig) GetName() string {
+	return c.Name
+}
+
+func (c *SamplingAppenderConfig) GetAppenderRefs() []string {
+	var refs []string
+	for _, ref := range c.AppenderRefs {
+		refs = append(refs, ref.Ref)
+	}
+	return refs
+}
+
+func (c *SamplingAppenderConfig) SetAppenders(appenders []Appender) {
+	c.appenders = appenders
+}
+
+// SamplingAppender 对日志进行采样后输出到引用的 Appender 。
+type SamplingAppender struct {
+	sampler   *Sampler
+	appenders []Appender
+}
+
+func NewSamplingAppender(config *SamplingAppenderConfig, appenders ...Appender) (*SamplingAppender, error) {
+	if len(appenders) == 0 {
+		appenders = config.appenders
+	}
+	if len(appenders) == 0 {
+		return nil, fmt.Errorf("no appender ref for SamplingAppender `%s`", config.Name)
+	}
+	sampler, err := NewSampler(&config.SamplingConfig)
+	if err != nil {
+		return nil, err
+	}
+	a := &SamplingAppender{sampler: sampler, appenders: appenders}
+	sampler.OnSummary(func(msgs []*Message) {
+		appendSummaries("", a.appenders, msgs)
+	})
+	return a, nil
+}
+
+func (a *SamplingAppender) Append(msg *Message) {
+	ok, summaries := a.sampler.Sample(msg)
+	appendSummaries("", a.appenders, summaries)
+	if !ok {
+		return
+	}
+	for _, appender := range a.appenders {
+		appender.Append(msg)
+	}
+}
+
+// Flush 输出尚未输出的汇总日志。
+func (a *SamplingAppender) Flush() {
+	appendSummaries("", a.appenders, a.sampler.Flush())
+}
+
+// Close 停止定时器并输出尚未输出的汇总日志，引用的 Appender 由其自身关闭。
+func (a *SamplingAppender) Close() error {
+	appendSummaries("", a.appenders, a.sampler.Stop())
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+func TestSampler(t *testing.T) {
+	s, err := log.NewSampler(&log.SamplingConfig{
+		First:           2,
+		Thereafter:      3,
+		Interval:        "1s",
+		SummaryInterval: "10s",
+	})
+	assert.Nil(t, err)
+
+	now := time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)
+	sample := func(line int, d time.Duration) (bool, []*log.Message) {
+		msg := log.NewMessageBuilder().
+			WithTime(now.Add(d)).
+			WithFile("a.go").
+			WithLine(line).
+			Build()
+		return s.Sample(msg)
+	}
+
+	var allowed []int
+	for i := 1; i <= 8; i++ {
+		if ok, _ := sample(10, 0); ok {
+			allowed = append(allowed, i)
+		}
+	}
+	assert.Equal(t, allowed, []int{1, 2, 5, 8})
+
+	// 不同的调用位置分别计数。
+	ok, _ := sample(20, 0)
+	assert.True(t, ok)
+
+	// 新的周期重新计数。
+	ok, _ = sample(10, 2*time.Second)
+	assert.True(t, ok)
+
+	ok, summaries := sample(20, 10*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, len(summaries), 1)
+	assert.Equal(t, summaries[0].Level(), log.WarnLevel)
+	assert.Equal(t, summaries[0].Line(), 10)
+	assert.Equal(t, summaries[0].Args(), []interface{}{"sampling suppressed 4 messages in last 10s"})
+}
+
+func TestSampler_Flush(t *testing.T) {
+	s, err := log.NewSampler(&log.SamplingConfig{First: 1, SummaryInterval: "1h"})
+	assert.Nil(t, err)
+
+	msg := log.NewMessageBuilder().WithTime(time.Now()).WithFile("a.go").WithLine(10).Build()
+	for i := 0; i < 3; i++ {
+		s.Sample(msg)
+	}
+	summaries := s.Flush()
+	assert.Equal(t, len(summaries), 1)
+	assert.Equal(t, summaries[0].Args(), []interface{}{"sampling suppressed 2 messages in last 1h0m0s"})
+	assert.Equal(t, len(s.Flush()), 0)
+}
+
+func TestSamplingAppender_Summary(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s"/>
+				<SamplingAppender name="sampling" first="1" thereafter="0" interval="1h" summaryInterval="50ms">
+					<AppenderRef ref="file"/>
+				</SamplingAppender>
+			</Appenders>
+			<Loggers>
+				<Logger name="quiet" level="info">
+					<AppenderRef ref="file"/>
+					<Sampling first="1" thereafter="0" interval="1h" summaryInterval="1h"/>
+				</Logger>
+				<Root level="info">
+					<AppenderRef ref="sampling"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName))
+	assert.Nil(t, err)
+
+	// 突发之后调用位置不再有日志，汇总日志由定时器输出
+	for i := 0; i < 5; i++ {
+		log.GetLogger(log.RootLoggerName).Info("burst")
+	}
+	// 汇总周期很长时，Flush 输出尚未输出的汇总日志
+	for i := 0; i < 4; i++ {
+		log.GetLogger("quiet").Info("quiet")
+	}
+	time.Sleep(200 * time.Millisecond)
+	log.Flush()
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Count(string(b), "burst"), 1)
+	assert.True(t, strings.Contains(string(b), "sampling suppressed 4 messages"))
+	assert.True(t, strings.Contains(string(b), "sampling suppressed 3 messages"))
+}
+
+func TestSampler_Load(t *testing.T) {
+	sampled := log.GetLogger("sampled")
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := log.Load(fmt.Sprintf(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<FileAppender name="file" fileName="%s"/>
+				<SamplingAppender name="sampling" first="1" thereafter="0" interval="1h">
+					<AppenderRef ref="file"/>
+				</SamplingAppender>
+			</Appenders>
+			<Loggers>
+				<Logger name="sampled" level="info">
+					<AppenderRef ref="file"/>
+					<Sampling first="3" thereafter="0" interval="1h"/>
+				</Logger>
+				<Root level="info">
+					<AppenderRef ref="sampling"/>
+				</Root>
+			</Loggers>
+		</Configuration>
+	`, fileName))
+	assert.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		sampled.Info("logger")
+		log.GetLogger(log.RootLoggerName).Info("appender")
+	}
+
+	b, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Count(string(b), "logger"), 3)
+	assert.Equal(t, strings.Count(string(b), "appender"), 1)
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterAppenderFactory("SocketAppender", new(SocketAppenderFactory))
+}
+
+// errWaitReconnect 表示连接失败后还没有到再次连接的时间。
+var errWaitReconnect = errors.New("waiting to reconnect")
+
+// NetworkConfig 网络连接配置。
+type NetworkConfig struct {
+
+	// Network 网络类型，支持 tcp、udp、unix、unixgram 。
+	Network string `xml:"network,attr"`
+
+	// Address 连接地址，unix 网络时为 socket 文件路径。
+	Address string `xml:"address,attr"`
+
+	// DialTimeout 连接和写入的超时时间，默认为 1s 。
+	DialTimeout string `xml:"dialTimeout,attr"`
+
+	// ReconnectDelay 连接失败后再次尝试连接的间隔，期间的日志会被丢弃，默认为 1s 。
+	ReconnectDelay string `xml:"reconnectDelay,attr"`
+}
+
+// netWriter 维护一个网络连接，写入失败时断开并重新连接。
+type netWriter struct {
+	network        string
+	address        string
+	dialTimeout    time.Duration
+	reconnectDelay time.Duration
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	nextDial time.Time
+	closed   bool
+}
+
+func newNetWriter(config *NetworkConfig, defaultNetwork string) (*netWriter, error) {
+	if config.Network == "" {
+		config.Network = defaultNetwork
+	}
+	switch config.Network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("error network `%s`", config.Network)
+	}
+	if config.Address == "" {
+		return nil, errors.New("address is empty")
+	}
+	dialTimeout, err := parseDuration(config.DialTimeout, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	reconnectDelay, err := parseDuration(config.ReconnectDelay, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &netWriter{
+		network:        config.Network,
+		address:        config.Address,
+		dialTimeout:    dialTimeout,
+		reconnectDelay: reconnectDelay,
+	}, nil
+}
+
+// isStream 返回是否为面向流的网络。
+func (w *netWriter) isStream() bool {
+	return strings.HasPrefix(w.network, "tcp") || w.network == "unix"
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	// 连接可能已经被对端关闭，写入失败时重新连接并再写一次。
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if err = w.dial(); err != nil {
+				return 0, err
+			}
+		}
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.dialTimeout))
+		var n int
+		if n, err = w.conn.Write(p); err == nil {
+			return n, nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *netWriter) dial() error {
+	now := time.Now()
+	if now.Before(w.nextDial) {
+		return errWaitReconnect
+	}
+	conn, err := net.DialTimeout(w.network, w.address, w.dialTimeout)
+	if err != nil {
+		w.nextDial = now.Add(w.reconnectDelay)
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *netWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+type SocketAppenderFactory struct{}
+
+func (f *SocketAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(SocketAppenderConfig)
+}
+
+func (f *SocketAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewSocketAppender(config.(*SocketAppenderConfig))
+}
+
+// SocketAppenderConfig 网络输出配置，例如：
+//
+//	<SocketAppender name="socket" network="tcp" address="127.0.0.1:5170"/>
+type SocketAppenderConfig struct {
+	AppenderFilters
+	NetworkConfig
+	Name string `xml:"name,attr"`
+
+	// Layout 日志格式，以子元素的形式配置，默认为 JSONLayout 。
+	Layout LayoutElement `xml:",any"`
+}
+
+func (c *SocketAppenderConfig) GetName() string {
+	return c.Name
+}
+
+// SocketAppender 通过 TCP 或者 UDP 输出日志，默认每条日志为一行 JSON 。连接断开后
+// 自动重新连接，写入是同步的，可以配合 AsyncAppender 使用。
+type SocketAppender struct {
+	config *SocketAppenderConfig
+	layout Layout
+	writer *netWriter
+}
+
+func NewSocketAppender(config *SocketAppenderConfig) (*SocketAppender, error) {
+	writer, err := newNetWriter(&config.NetworkConfig, "tcp")
+	if err != nil {
+		return nil, fmt.Errorf("SocketAppender `%s` %w", config.Name, err)
+	}
+	layout := config.Layout.Layout
+	if layout == nil {
+		layout = NewJSONLayout(new(JSONLayoutConfig))
+	}
+	return &SocketAppender{config: config, layout: layout, writer: writer}, nil
+}
+
+func (c *SocketAppender) Append(msg *Message) {
+	b, err := c.layout.ToBytes(msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "SocketAppender layout error: %v\n", err)
+		return
+	}
+	if _, err = c.writer.Write(b); err != nil && err != errWaitReconnect {
+		fmt.Fprintf(os.Stderr, "SocketAppender write error: %v\n", err)
+	}
+}
+
+func (c *SocketAppender) Close() error {
+	return c.writer.Close()
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterAppenderFactory("SyslogAppender", new(SyslogAppenderFactory))
+}
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverity 返回日志级别对应的 syslog severity 。
+func syslogSeverity(level Level) int {
+	switch level {
+	case FatalLevel:
+		return 1 // alert
+	case PanicLevel:
+		return 2 // crit
+	case ErrorLevel:
+		return 3 // err
+	case WarnLevel:
+		return 4 // warning
+	case InfoLevel:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+type SyslogAppenderFactory struct{}
+
+func (f *SyslogAppenderFactory) NewAppenderConfig() AppenderConfig {
+	return new(SyslogAppenderConfig)
+}
+
+func (f *SyslogAppenderFactory) NewAppender(config AppenderConfig) (Appender, error) {
+	return NewSyslogAppender(config.(*SyslogAppenderConfig))
+}
+
+// SyslogAppenderConfig syslog 输出配置，例如：
+//
+//	<SyslogAppender name="syslog" network="udp" address="127.0.0.1:514" facility="local0"/>
+type SyslogAppenderConfig struct {
+	AppenderFilters
+	NetworkConfig
+	Name string `xml:"name,attr"`
+
+	// Facility 默认为 user 。
+	Facility string `xml:"facility,attr"`
+
+	// AppName 默认为程序的文件名。
+	AppName string `xml:"appName,attr"`
+
+	// Hostname 默认为 os.Hostname() 。
+	Hostname string `xml:"hostname,attr"`
+
+	// Layout 日志格式，以子元素的形式配置，默认为 [file:line] message key=value 。
+	Layout LayoutElement `xml:",any"`
+}
+
+func (c *SyslogAppenderConfig) GetName() string {
+	return c.Name
+}
+
+// SyslogAppender 按照 RFC5424 的格式通过 UDP、TCP 或者 unix socket 输出日志，面向流
+// 的连接使用 RFC6587 的 octet counting 分帧。
+type SyslogAppender struct {
+	config   *SyslogAppenderConfig
+	facility int
+	procId   int
+	writer   *netWriter
+}
+
+func NewSyslogAppender(config *SyslogAppenderConfig) (*SyslogAppender, error) {
+	facility, ok := syslogFacilities[strings.ToLower(config.Facility)]
+	if config.Facility == "" {
+		facility, ok = syslogFacilities["user"], true
+	}
+	if !ok {
+		return nil, fmt.Errorf("error facility `%s` for SyslogAppender `%s`", config.Facility, config.Name)
+	}
+	writer, err := newNetWriter(&config.NetworkConfig, "udp")
+	if err != nil {
+		return nil, fmt.Errorf("SyslogAppender `%s` %w", config.Name, err)
+	}
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	return &SyslogAppender{
+		config:   config,
+		facility: facility,
+		procId:   os.Getpid(),
+		writer:   writer,
+	}, nil
+}
+
+func (c *SyslogAppender) Append(msg *Message) {
+	b, err := c.format(msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "SyslogAppender layout error: %v\n", err)
+		return
+	}
+	if c.writer.isStream() {
+		b = append([]byte(fmt.Sprintf("%d ", len(b))), b...)
+	}
+	if _, err = c.writer.Write(b); err != nil && err != errWaitReconnect {
+		fmt.Fprintf(os.Stderr, "SyslogAppender write error: %v\n", err)
+	}
+}
+
+// format 返回 RFC5424 格式的日志，
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+func (c *SyslogAppender) format(msg *Message) ([]byte, error) {
+	var text string
+	if layout := c.config.Layout.Layout; layout != nil {
+		b, err := layout.ToBytes(msg)
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimSuffix(string(b), "\n")
+	} else {
+		text = fmt.Sprintf("[%s:%d] %s%s", msg.File(), msg.Line(), messageText(msg), fieldsText(msg.Fields()))
+	}
+	var buf bytes.Buffer
+	pri := c.facility*8 + syslogSeverity(msg.Level())
+	strTime := msg.Time().Format("2006-01-02T15:04:05.000000Z07:00")
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s - %s",
+		pri, strTime,
+		syslogHeader(c.config.Hostname, 255),
+		syslogHeader(c.config.AppName, 48),
+		c.procId,
+		syslogHeader(msg.Tag(), 32),
+		text)
+	return buf.Bytes(), nil
+}
+
+// syslogHeader 返回符合 RFC5424 要求的头部字段，空值使用 - 表示。
+func syslogHeader(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+func (c *SyslogAppender) Close() error {
+	return c.writer.Close()
+}
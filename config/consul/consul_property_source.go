@@ -0,0 +1,164 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// FormatKeys 每个 key 对应一个属性，例如 config/{app}/server/port 对应 server.port
+const FormatKeys = "keys"
+
+// profilesPath keys 格式下 profile 属性所在的子路径
+const profilesPath = "profiles"
+
+// consulPropertySource 从Consul KV加载属性，profile 的配置覆盖默认配置。
+// format 为 yaml、properties、toml 等格式时 key 的值为整个配置文档，默认配置保存在
+// {prefix}/{app}，profile 的配置保存在 {prefix}/{app}/{profile}；为 keys 时
+// {prefix}/{app}/ 下的每个 key 对应一个属性，profile 的属性保存在
+// {prefix}/{app}/profiles/{profile}/ 下，默认配置不包含 profiles/ 下的任何 key 。
+type consulPropertySource struct {
+	config   consulConfig
+	client   *api.Client
+	profiles []string
+	index    uint64
+}
+
+type consulConfig struct {
+	appName   string        `value:"${application.name}"`
+	url       string        `value:"${config.consul.url:=${discovery.url:=127.0.0.1:8500}}"`
+	token     string        `value:"${config.consul.token:=}"`
+	prefix    string        `value:"${config.consul.prefix:=config}"`
+	format    string        `value:"${config.consul.format:=yaml}"`
+	waitTime  time.Duration `value:"${config.consul.wait-time:=55s}"`
+	retryTime time.Duration `value:"${config.consul.retry-time:=5s}"`
+}
+
+func NewConsulPropertySource() ioc.PropertySource {
+	return &consulPropertySource{}
+}
+
+// 根路径，默认配置和profile配置都在该路径下
+func (s *consulPropertySource) base() string {
+	return strings.Trim(s.config.prefix, "/") + "/" + s.config.appName
+}
+
+func (s *consulPropertySource) Load(p *conf.Properties, profiles []string) (*conf.Properties, error) {
+	if err := p.Bind(&s.config); err != nil {
+		return nil, err
+	}
+	if s.config.appName == "" {
+		return nil, fmt.Errorf("consulPropertySource application.name不能为空")
+	}
+	s.profiles = profiles
+
+	config := api.DefaultConfig()
+	config.Address = s.config.url
+	config.Token = s.config.token
+	var err error
+	s.client, err = api.NewClient(config)
+	if err != nil {
+		log.Errorf(context.Background(), "consulPropertySource 实例化consul客户端异常:%+v", err)
+		return nil, err
+	}
+
+	pairs, meta, err := s.client.KV().List(s.base(), nil)
+	if err != nil {
+		log.Errorf(context.Background(), "consulPropertySource 加载配置异常:%+v key:%s", err, s.base())
+		return nil, err
+	}
+	s.index = meta.LastIndex
+	return s.toProperties(pairs)
+}
+
+// Watch 使用阻塞查询监听配置的变化
+func (s *consulPropertySource) Watch(ctx context.Context, fn func(p *conf.Properties)) {
+	for {
+		opts := (&api.QueryOptions{WaitIndex: s.index, WaitTime: s.config.waitTime}).WithContext(ctx)
+		pairs, meta, err := s.client.KV().List(s.base(), opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Errorf(ctx, "consulPropertySource 监听配置异常:%+v key:%s", err, s.base())
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.config.retryTime):
+			}
+			continue
+		}
+		if meta.LastIndex == s.index {
+			continue
+		}
+		// 索引变小说明consul的数据被重置，需要重新开始监听
+		if meta.LastIndex < s.index {
+			s.index = 0
+			continue
+		}
+		s.index = meta.LastIndex
+		p, err := s.toProperties(pairs)
+		if err != nil {
+			log.Errorf(ctx, "consulPropertySource 解析配置异常:%+v key:%s", err, s.base())
+			continue
+		}
+		fn(p)
+	}
+}
+
+// toProperties 按照默认配置、profile配置的顺序合并属性
+func (s *consulPropertySource) toProperties(pairs api.KVPairs) (*conf.Properties, error) {
+	base := s.base()
+	var keys []string
+	values := make(map[string][]byte)
+	for _, pair := range pairs {
+		if pair.Key == base || strings.HasPrefix(pair.Key, base+"/") {
+			keys = append(keys, pair.Key)
+			values[pair.Key] = pair.Value
+		}
+	}
+	sort.Strings(keys)
+
+	p := conf.New()
+	contexts := []string{base}
+	for _, profile := range s.profiles {
+		if s.config.format == FormatKeys {
+			contexts = append(contexts, base+"/"+profilesPath+"/"+profile)
+		} else {
+			contexts = append(contexts, base+"/"+profile)
+		}
+	}
+	for i, c := range contexts {
+		if s.config.format != FormatKeys {
+			b, ok := values[c]
+			if !ok {
+				continue
+			}
+			if err := p.Bytes(b, "."+s.config.format); err != nil {
+				return nil, fmt.Errorf("%s %w", c, err)
+			}
+			continue
+		}
+		for _, k := range keys {
+			if !strings.HasPrefix(k, c+"/") || strings.HasSuffix(k, "/") {
+				continue
+			}
+			key := strings.TrimPrefix(k, c+"/")
+			// 默认配置中跳过所有profile(包括未激活的)的key
+			if i == 0 && strings.HasPrefix(key, profilesPath+"/") {
+				continue
+			}
+			if err := p.Set(strings.ReplaceAll(key, "/", "."), string(values[k])); err != nil {
+				return nil, fmt.Errorf("%s %w", k, err)
+			}
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,169 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// fakeConsul 模拟Consul KV的递归查询和阻塞查询
+type fakeConsul struct {
+	mutex   sync.Mutex
+	index   uint64
+	values  map[string]string
+	changed chan struct{}
+}
+
+func newFakeConsul(t *testing.T, values map[string]string) (*fakeConsul, string) {
+	c := &fakeConsul{index: 1, values: values, changed: make(chan struct{})}
+	s := httptest.NewServer(http.HandlerFunc(c.serveKV))
+	t.Cleanup(s.Close)
+	return c, strings.TrimPrefix(s.URL, "http://")
+}
+
+func (c *fakeConsul) put(key, value string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] = value
+	c.index++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *fakeConsul) serveKV(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	c.mutex.Lock()
+	if index > 0 && index == c.index {
+		changed := c.changed
+		c.mutex.Unlock()
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		c.mutex.Lock()
+	}
+	var pairs api.KVPairs
+	for k, v := range c.values {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: k, Value: []byte(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	c.mutex.Unlock()
+
+	w.Header().Set("X-Consul-LastContact", "0")
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func load(t *testing.T, url, format string, profiles ...string) (*consulPropertySource, *conf.Properties) {
+	p := conf.New()
+	_ = p.Set("application.name", "demo")
+	_ = p.Set("config.consul.url", url)
+	_ = p.Set("config.consul.format", format)
+	_ = p.Set("config.consul.wait-time", "1s")
+	s := NewConsulPropertySource().(*consulPropertySource)
+	r, err := s.Load(p, profiles)
+	assert.Nil(t, err)
+	return s, r
+}
+
+func TestConsulPropertySource_Keys(t *testing.T) {
+	_, url := newFakeConsul(t, map[string]string{
+		"config/demo/server/port":                "8080",
+		"config/demo/db/url":                     "default",
+		"config/demo/profiles/dev/db/url":        "dev",
+		"config/demo/profiles/prod/db/url":       "prod",
+		"config/demo/profiles/prod/db/password":  "secret",
+		"config/demo/dir/":                       "",
+		"config/other/server/port":               "9090",
+		"config/demo-suffix/should/not/be/found": "x",
+	})
+
+	_, p := load(t, url, FormatKeys, "dev")
+	assert.Equal(t, p.Get("server.port"), "8080")
+	assert.Equal(t, p.Get("db.url"), "dev")
+	// 未激活的profile不能混入默认配置
+	for _, key := range []string{"db.password", "profiles.prod.db.url", "prod.db.url", "should.not.be.found"} {
+		assert.False(t, p.Has(key), key)
+	}
+}
+
+func TestConsulPropertySource_Document(t *testing.T) {
+	_, url := newFakeConsul(t, map[string]string{
+		"config/demo":      "server:\n  port: 8080\ndb:\n  url: default\n",
+		"config/demo/dev":  "db:\n  url: dev\n",
+		"config/demo/prod": "db:\n  url: prod\n  password: secret\n",
+	})
+
+	_, p := load(t, url, "yaml", "dev")
+	assert.Equal(t, p.Get("server.port"), "8080")
+	assert.Equal(t, p.Get("db.url"), "dev")
+	assert.False(t, p.Has("db.password"))
+}
+
+func TestConsulPropertySource_Watch(t *testing.T) {
+	c, url := newFakeConsul(t, map[string]string{
+		"config/demo/server/port": "8080",
+	})
+	s, _ := load(t, url, FormatKeys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan *conf.Properties, 1)
+	go s.Watch(ctx, func(p *conf.Properties) { ch <- p })
+
+	// 阻塞查询在数据变化后立即返回
+	c.put("config/demo/server/port", "9090")
+	select {
+	case p := <-ch:
+		assert.Equal(t, p.Get("server.port"), "9090")
+	case <-time.After(3 * time.Second):
+		t.Fatal("watch timeout")
+	}
+}
+
+func TestConsulPropertySource_LiveUpdate(t *testing.T) {
+	c, url := newFakeConsul(t, map[string]string{
+		"config/demo/server/port": "8080",
+	})
+
+	app := ioc.NewApp()
+	app.Property("application.name", "demo")
+	app.Property("config.consul.url", url)
+	app.Property("config.consul.format", FormatKeys)
+	app.Property("config.consul.wait-time", "1s")
+	app.AddPropertySource(NewConsulPropertySource())
+
+	ports := make(chan int, 2)
+	app.OnProperty("server.port", func(port int) { ports <- port })
+	assert.Nil(t, app.Start())
+	defer app.Stop()
+
+	assert.Equal(t, <-ports, 8080)
+	c.put("config/demo/server/port", "9090")
+	select {
+	case port := <-ports:
+		assert.Equal(t, port, 9090)
+	case <-time.After(3 * time.Second):
+		t.Fatal("live update timeout")
+	}
+}
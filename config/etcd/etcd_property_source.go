@@ -0,0 +1,184 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdPropertySource 从etcd加载属性，所有配置保存在 {prefix}/{namespace}/{app}/ 下。
+// 扩展名能被 conf.Reader 解析的 key 作为整个配置文档，例如 application.yaml、
+// application-{profile}.yaml；其他 key 每个对应一个属性，例如 server/port 对应
+// server.port，profiles/{profile}/server/port 为 profile 的配置，默认配置不包含
+// profiles/ 下的任何 key 。profile 的配置覆盖默认配置，单个属性覆盖配置文档中的属性。
+type etcdPropertySource struct {
+	config   etcdConfig
+	client   *clientv3.Client
+	profiles []string
+	revision int64
+}
+
+type etcdConfig struct {
+	appName     string        `value:"${application.name}"`
+	url         string        `value:"${config.etcd.url:=${discovery.url:=127.0.0.1:2379}}"`
+	namespace   string        `value:"${config.etcd.namespace:=${discovery.namespace:=default}}"`
+	prefix      string        `value:"${config.etcd.prefix:=config}"`
+	dialTimeout time.Duration `value:"${config.etcd.dial-timeout:=5s}"`
+	retryTime   time.Duration `value:"${config.etcd.retry-time:=5s}"`
+}
+
+// profilesPath profile 属性所在的子路径
+const profilesPath = "profiles/"
+
+func NewEtcdPropertySource() ioc.PropertySource {
+	return &etcdPropertySource{}
+}
+
+// NewEtcdPropertySourceWithClient 使用已有的etcd客户端创建属性源，例如连接内嵌的etcd。
+func NewEtcdPropertySourceWithClient(client *clientv3.Client) ioc.PropertySource {
+	return &etcdPropertySource{client: client}
+}
+
+// 根路径，以 / 结尾
+func (s *etcdPropertySource) base() string {
+	return path.Join(strings.Trim(s.config.prefix, "/"), s.config.namespace, s.config.appName) + "/"
+}
+
+func (s *etcdPropertySource) Load(p *conf.Properties, profiles []string) (*conf.Properties, error) {
+	if err := p.Bind(&s.config); err != nil {
+		return nil, err
+	}
+	if s.config.appName == "" {
+		return nil, fmt.Errorf("etcdPropertySource application.name不能为空")
+	}
+	s.profiles = profiles
+
+	if s.client == nil {
+		var err error
+		s.client, err = clientv3.New(clientv3.Config{
+			Endpoints:   strings.Split(s.config.url, ","),
+			DialTimeout: s.config.dialTimeout,
+		})
+		if err != nil {
+			log.Errorf(context.Background(), "etcdPropertySource 实例化etcd客户端异常:%+v", err)
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.dialTimeout)
+	defer cancel()
+	return s.load(ctx)
+}
+
+// load 读取根路径下的所有 key 并记录读取时的版本号
+func (s *etcdPropertySource) load(ctx context.Context) (*conf.Properties, error) {
+	resp, err := s.client.Get(ctx, s.base(), clientv3.WithPrefix())
+	if err != nil {
+		log.Errorf(ctx, "etcdPropertySource 加载配置异常:%+v key:%s", err, s.base())
+		return nil, err
+	}
+	s.revision = resp.Header.Revision
+	values := make(map[string][]byte)
+	for _, kv := range resp.Kvs {
+		values[strings.TrimPrefix(string(kv.Key), s.base())] = kv.Value
+	}
+	return s.toProperties(values)
+}
+
+// Watch 监听根路径下 key 的变化，发生变化后重新读取全部配置
+func (s *etcdPropertySource) Watch(ctx context.Context, fn func(p *conf.Properties)) {
+	for {
+		s.watch(ctx, fn)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.retryTime):
+		}
+	}
+}
+
+func (s *etcdPropertySource) watch(ctx context.Context, fn func(p *conf.Properties)) {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	ch := s.client.Watch(ctx, s.base(), clientv3.WithPrefix(), clientv3.WithRev(s.revision+1))
+	for resp := range ch {
+		if err := resp.Err(); err != nil {
+			// 版本被压缩时从当前版本重新加载
+			if err == rpctypes.ErrCompacted {
+				s.reload(ctx, fn)
+				return
+			}
+			log.Errorf(ctx, "etcdPropertySource 监听配置异常:%+v key:%s", err, s.base())
+			return
+		}
+		if len(resp.Events) == 0 {
+			continue
+		}
+		s.reload(ctx, fn)
+	}
+}
+
+func (s *etcdPropertySource) reload(ctx context.Context, fn func(p *conf.Properties)) {
+	p, err := s.load(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorf(ctx, "etcdPropertySource 解析配置异常:%+v key:%s", err, s.base())
+		}
+		return
+	}
+	fn(p)
+}
+
+// toProperties 按照默认配置、profile配置的顺序合并属性，同一个 profile 中先合并配置文档再合并单个属性
+func (s *etcdPropertySource) toProperties(values map[string][]byte) (*conf.Properties, error) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := conf.New()
+	for _, profile := range append([]string{""}, s.profiles...) {
+		name := "application"
+		if profile != "" {
+			name += "-" + profile
+		}
+		for _, k := range keys {
+			ext := path.Ext(k)
+			if !conf.HasReader(ext) || strings.TrimSuffix(k, ext) != name {
+				continue
+			}
+			if err := p.Bytes(values[k], ext); err != nil {
+				return nil, fmt.Errorf("%s %w", s.base()+k, err)
+			}
+		}
+		for _, k := range keys {
+			if k == "" || strings.HasSuffix(k, "/") || conf.HasReader(path.Ext(k)) {
+				continue
+			}
+			key := k
+			if profile != "" {
+				if !strings.HasPrefix(k, profilesPath+profile+"/") {
+					continue
+				}
+				key = strings.TrimPrefix(k, profilesPath+profile+"/")
+			} else if strings.HasPrefix(k, profilesPath) {
+				// 默认配置中跳过所有profile(包括未激活的)的key
+				continue
+			}
+			if err := p.Set(strings.ReplaceAll(key, "/", "."), string(values[k])); err != nil {
+				return nil, fmt.Errorf("%s %w", s.base()+k, err)
+			}
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,203 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// embeddedEtcd 内嵌在测试进程中的etcd服务，实现属性源用到的 KV 和 Watch 接口
+type embeddedEtcd struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedWatchServer
+
+	mutex    sync.Mutex
+	revision int64
+	values   map[string]string
+	watchers map[chan *mvccpb.Event]struct{}
+	addr     string
+}
+
+func startEtcd(t *testing.T, values map[string]string) *embeddedEtcd {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	e := &embeddedEtcd{
+		revision: 1,
+		values:   values,
+		watchers: map[chan *mvccpb.Event]struct{}{},
+		addr:     l.Addr().String(),
+	}
+	s := grpc.NewServer()
+	pb.RegisterKVServer(s, e)
+	pb.RegisterWatchServer(s, e)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return e
+}
+
+func (e *embeddedEtcd) client(t *testing.T) *clientv3.Client {
+	c, err := clientv3.New(clientv3.Config{Endpoints: []string{e.addr}, DialTimeout: time.Second})
+	assert.Nil(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func (e *embeddedEtcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{Revision: e.revision}
+}
+
+func inRange(key, start, end []byte) bool {
+	if len(end) == 0 {
+		return bytes.Equal(key, start)
+	}
+	return bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0
+}
+
+func (e *embeddedEtcd) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var kvs []*mvccpb.KeyValue
+	for k, v := range e.values {
+		if inRange([]byte(k), r.Key, r.RangeEnd) {
+			kvs = append(kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v), ModRevision: e.revision})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0 })
+	return &pb.RangeResponse{Header: e.header(), Kvs: kvs, Count: int64(len(kvs))}, nil
+}
+
+func (e *embeddedEtcd) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.revision++
+	e.values[string(r.Key)] = string(r.Value)
+	event := &mvccpb.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: r.Key, Value: r.Value, ModRevision: e.revision}}
+	for ch := range e.watchers {
+		ch <- event
+	}
+	return &pb.PutResponse{Header: e.header()}, nil
+}
+
+func (e *embeddedEtcd) Watch(s pb.Watch_WatchServer) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	create := req.GetCreateRequest()
+	ch := make(chan *mvccpb.Event, 16)
+	e.mutex.Lock()
+	e.watchers[ch] = struct{}{}
+	header := e.header()
+	e.mutex.Unlock()
+	defer func() {
+		e.mutex.Lock()
+		delete(e.watchers, ch)
+		e.mutex.Unlock()
+	}()
+
+	if err = s.Send(&pb.WatchResponse{Header: header, WatchId: 1, Created: true}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-s.Context().Done():
+			return nil
+		case event := <-ch:
+			if !inRange(event.Kv.Key, create.Key, create.RangeEnd) {
+				continue
+			}
+			resp := &pb.WatchResponse{
+				Header:  &pb.ResponseHeader{Revision: event.Kv.ModRevision},
+				WatchId: 1,
+				Events:  []*mvccpb.Event{event},
+			}
+			if err = s.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func load(t *testing.T, e *embeddedEtcd, profiles ...string) (*etcdPropertySource, *conf.Properties) {
+	p := conf.New()
+	_ = p.Set("application.name", "demo")
+	s := NewEtcdPropertySourceWithClient(e.client(t)).(*etcdPropertySource)
+	r, err := s.Load(p, profiles)
+	assert.Nil(t, err)
+	return s, r
+}
+
+func TestEtcdPropertySource_Keys(t *testing.T) {
+	e := startEtcd(t, map[string]string{
+		"config/default/demo/server/port":               "8080",
+		"config/default/demo/db/url":                    "default",
+		"config/default/demo/profiles/dev/db/url":       "dev",
+		"config/default/demo/profiles/prod/db/url":      "prod",
+		"config/default/demo/profiles/prod/db/password": "secret",
+		"config/default/other/server/port":              "9090",
+	})
+
+	_, p := load(t, e, "dev")
+	assert.Equal(t, p.Get("server.port"), "8080")
+	assert.Equal(t, p.Get("db.url"), "dev")
+	// 未激活的profile不能混入默认配置
+	for _, key := range []string{"db.password", "profiles.prod.db.url", "prod.db.url"} {
+		assert.False(t, p.Has(key), key)
+	}
+}
+
+func TestEtcdPropertySource_Document(t *testing.T) {
+	e := startEtcd(t, map[string]string{
+		"config/default/demo/application.yaml":      "server:\n  port: 8080\ndb:\n  url: default\n  user: root\n",
+		"config/default/demo/application-dev.yaml":  "db:\n  url: dev\n",
+		"config/default/demo/application-prod.yaml": "db:\n  url: prod\n",
+		"config/default/demo/db/user":               "admin",
+	})
+
+	_, p := load(t, e, "dev")
+	assert.Equal(t, p.Get("server.port"), "8080")
+	assert.Equal(t, p.Get("db.url"), "dev")
+	// 单个属性覆盖配置文档中的属性
+	assert.Equal(t, p.Get("db.user"), "admin")
+}
+
+func TestEtcdPropertySource_Watch(t *testing.T) {
+	e := startEtcd(t, map[string]string{
+		"config/default/demo/server/port": "8080",
+	})
+
+	app := ioc.NewApp()
+	app.Property("application.name", "demo")
+	app.Property("config.etcd.url", e.addr)
+	app.AddPropertySource(NewEtcdPropertySource())
+
+	ports := make(chan int, 2)
+	app.OnProperty("server.port", func(port int) { ports <- port })
+	assert.Nil(t, app.Start())
+	defer app.Stop()
+	assert.Equal(t, <-ports, 8080)
+
+	// 其他应用的变化不会触发刷新
+	_, err := e.client(t).Put(context.Background(), "config/default/other/server/port", "7070")
+	assert.Nil(t, err)
+	_, err = e.client(t).Put(context.Background(), "config/default/demo/server/port", "9090")
+	assert.Nil(t, err)
+	select {
+	case port := <-ports:
+		assert.Equal(t, port, 9090)
+	case <-time.After(3 * time.Second):
+		t.Fatal("watch timeout")
+	}
+}
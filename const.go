@@ -1,11 +1,13 @@
 package stark
 
+import "github.com/huazai2008101/stark/base/log"
+
 const (
 	Version = "1.0.0"
 )
 
 const (
-	MetadataRequestId    = "x-request-id"
+	MetadataRequestId    = log.RequestIdKey
 	MetadataServiceNode  = "x-service-node"
 	MetadataServiceName  = "x-service-name"
 	MetadataResponseTime = "x-response-time"
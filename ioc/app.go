@@ -19,12 +19,15 @@ package ioc
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/huazai2008101/stark/base/log"
@@ -41,7 +44,7 @@ type AppRunner interface {
 // AppEvent 应用运行过程中的事件
 type AppEvent interface {
 	OnAppStart(ctx Context)        // 应用启动的事件
-	OnAppStop(ctx context.Context) // 应用停止的事件
+	OnAppStop(ctx context.Context) // 应用停止的事件，ctx 在停止超时时间之后结束
 }
 
 // App 应用
@@ -49,9 +52,16 @@ type App struct {
 	c *container
 
 	exitChan chan struct{}
+	shutdown int32
 
-	Events  []AppEvent  `autowire:"${application-event.collection:=*?}"`
-	Runners []AppRunner `autowire:"${command-line-runner.collection:=*?}"`
+	config    *configuration
+	sources   []PropertySource
+	layers    propertyLayers
+	lifecycle lifecycleConfig
+
+	Events     []AppEvent       `autowire:"${application-event.collection:=*?}"`
+	Runners    []AppRunner      `autowire:"${command-line-runner.collection:=*?}"`
+	Lifecycles []SmartLifecycle `autowire:"${lifecycle.collection:=*?}"`
 }
 
 // NewApp application 的构造函数
@@ -64,6 +74,9 @@ func NewApp() *App {
 
 func (app *App) Run() error {
 
+	// 退出时输出异步日志中缓存的内容，包括启动失败的日志
+	defer log.Flush()
+
 	// 响应控制台的 Ctrl+C 及 kill 命令。
 	go func() {
 		ch := make(chan os.Signal, 1)
@@ -75,6 +88,7 @@ func (app *App) Run() error {
 	if err := app.start(); err != nil {
 		return err
 	}
+	app.clear()
 
 	<-app.exitChan
 
@@ -83,11 +97,38 @@ func (app *App) Run() error {
 	return nil
 }
 
+// Start 启动应用但是不等待停止信号，需要调用 Stop 停止应用，可以用于测试或者在一
+// 个进程中运行多个应用。和 Run 不同，启动后会保留 bean 的索引，因此可以通过
+// Context 获取 bean 。
+func (app *App) Start() error {
+	return app.start()
+}
+
+// Stop 停止 Start 启动的应用，等待所有 goroutine 结束并执行销毁函数。
+func (app *App) Stop() {
+	app.ShutDown("stop")
+	app.c.Close()
+	log.Info(app.c.Context(), "application exited")
+	log.Flush()
+}
+
+// Context 返回应用的 Context ，在应用启动后获取属性和 bean 。
+func (app *App) Context() Context {
+	return app.c
+}
+
 func (app *App) clear() {
 	app.c.clear()
 }
 
-func (app *App) start() error {
+func (app *App) start() (err error) {
+
+	// 启动失败时打印分析报告，这是启动错误唯一输出日志的地方
+	defer func() {
+		if err != nil {
+			ReportFailure(app.c.Context(), err)
+		}
+	}()
 
 	app.Object(app)
 
@@ -99,20 +140,38 @@ func (app *App) start() error {
 	if err := e.prepare(); err != nil {
 		return err
 	}
+	app.config = e
 
 	if err := app.loadProperties(e); err != nil {
 		return err
 	}
 
-	// 保存从环境变量和命令行解析的属性
-	for _, k := range e.p.Keys() {
-		app.c.p.Set(k, e.p.Get(k))
+	// 加载属性源，并保存从环境变量和命令行解析的属性
+	if err := app.loadPropertySources(e); err != nil {
+		return err
+	}
+
+	// 属性值默认在使用时才解密，开启后在启动时发现无法解密的属性值
+	if app.c.p.Get("spring.config.decrypt.fail-fast") == "true" {
+		if err := app.c.p.CheckEncrypted(); err != nil {
+			return err
+		}
+	}
+
+	if err := app.loadLogging(); err != nil {
+		return err
 	}
 
 	if err := app.c.Refresh(internal.AutoClear(false)); err != nil {
 		return err
 	}
 
+	// 按照阶段启动组件，启动失败时关闭容器
+	if err := app.startLifecycles(); err != nil {
+		app.c.Close()
+		return err
+	}
+
 	// 执行命令行启动器
 	for _, r := range app.Runners {
 		r.Run(app.c)
@@ -122,15 +181,17 @@ func (app *App) start() error {
 	for _, event := range app.Events {
 		event.OnAppStart(app.c)
 	}
+	app.c.publishEvent(&AppStartedEvent{})
 
-	app.clear()
+	// 监听属性源和本地配置文件的变化
+	app.watchPropertySources()
+	app.watchConfigFiles()
 
 	// 通知应用停止事件
 	app.c.Go(func(ctx context.Context) {
 		<-ctx.Done()
-		for _, event := range app.Events {
-			event.OnAppStop(context.Background())
-		}
+		app.stopLifecycles()
+		app.stopEvents()
 	})
 
 	log.Info(app.c.Context(), "application started successfully")
@@ -138,12 +199,28 @@ func (app *App) start() error {
 }
 
 func (app *App) loadProperties(e *configuration) error {
+	files, _, err := app.loadConfigFiles(e)
+	if err != nil {
+		return err
+	}
+	app.layers.code = copyProperties(app.c.p)
+	app.layers.files = files
+	for _, key := range files.Keys() {
+		app.c.p.Set(key, files.Raw(key))
+		app.c.p.SetSource(key, files.Source(key))
+	}
+	return nil
+}
+
+// loadConfigFiles 按照默认配置、profile配置的顺序读取本地配置文件，返回合并后的属性
+// 以及读取到的文件列表。
+func (app *App) loadConfigFiles(e *configuration) (*conf.Properties, []string, error) {
 	var resources []Resource
 
 	for _, ext := range e.ConfigExtensions {
 		sources, err := app.loadResource(e, "application"+ext)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		resources = append(resources, sources...)
 	}
@@ -152,26 +229,74 @@ func (app *App) loadProperties(e *configuration) error {
 		for _, ext := range e.ConfigExtensions {
 			sources, err := app.loadResource(e, "application-"+profile+ext)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 			resources = append(resources, sources...)
 		}
 	}
 
+	defer func() {
+		for _, resource := range resources {
+			if c, ok := resource.(io.Closer); ok {
+				c.Close()
+			}
+		}
+	}()
+
+	files := conf.New()
+	var names []string
 	for _, resource := range resources {
 		b, err := ioutil.ReadAll(resource)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		p, err := conf.Bytes(b, filepath.Ext(resource.Name()))
 		if err != nil {
-			return err
+			return nil, nil, fmt.Errorf("%s %w", resource.Name(), err)
 		}
 		for _, key := range p.Keys() {
-			app.c.p.Set(key, p.Get(key))
+			files.Set(key, p.Raw(key))
+			files.SetSource(key, resource.Name())
 		}
+		names = append(names, resource.Name())
 	}
+	return files, names, nil
+}
 
+// 已经加载的 logging. 属性，日志配置是进程级的，同一进程中的多个应用使用相同的日志
+// 配置时只加载一次，避免后启动的应用关闭先启动应用正在使用的 Appender 。
+var (
+	loadedLoggingMutex sync.Mutex
+	loadedLogging      map[string]string
+)
+
+// loadLogging 使用 logging. 开头的属性加载日志配置，在 bean 刷新之前执行。日志配置
+// 是进程级的，logging. 属性与已经加载的相同时不重新加载，不同时会替换并关闭先启动
+// 应用的 Appender ，没有配置的应用不修改日志配置。
+func (app *App) loadLogging() error {
+	m := make(map[string]string)
+	for _, k := range app.c.p.Keys() {
+		if !strings.HasPrefix(k, "logging.") {
+			continue
+		}
+		v, err := app.c.p.Resolve(app.c.p.Get(k))
+		if err != nil {
+			return err
+		}
+		m[k] = v
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	loadedLoggingMutex.Lock()
+	defer loadedLoggingMutex.Unlock()
+	if reflect.DeepEqual(m, loadedLogging) {
+		return nil
+	}
+	if err := log.LoadProperties(m); err != nil {
+		return err
+	}
+	loadedLogging = m
 	return nil
 }
 
@@ -194,12 +319,12 @@ func (app *App) loadResource(e *configuration, filename string) ([]Resource, err
 // ShutDown 关闭执行器
 func (app *App) ShutDown(msg ...string) {
 	log.Infof(app.c.Context(), "program will exit %s", strings.Join(msg, " "))
-	select {
-	case <-app.exitChan:
-		// chan 已关闭，无需再次关闭。
-	default:
-		close(app.exitChan)
+	// 只在第一次停止请求时发布事件，监听器中可以再次调用 ShutDown 。
+	if !atomic.CompareAndSwapInt32(&app.shutdown, 0, 1) {
+		return
 	}
+	app.c.publishEvent(&ShutdownRequestedEvent{Reason: strings.Join(msg, " ")})
+	close(app.exitChan)
 }
 
 // Go 参考 Container.Go 的解释。
@@ -226,3 +351,22 @@ func (app *App) Object(i interface{}) *BeanDefinition {
 func (app *App) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return app.c.register(NewBean(ctor, args...))
 }
+
+// Register 注册 NewBean 创建的 bean ，封装 App 的工具可以通过该方法记录正确的注
+// 册位置。
+func (app *App) Register(b *BeanDefinition) *BeanDefinition {
+	return app.c.register(b)
+}
+
+// Replace 使用对象形式的 bean 替换 selector 选中的 bean ，被替换的 bean 标记为已
+// 删除，新的 bean 继承其名称、主版本标记以及自身实现了的导出接口，主要用于在测试
+// 中使用 mock 对象。需要注意的是，按照具体类型注入的地方只能使用相同类型的对象替换。
+func (app *App) Replace(selector BeanSelector, i interface{}) *BeanDefinition {
+	return app.c.replace(selector, NewBean(reflect.ValueOf(i)))
+}
+
+// ReplaceBean 使用 NewBean 创建的 bean 替换 selector 选中的 bean ，参考 Replace
+// 的解释。
+func (app *App) ReplaceBean(selector BeanSelector, b *BeanDefinition) *BeanDefinition {
+	return app.c.replace(selector, b)
+}
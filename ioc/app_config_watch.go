@@ -0,0 +1,168 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/huazai2008101/stark/base/log"
+)
+
+// configWatchConfig 本地配置文件监听的配置。
+type configWatchConfig struct {
+	Enabled bool          `value:"${spring.config.watch.enabled:=false}"`
+	Delay   time.Duration `value:"${spring.config.watch.delay:=500ms}"`
+}
+
+// debounceTimer 合并配置目录变化事件的定时器，测试时替换为手动触发的定时器。
+type debounceTimer interface {
+	Chan() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+type stdTimer struct {
+	*time.Timer
+}
+
+func (t stdTimer) Chan() <-chan time.Time {
+	return t.C
+}
+
+// newDebounceTimer 创建处于停止状态的定时器。
+var newDebounceTimer = func() debounceTimer {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	return stdTimer{t}
+}
+
+// watchConfigFiles 监听本地配置文件所在的目录，目录中的文件发生变化后重新读取全部配置
+// 文件。监听的是目录而不是文件，因此可以感知新建的配置文件以及 Kubernetes ConfigMap
+// 通过替换 ..data 软链接完成的更新。
+func (app *App) watchConfigFiles() {
+	var config configWatchConfig
+	if err := app.c.Bind(&config); err != nil {
+		log.Errorf(app.c.Context(), "配置文件监听的配置异常:%+v", err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+
+	_, names, err := app.loadConfigFiles(app.config)
+	if err != nil {
+		log.Errorf(app.c.Context(), "读取配置文件异常:%+v", err)
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf(app.c.Context(), "创建配置文件监听器异常:%+v", err)
+		return
+	}
+
+	dirs := configDirs(app.config.resourceLocator, names)
+	for _, dir := range dirs {
+		if err = watcher.Add(dir); err != nil {
+			log.Errorf(app.c.Context(), "监听配置目录 %s 异常:%+v", dir, err)
+		}
+	}
+	log.Infof(app.c.Context(), "开始监听配置目录 %v", dirs)
+
+	app.c.Go(func(ctx context.Context) {
+		defer watcher.Close()
+
+		// 一次更新通常会产生多个事件，例如 ConfigMap 的更新，延迟一段时间后合并处理。
+		timer := newDebounceTimer()
+
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				log.Debugf(ctx, "配置目录发生变化 %s", event)
+				timer.Reset(config.Delay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf(ctx, "监听配置目录异常:%+v", err)
+			case <-timer.Chan():
+				app.onConfigFileChange()
+			}
+		}
+	})
+}
+
+// configDirs 返回需要监听的目录，包括资源定位器的查找目录以及已读取文件所在的目录，
+// 不存在的目录会被忽略。
+func configDirs(locator ResourceLocator, names []string) []string {
+	var candidates []string
+	if l, ok := locator.(*defaultResourceLocator); ok {
+		candidates = append(candidates, l.configLocations...)
+	}
+	for _, name := range names {
+		candidates = append(candidates, filepath.Dir(name))
+	}
+	var dirs []string
+	exists := make(map[string]bool)
+	for _, dir := range candidates {
+		dir = filepath.Clean(dir)
+		if exists[dir] {
+			continue
+		}
+		exists[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// onConfigFileChange 重新读取本地配置文件，解析失败时拒绝本次更新。
+func (app *App) onConfigFileChange() {
+	files, _, err := app.loadConfigFiles(app.config)
+	if err != nil {
+		log.Errorf(app.c.Context(), "配置文件解析异常，忽略本次更新:%+v", err)
+		return
+	}
+
+	app.layers.mutex.Lock()
+	defer app.layers.mutex.Unlock()
+
+	old := app.layers.files
+	app.layers.files = files
+	changed, err := app.c.updateProperties(app.layers.merge())
+	if err != nil {
+		// 更新被拒绝时恢复原来的属性，保证各层属性与容器一致
+		app.layers.files = old
+		log.Errorf(app.c.Context(), "配置文件的属性更新被拒绝:%+v", err)
+		return
+	}
+	if len(changed) > 0 {
+		log.Infof(app.c.Context(), "配置文件的属性发生变化 keys:%v", changed)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+func TestApp_WatchConfigFiles(t *testing.T) {
+	timer := &ioc.DebounceTimer{C: make(chan time.Time), Resets: make(chan time.Duration, 64)}
+	defer ioc.SetDebounceTimer(timer)()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "application.properties")
+	write := func(s string) {
+		assert.Nil(t, ioutil.WriteFile(file, []byte(s), 0644))
+	}
+	write("server.port=8080\n")
+	t.Setenv("STARK_SPRING_CONFIG_LOCATIONS", dir)
+
+	app := ioc.NewApp()
+	app.Property("spring.config.watch.enabled", true)
+	app.Property("spring.config.watch.delay", "200ms")
+	ports := make(chan int, 8)
+	app.OnProperty("server.port", func(port int) { ports <- port })
+	assert.Nil(t, app.Start())
+	defer app.Stop()
+	assert.Equal(t, <-ports, 8080)
+
+	// waitReset 等待目录变化事件重置定时器
+	waitReset := func() {
+		t.Helper()
+		select {
+		case d := <-timer.Resets:
+			assert.Equal(t, d, 200*time.Millisecond)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no config file event")
+		}
+	}
+	// fire 触发定时器，第二次发送成功时第一次触发的重新加载已经处理完成
+	fire := func() {
+		timer.C <- time.Now()
+		timer.C <- time.Now()
+	}
+
+	// 定时器触发之前的多次修改合并为一次更新
+	write("server.port=8081\n")
+	write("server.port=8082\n")
+	write("server.port=9090\n")
+	waitReset()
+	fire()
+	assert.Equal(t, <-ports, 9090)
+	select {
+	case port := <-ports:
+		t.Fatalf("unexpected reload %d", port)
+	default:
+	}
+
+	// 解析失败的配置文件被忽略，保留原来的属性
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "application.yaml"), []byte("server: [\n"), 0644))
+	write("server.port=7070\n")
+	waitReset()
+	fire()
+	assert.Equal(t, app.Context().Prop("server.port"), "9090")
+	select {
+	case port := <-ports:
+		t.Fatalf("unexpected reload %d", port)
+	default:
+	}
+}
@@ -52,12 +52,14 @@ func loadCmdArgs(p *conf.Properties) error {
 				v = ss[1]
 			}
 			p.Set(k, v)
+			p.SetSource(k, "args")
 			continue
 		}
 		if strings.HasPrefix(s, "-") {
 			k, v := s[1:], ""
 			if i >= len(os.Args)-1 {
 				p.Set(k, v)
+				p.SetSource(k, "args")
 				return nil
 			}
 			next := os.Args[i+1]
@@ -66,6 +68,7 @@ func loadCmdArgs(p *conf.Properties) error {
 				i++
 			}
 			p.Set(k, v)
+			p.SetSource(k, "args")
 		}
 	}
 	return nil
@@ -120,15 +123,21 @@ func loadSystemEnv(p *conf.Properties) error {
 		if len(ss) > 1 {
 			v = ss[1]
 		}
+		// 密钥不能作为属性保存
+		if k == conf.EncryptKeyEnv || k == conf.EncryptKeyFileEnv {
+			continue
+		}
 		if strings.HasPrefix(k, EnvPrefix) {
 			propKey := strings.TrimPrefix(k, EnvPrefix)
 			propKey = strings.ReplaceAll(propKey, "_", ".")
 			propKey = strings.ToLower(propKey)
 			p.Set(propKey, v)
+			p.SetSource(propKey, "env")
 			continue
 		}
 		if matches(includeRex, k) && !matches(excludeRex, k) {
 			p.Set(k, v)
+			p.SetSource(k, "env")
 		}
 	}
 	return nil
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/huazai2008101/stark/base/cast"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/base/util"
+)
+
+// SmartLifecycle 分阶段启动和停止的组件。容器刷新完成后按照 Phase 升序启动，同一
+// 阶段的组件依次启动；应用停止时按照 Phase 降序停止，同一阶段的组件并发停止。
+type SmartLifecycle interface {
+	Phase() int
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	IsRunning() bool
+}
+
+// lifecycleConfig 每个阶段启动和停止的超时时间，可以通过
+// spring.lifecycle.phase.{phase}.start-timeout 和
+// spring.lifecycle.phase.{phase}.stop-timeout 单独设置某个阶段的超时时间。
+type lifecycleConfig struct {
+	StartTimeout time.Duration `value:"${spring.lifecycle.start-timeout:=30s}"`
+	StopTimeout  time.Duration `value:"${spring.lifecycle.stop-timeout:=30s}"`
+}
+
+// lifecyclePhases 按照 phase 对组件分组，返回升序排列的 phase 列表。
+func lifecyclePhases(lifecycles []SmartLifecycle) ([]int, map[int][]SmartLifecycle) {
+	var phases []int
+	groups := make(map[int][]SmartLifecycle)
+	for _, l := range lifecycles {
+		phase := l.Phase()
+		if _, ok := groups[phase]; !ok {
+			phases = append(phases, phase)
+		}
+		groups[phase] = append(groups[phase], l)
+	}
+	sort.Ints(phases)
+	return phases, groups
+}
+
+// phaseTimeout 返回阶段的超时时间，name 为 start-timeout 或者 stop-timeout 。
+func (app *App) phaseTimeout(phase int, name string, def time.Duration) (time.Duration, error) {
+	key := fmt.Sprintf("spring.lifecycle.phase.%d.%s", phase, name)
+	if !app.c.Has(key) {
+		return def, nil
+	}
+	d, err := cast.ToDurationE(app.c.Prop(key))
+	if err != nil {
+		return 0, fmt.Errorf("属性 %s 格式错误: %w", key, err)
+	}
+	return d, nil
+}
+
+// startLifecycles 按照 phase 升序启动组件，某个组件启动失败或者阶段超时后停止已经
+// 启动的组件并返回 error 。
+func (app *App) startLifecycles() error {
+	if err := app.c.Bind(&app.lifecycle); err != nil {
+		return err
+	}
+	phases, groups := lifecyclePhases(app.Lifecycles)
+	for _, phase := range phases {
+		timeout, err := app.phaseTimeout(phase, "start-timeout", app.lifecycle.StartTimeout)
+		if err == nil {
+			err = app.startPhase(phase, groups[phase], timeout)
+		}
+		if err != nil {
+			log.Errorf(app.c.Context(), "lifecycle 启动异常，停止已经启动的组件:%+v", err)
+			app.stopLifecycles()
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *App) startPhase(phase int, lifecycles []SmartLifecycle, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(app.c.Context(), timeout)
+	defer cancel()
+	for _, l := range lifecycles {
+		if l.IsRunning() {
+			continue
+		}
+		name := fmt.Sprintf("lifecycle phase %d %T Start", phase, l)
+		if err := callWithContext(ctx, name, l.Start); err != nil {
+			return fmt.Errorf("lifecycle phase %d %T 启动异常: %w", phase, l, err)
+		}
+	}
+	log.Infof(ctx, "lifecycle phase %d 启动完成", phase)
+	return nil
+}
+
+// stopLifecycles 按照 phase 降序停止正在运行的组件，阶段超时后不再等待该阶段的组件。
+func (app *App) stopLifecycles() {
+	phases, groups := lifecyclePhases(app.Lifecycles)
+	for i := len(phases) - 1; i >= 0; i-- {
+		phase := phases[i]
+		timeout, err := app.phaseTimeout(phase, "stop-timeout", app.lifecycle.StopTimeout)
+		if err != nil {
+			log.Error(app.c.Context(), err)
+			timeout = app.lifecycle.StopTimeout
+		}
+		app.stopPhase(phase, groups[phase], timeout)
+	}
+}
+
+func (app *App) stopPhase(phase int, lifecycles []SmartLifecycle, timeout time.Duration) {
+	// 停止时容器的 ctx 可能已经结束，因此使用新的 ctx 。
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, l := range lifecycles {
+		if !l.IsRunning() {
+			continue
+		}
+		wg.Add(1)
+		go func(l SmartLifecycle) {
+			defer wg.Done()
+			name := fmt.Sprintf("lifecycle phase %d %T Stop", phase, l)
+			if err := callWithContext(ctx, name, l.Stop); err != nil {
+				log.Errorf(ctx, "lifecycle phase %d %T 停止异常:%+v", phase, l, err)
+			}
+		}(l)
+	}
+	wg.Wait()
+	log.Infof(ctx, "lifecycle phase %d 停止完成", phase)
+}
+
+// stopEvents 依次调用 AppEvent 的 OnAppStop ，每个调用的 ctx 都在停止超时时间
+// spring.lifecycle.stop-timeout 之后结束，超时后不再等待。
+func (app *App) stopEvents() {
+	for _, event := range app.Events {
+		event := event
+		ctx, cancel := context.WithTimeout(context.Background(), app.lifecycle.StopTimeout)
+		name := fmt.Sprintf("%T OnAppStop", event)
+		err := callWithContext(ctx, name, func(ctx context.Context) error {
+			event.OnAppStop(ctx)
+			return nil
+		})
+		cancel()
+		if err != nil && err != context.DeadlineExceeded {
+			log.Errorf(ctx, "%s 异常:%+v", name, err)
+		}
+	}
+}
+
+// callWithContext 调用 fn 并等待其返回，ctx 结束后不再等待并记录被放弃的调用，fn
+// 的 panic 转换为 error 。
+func callWithContext(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ch := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- fmt.Errorf("panic:%v %s", r, util.PanicStack())
+			}
+		}()
+		ch <- fn(ctx)
+	}()
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		log.Warnf(ctx, "%s 超时，不再等待其返回:%v", name, ctx.Err())
+		return ctx.Err()
+	}
+}
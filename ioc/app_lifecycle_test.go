@@ -0,0 +1,176 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+type phaseRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (r *phaseRecorder) add(format string, a ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, a...))
+}
+
+func (r *phaseRecorder) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.events...)
+}
+
+type phaseComponent struct {
+	name     string
+	phase    int
+	recorder *phaseRecorder
+	block    bool // Start 阻塞直到超时
+	running  int32
+}
+
+func (c *phaseComponent) Phase() int {
+	return c.phase
+}
+
+func (c *phaseComponent) Start(ctx context.Context) error {
+	if c.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	c.recorder.add("start %s", c.name)
+	atomic.StoreInt32(&c.running, 1)
+	return nil
+}
+
+func (c *phaseComponent) Stop(ctx context.Context) error {
+	c.recorder.add("stop %s", c.name)
+	atomic.StoreInt32(&c.running, 0)
+	return nil
+}
+
+func (c *phaseComponent) IsRunning() bool {
+	return atomic.LoadInt32(&c.running) == 1
+}
+
+func newPhaseApp(r *phaseRecorder, components ...*phaseComponent) *ioc.App {
+	app := ioc.NewApp()
+	for _, c := range components {
+		c.recorder = r
+		app.Object(c).Name(c.name).Export((*ioc.SmartLifecycle)(nil))
+	}
+	return app
+}
+
+func TestApp_Lifecycle(t *testing.T) {
+	r := new(phaseRecorder)
+	app := newPhaseApp(r,
+		&phaseComponent{name: "web", phase: 10},
+		&phaseComponent{name: "cache", phase: 5},
+		&phaseComponent{name: "db", phase: -1},
+		&phaseComponent{name: "mq", phase: 5},
+	)
+	assert.Nil(t, app.Start())
+
+	// 按照 phase 升序启动，同一阶段依次启动
+	events := r.get()
+	assert.Equal(t, events[0], "start db")
+	sort.Strings(events[1:3])
+	assert.Equal(t, events[1:], []string{"start cache", "start mq", "start web"})
+
+	// 按照 phase 降序停止，同一阶段并发停止
+	app.Stop()
+	events = r.get()[4:]
+	assert.Equal(t, len(events), 4)
+	assert.Equal(t, events[0], "stop web")
+	sort.Strings(events[1:3])
+	assert.Equal(t, events[1:], []string{"stop cache", "stop mq", "stop db"})
+}
+
+func TestApp_LifecycleStartTimeout(t *testing.T) {
+	r := new(phaseRecorder)
+	app := newPhaseApp(r,
+		&phaseComponent{name: "db", phase: 0},
+		&phaseComponent{name: "slow", phase: 1, block: true},
+		&phaseComponent{name: "web", phase: 2},
+	)
+	app.Property("spring.lifecycle.phase.1.start-timeout", "100ms")
+
+	// 阶段超时后停止已经启动的组件，后续阶段不再启动
+	err := app.Start()
+	assert.Error(t, err, "lifecycle phase 1 \\*ioc_test.phaseComponent 启动异常: context deadline exceeded")
+	assert.Equal(t, r.get(), []string{"start db", "stop db"})
+}
+
+// slowStopEvent OnAppStop 阻塞直到 ctx 结束
+type slowStopEvent struct {
+	deadline chan bool
+}
+
+func (e *slowStopEvent) OnAppStart(ctx ioc.Context) {}
+
+func (e *slowStopEvent) OnAppStop(ctx context.Context) {
+	_, ok := ctx.Deadline()
+	<-ctx.Done()
+	e.deadline <- ok
+}
+
+// stuckStopEvent OnAppStop 忽略 ctx 一直阻塞
+type stuckStopEvent struct {
+	release chan struct{}
+}
+
+func (e *stuckStopEvent) OnAppStart(ctx ioc.Context) {}
+
+func (e *stuckStopEvent) OnAppStop(ctx context.Context) {
+	<-e.release
+}
+
+func TestApp_StopEventTimeout(t *testing.T) {
+	e := &slowStopEvent{deadline: make(chan bool, 1)}
+	stuck := &stuckStopEvent{release: make(chan struct{})}
+	defer close(stuck.release)
+	app := ioc.NewApp()
+	app.Property("spring.lifecycle.stop-timeout", "100ms")
+	app.Object(e).Export((*ioc.AppEvent)(nil))
+	app.Object(stuck).Export((*ioc.AppEvent)(nil))
+	assert.Nil(t, app.Start())
+
+	// OnAppStop 的 ctx 在停止超时时间之后结束，忽略 ctx 的调用超时后被放弃
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.Stop()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop timeout")
+	}
+	assert.True(t, <-e.deadline)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc"
+)
+
+func TestApp_LoadLogging(t *testing.T) {
+	l := log.GetLogger("ioc-logging-test")
+	defer l.SetLevel(log.InfoLevel)
+
+	start := func(level string) *ioc.App {
+		app := ioc.NewApp()
+		app.Property("logging.level.ioc-logging-test", level)
+		assert.Nil(t, app.Start())
+		t.Cleanup(app.Stop)
+		return app
+	}
+
+	start("info")
+	assert.Equal(t, l.Level(), log.InfoLevel)
+	l.SetLevel(log.DebugLevel)
+
+	// 日志配置相同时不重新加载，不影响先启动的应用
+	start("info")
+	assert.Equal(t, l.Level(), log.DebugLevel)
+
+	// 没有配置 logging. 属性时不修改日志配置
+	app := ioc.NewApp()
+	assert.Nil(t, app.Start())
+	t.Cleanup(app.Stop)
+	assert.Equal(t, l.Level(), log.DebugLevel)
+
+	// 日志配置不同时重新加载
+	start("warn")
+	assert.Equal(t, l.Level(), log.WarnLevel)
+}
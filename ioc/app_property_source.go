@@ -0,0 +1,138 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// PropertySource 属性源，例如配置中心。属性源在本地配置文件之后、环境变量和命令行
+// 参数之前加载，相同的 key 以后加载的为准。
+type PropertySource interface {
+
+	// Load 加载属性，p 为已经加载的属性，可以从中读取属性源自身的配置，profiles
+	// 为激活的 profile 列表，返回的属性中 profile 的属性应当覆盖默认的属性。
+	Load(p *conf.Properties, profiles []string) (*conf.Properties, error)
+
+	// Watch 监听属性的变化，属性发生变化时调用 fn 传入最新的全部属性，ctx 结束
+	// 时返回。
+	Watch(ctx context.Context, fn func(p *conf.Properties))
+}
+
+// propertyLayers 按照优先级从低到高保存各个来源的属性。
+type propertyLayers struct {
+	mutex   sync.Mutex
+	code    *conf.Properties   // 代码设置的属性
+	files   *conf.Properties   // 本地配置文件
+	sources []*conf.Properties // 属性源
+	args    *conf.Properties   // 环境变量和命令行参数
+}
+
+// merge 按照优先级合并各个来源的属性，与已有属性结构冲突的 key 会被忽略。
+func (l *propertyLayers) merge() *conf.Properties {
+	p := conf.New()
+	layers := append([]*conf.Properties{l.code, l.files}, l.sources...)
+	layers = append(layers, l.args)
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		for _, k := range layer.Keys() {
+			if err := p.Set(k, layer.Raw(k)); err != nil {
+				log.Warnf(context.Background(), "忽略属性 %s:%v", k, err)
+				continue
+			}
+			p.SetSource(k, layer.Source(k))
+		}
+	}
+	return p
+}
+
+// copyProperties 返回 p 的副本。
+func copyProperties(p *conf.Properties) *conf.Properties {
+	r := conf.New()
+	for _, k := range p.Keys() {
+		_ = r.Set(k, p.Raw(k))
+		r.SetSource(k, p.Source(k))
+	}
+	return r
+}
+
+// markSource 将属性源返回的属性中没有来源的属性的来源设置为属性源的类型。
+func markSource(p *conf.Properties, s PropertySource) {
+	for _, k := range p.Keys() {
+		if p.Source(k) == "" {
+			p.SetSource(k, fmt.Sprintf("%T", s))
+		}
+	}
+}
+
+// AddPropertySource 添加属性源，需要在 Run 之前调用。
+func (app *App) AddPropertySource(s PropertySource) {
+	app.sources = append(app.sources, s)
+}
+
+// loadPropertySources 依次加载属性源，并将合并后的属性设置到容器中。
+func (app *App) loadPropertySources(e *configuration) error {
+	app.layers.args = e.p
+	for _, s := range app.sources {
+		p, err := s.Load(app.layers.merge(), e.ActiveProfiles)
+		if err != nil {
+			return err
+		}
+		markSource(p, s)
+		app.layers.sources = append(app.layers.sources, p)
+	}
+	app.c.p = app.layers.merge()
+	return nil
+}
+
+// watchPropertySources 监听属性源的变化，重新合并属性并通知监听函数。
+func (app *App) watchPropertySources() {
+	for i, s := range app.sources {
+		i, s := i, s
+		app.c.Go(func(ctx context.Context) {
+			s.Watch(ctx, func(p *conf.Properties) {
+				app.onPropertySourceChange(i, p)
+			})
+		})
+	}
+}
+
+func (app *App) onPropertySourceChange(i int, p *conf.Properties) {
+	app.layers.mutex.Lock()
+	defer app.layers.mutex.Unlock()
+
+	markSource(p, app.sources[i])
+	old := app.layers.sources[i]
+	app.layers.sources[i] = p
+	changed, err := app.c.updateProperties(app.layers.merge())
+	if err != nil {
+		// 更新被拒绝时恢复原来的属性，保证各层属性与容器一致
+		app.layers.sources[i] = old
+		log.Errorf(app.c.Context(), "属性源的属性更新被拒绝:%+v", err)
+		return
+	}
+	if len(changed) > 0 {
+		log.Infof(app.c.Context(), "属性源的属性发生变化 keys:%v", changed)
+	}
+}
@@ -44,8 +44,23 @@ const (
 	SingletonBeanScope BeanScope = iota
 	// 原型模式，注入多少次bean，浅拷贝对象多少次，类似值传递
 	PrototypeBeanScope
+	// 请求作用域，每次请求浅拷贝一次对象，请求结束时销毁，需要通过 provider 获取
+	RequestBeanScope
+	// goroutine作用域，每个 BeginScope 开始的周期浅拷贝一次对象，需要通过 provider 获取
+	GoroutineBeanScope
+	// 刷新作用域，属性发生变化后重新创建，旧的对象被销毁，需要通过 provider 获取
+	RefreshBeanScope
 )
 
+func (s BeanScope) String() string {
+	scopeMutex.RLock()
+	defer scopeMutex.RUnlock()
+	if name, ok := scopeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("BeanScope(%d)", int32(s))
+}
+
 type beanStatus int8
 
 const (
@@ -111,8 +126,23 @@ type BeanDefinition struct {
 	depends []BeanSelector // 间接依赖项
 	exports []reflect.Type // 导出的接口
 	scope   BeanScope      // 作用域
+
+	condition string           // 条件判断的结果
+	deps      []BeanDependency // 注入时解析出的依赖项
+}
+
+// BeanDependency bean 的依赖项，Kind 为依赖的方式。
+type BeanDependency struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
 }
 
+const (
+	AutowireDependency  = "autowire"  // 字段注入或者构造函数参数注入
+	LazyDependency      = "lazy"      // 延迟注入的字段
+	DependsOnDependency = "dependsOn" // DependsOn 设置的间接依赖
+)
+
 // Type 返回 bean 的类型。
 func (d *BeanDefinition) Type() reflect.Type {
 	return d.t
@@ -148,6 +178,37 @@ func (d *BeanDefinition) GetScope() BeanScope {
 	return d.scope
 }
 
+// Exports 返回 bean 导出的接口。
+func (d *BeanDefinition) Exports() []reflect.Type {
+	return d.exports
+}
+
+// GetOrder 返回 bean 的排序序号。
+func (d *BeanDefinition) GetOrder() float32 {
+	return d.order
+}
+
+// ConditionOutcome 返回 bean 的条件判断结果，没有设置条件时返回空字符串。
+func (d *BeanDefinition) ConditionOutcome() string {
+	return d.condition
+}
+
+// Dependencies 返回 bean 在注入时解析出的依赖项。
+func (d *BeanDefinition) Dependencies() []BeanDependency {
+	return d.deps
+}
+
+// addDependency 添加一个依赖项，重复的依赖项只记录一次。
+func (d *BeanDefinition) addDependency(b *BeanDefinition, kind string) {
+	dep := BeanDependency{ID: b.ID(), Kind: kind}
+	for _, v := range d.deps {
+		if v == dep {
+			return
+		}
+	}
+	d.deps = append(d.deps, dep)
+}
+
 // TypeName 返回 bean 的原始类型的全限定名。
 func (d *BeanDefinition) TypeName() string {
 	return d.typeName
@@ -163,6 +224,11 @@ func (d *BeanDefinition) Wired() bool {
 	return d.status == Wired
 }
 
+// Status 返回 bean 的状态描述。
+func (d *BeanDefinition) Status() string {
+	return getStatusString(d.status)
+}
+
 // FileLine 返回 bean 的注册点。
 func (d *BeanDefinition) FileLine() string {
 	return fmt.Sprintf("%s:%d", d.file, d.line)
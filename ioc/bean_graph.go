@@ -0,0 +1,105 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BeanInfo bean 元数据的描述信息。
+type BeanInfo struct {
+	ID           string           `json:"id"`
+	Type         string           `json:"type"`
+	Exports      []string         `json:"exports,omitempty"`
+	Order        float32          `json:"order"`
+	Scope        string           `json:"scope"`
+	Status       string           `json:"status"`
+	Condition    string           `json:"condition,omitempty"`
+	File         string           `json:"file"`
+	Dependencies []BeanDependency `json:"dependencies,omitempty"`
+}
+
+// BeanGraph 容器中的 bean 以及它们之间的依赖关系，Beans 按照注册顺序排列。
+type BeanGraph struct {
+	Beans []BeanInfo `json:"beans"`
+}
+
+// NewBeanGraph 使用 bean 元数据创建依赖关系图。
+func NewBeanGraph(beans []*BeanDefinition) *BeanGraph {
+	g := &BeanGraph{Beans: make([]BeanInfo, 0, len(beans))}
+	for _, b := range beans {
+		info := BeanInfo{
+			ID:           b.ID(),
+			Type:         b.Type().String(),
+			Order:        b.GetOrder(),
+			Scope:        b.GetScope().String(),
+			Status:       b.Status(),
+			Condition:    b.ConditionOutcome(),
+			File:         b.FileLine(),
+			Dependencies: b.Dependencies(),
+		}
+		for _, t := range b.Exports() {
+			info.Exports = append(info.Exports, t.String())
+		}
+		g.Beans = append(g.Beans, info)
+	}
+	return g
+}
+
+// JSON 返回 json 格式的依赖关系图。
+func (g *BeanGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// DOT 返回 Graphviz DOT 格式的依赖关系图，被条件删除的 bean 使用虚线表示，延迟注入
+// 和 DependsOn 的依赖使用虚线箭头表示。
+func (g *BeanGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph beans {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, b := range g.Beans {
+		attrs := []string{fmt.Sprintf("label=%q", b.ID+"\n"+b.Type)}
+		if b.Status == getStatusString(Deleted) {
+			attrs = append(attrs, "style=dashed", "color=gray")
+		}
+		fmt.Fprintf(&sb, "  %q [%s];\n", b.ID, strings.Join(attrs, ", "))
+	}
+	for _, b := range g.Beans {
+		deps := append([]BeanDependency{}, b.Dependencies...)
+		sort.Slice(deps, func(i, j int) bool { return deps[i].ID < deps[j].ID })
+		for _, d := range deps {
+			switch d.Kind {
+			case AutowireDependency:
+				fmt.Fprintf(&sb, "  %q -> %q;\n", b.ID, d.ID)
+			default:
+				fmt.Fprintf(&sb, "  %q -> %q [style=dashed, label=%q];\n", b.ID, d.ID, d.Kind)
+			}
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// BeanGraph 返回容器中注册的所有 bean 以及它们之间的依赖关系，该方法只能在容器刷新
+// 之后调用，刷新失败时也可以调用。
+func (c *container) BeanGraph() *BeanGraph {
+	return NewBeanGraph(c.beanDefs)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/cond"
+)
+
+type graphDB struct{}
+
+type graphCache struct{}
+
+type graphRepo struct {
+	DB *graphDB `autowire:""`
+}
+
+type graphService struct {
+	Repo  *graphRepo  `autowire:""`
+	Cache *graphCache `autowire:",lazy"`
+}
+
+func TestBeanGraph(t *testing.T) {
+	c := ioc.New()
+	c.Object(new(graphDB)).Name("db")
+	c.Object(new(graphCache)).Name("cache")
+	c.Object(new(graphRepo)).Name("repo").DependsOn("cache")
+	c.Object(new(graphService)).Name("service")
+	c.Object(new(graphDB)).Name("mock").On(cond.OnProperty("mock.enabled"))
+	assert.Nil(t, c.Refresh())
+
+	g := c.(interface{ BeanGraph() *ioc.BeanGraph }).BeanGraph()
+	const pkg = "github.com/huazai2008101/stark/ioc_test/ioc_test."
+
+	b, err := g.JSON()
+	assert.Nil(t, err)
+	var r ioc.BeanGraph
+	assert.Nil(t, json.Unmarshal(b, &r))
+	beans := make(map[string]ioc.BeanInfo)
+	for _, info := range r.Beans {
+		beans[info.ID] = info
+	}
+	assert.Equal(t, beans[pkg+"graphRepo:repo"].Dependencies, []ioc.BeanDependency{
+		{ID: pkg + "graphCache:cache", Kind: ioc.DependsOnDependency},
+		{ID: pkg + "graphDB:db", Kind: ioc.AutowireDependency},
+	})
+	assert.Equal(t, beans[pkg+"graphService:service"].Dependencies, []ioc.BeanDependency{
+		{ID: pkg + "graphRepo:repo", Kind: ioc.AutowireDependency},
+		{ID: pkg + "graphCache:cache", Kind: ioc.LazyDependency},
+	})
+	assert.Equal(t, beans[pkg+"graphDB:db"].Type, "*ioc_test.graphDB")
+	assert.Equal(t, beans[pkg+"graphDB:db"].Scope, "singleton")
+	assert.Equal(t, beans[pkg+"graphDB:db"].Status, "Wired")
+	assert.True(t, strings.Contains(beans[pkg+"graphDB:db"].File, "bean_graph_test.go:"))
+	assert.Equal(t, beans[pkg+"graphDB:mock"].Status, "Deleted")
+	assert.Equal(t, beans[pkg+"graphDB:mock"].Condition, "not matched")
+	assert.Equal(t, beans["github.com/huazai2008101/stark/ioc/ioc.container:container"].Exports, []string{"ioc.Context"})
+
+	dot := g.DOT()
+	assert.True(t, strings.HasPrefix(dot, "digraph beans {\n"))
+	assert.True(t, strings.HasSuffix(dot, "}\n"))
+	for _, line := range []string{
+		`"` + pkg + `graphDB:db" [label="` + pkg + `graphDB:db\n*ioc_test.graphDB"];`,
+		`"` + pkg + `graphDB:mock" [label="` + pkg + `graphDB:mock\n*ioc_test.graphDB", style=dashed, color=gray];`,
+		`"` + pkg + `graphRepo:repo" -> "` + pkg + `graphDB:db";`,
+		`"` + pkg + `graphRepo:repo" -> "` + pkg + `graphCache:cache" [style=dashed, label="dependsOn"];`,
+		`"` + pkg + `graphService:service" -> "` + pkg + `graphRepo:repo";`,
+		`"` + pkg + `graphService:service" -> "` + pkg + `graphCache:cache" [style=dashed, label="lazy"];`,
+	} {
+		assert.True(t, strings.Contains(dot, "  "+line+"\n"), line)
+	}
+	// 依赖按照 ID 排序输出
+	assert.True(t, strings.Index(dot, `graphRepo:repo" -> "`+pkg+`graphCache`) < strings.Index(dot, `graphRepo:repo" -> "`+pkg+`graphDB`))
+}
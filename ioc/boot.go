@@ -48,6 +48,12 @@ func app() *App {
 	return gApp
 }
 
+// DefaultApp 返回包级函数使用的默认 App ，需要在一个进程中运行多个应用时应当使用
+// NewApp 分别创建。
+func DefaultApp() *App {
+	return app()
+}
+
 // Setenv 封装 os.Setenv 函数，如果发生 error 会 panic 。
 func SetEnv(key string, value string) {
 	err := os.Setenv(key, value)
@@ -59,6 +65,26 @@ func Go(fn func(ctx context.Context)) {
 	app().Go(fn)
 }
 
+// GoScope 参考 App.Go 的解释，fn 运行在一个新的 goroutine 作用域周期中，fn
+// 返回后销毁该周期内的 bean 。
+func GoScope(fn func(ctx context.Context)) {
+	app().Go(func(ctx context.Context) {
+		ctx, end := BeginScope(ctx, GoroutineBeanScope)
+		defer end()
+		fn(ctx)
+	})
+}
+
+// Publish 参考 EventBus.Publish 的解释。
+func Publish(ctx context.Context, event interface{}) error {
+	return app().c.bus.Publish(ctx, event)
+}
+
+// PublishAsync 参考 EventBus.PublishAsync 的解释。
+func PublishAsync(ctx context.Context, event interface{}) {
+	app().c.bus.PublishAsync(ctx, event)
+}
+
 // Run 启动程序。
 func Run() error {
 	return app().Run()
@@ -74,6 +100,11 @@ func OnProperty(key string, fn interface{}) {
 	app().OnProperty(key, fn)
 }
 
+// AddPropertySource 参考 App.AddPropertySource 的解释。
+func AddPropertySource(s PropertySource) {
+	app().AddPropertySource(s)
+}
+
 // Property 参考 Container.Property 的解释。
 func Property(key string, value interface{}) {
 	app().Property(key, value)
@@ -21,12 +21,12 @@ package cond
 
 import (
 	"errors"
+	"fmt"
 	"go/token"
 	"go/types"
 	"strconv"
 	"strings"
 
-	"github.com/huazai2008101/stark/base/util"
 	"github.com/huazai2008101/stark/ioc/conf"
 	"github.com/huazai2008101/stark/ioc/internal"
 )
@@ -151,13 +151,22 @@ func (c *onSingleBean) Matches(ctx Context) (bool, error) {
 	return len(beans) == 1, err
 }
 
-// onExpression 基于表达式的 Condition 实现。
+// onExpression 基于表达式的 Condition 实现，表达式的语法参考 expr.go 的说明。
 type onExpression struct {
 	expression string
+	node       exprNode
+	err        error // 表达式的解析错误
 }
 
 func (c *onExpression) Matches(ctx Context) (bool, error) {
-	return false, util.UnimplementedMethod
+	if c.err != nil {
+		return false, fmt.Errorf("表达式 %q 解析错误: %w", c.expression, c.err)
+	}
+	ok, err := evalBool(ctx, c.node)
+	if err != nil {
+		return false, fmt.Errorf("表达式 %q 计算错误: %w", c.expression, err)
+	}
+	return ok, nil
 }
 
 // Operator 条件操作符，包含 Or、And、None 三种。
@@ -384,9 +393,11 @@ func OnExpression(expression string) *conditional {
 	return New().OnExpression(expression)
 }
 
-// OnExpression 添加一个 onExpression 条件。
+// OnExpression 添加一个 onExpression 条件，表达式在注册时解析，语法错误在条件判断
+// 时返回，这样容器可以在错误信息中给出 bean 注册的位置。
 func (c *conditional) OnExpression(expression string) *conditional {
-	return c.On(&onExpression{expression: expression})
+	n, err := parseExpression(expression)
+	return c.On(&onExpression{expression: expression, node: n, err: err})
 }
 
 // OnMatches 返回一个以 onMatches 为开始条件的计算式。
@@ -0,0 +1,575 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cond
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 表达式语法：
+//
+//	expr    = or
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | compare
+//	compare = primary [ ("==" | "!=" | "<" | "<=" | ">" | ">=") primary | ["not"] "in" list ]
+//	list    = "[" [ primary { "," primary } ] "]"
+//	primary = string | number | "true" | "false" | func "(" [ args ] ")" | "(" expr ")"
+//
+// 支持的函数：
+//
+//	prop("key")          属性值，属性不存在时返回空字符串
+//	prop("key", "def")   属性值，属性不存在时返回默认值
+//	has("key")           属性是否存在
+//	bean("selector")     是否存在符合条件的 bean ，如 bean("*gorm.DB:stock")
+//	profile("dev", ...)  是否激活了其中任意一个 profile
+//
+// 比较时如果两边都是数字则按照数值比较，有一边是 bool 则按照 bool 比较，否则按照字符串比较。
+
+// exprNode 表达式语法树的节点。
+type exprNode interface {
+	eval(ctx Context) (interface{}, error)
+}
+
+type literalNode struct {
+	v interface{}
+}
+
+func (n *literalNode) eval(ctx Context) (interface{}, error) {
+	return n.v, nil
+}
+
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) eval(ctx Context) (interface{}, error) {
+	b, err := evalBool(ctx, n.x)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type logicNode struct {
+	op   string // && 或者 ||
+	x, y exprNode
+}
+
+func (n *logicNode) eval(ctx Context) (interface{}, error) {
+	b, err := evalBool(ctx, n.x)
+	if err != nil {
+		return nil, err
+	}
+	// 短路求值，避免不必要的 bean 查找
+	if (n.op == "&&" && !b) || (n.op == "||" && b) {
+		return b, nil
+	}
+	return evalBool(ctx, n.y)
+}
+
+type compareNode struct {
+	op   string
+	x, y exprNode
+}
+
+func (n *compareNode) eval(ctx Context) (interface{}, error) {
+	x, err := n.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return compareValues(n.op, x, y)
+}
+
+type inNode struct {
+	not  bool
+	x    exprNode
+	list []exprNode
+}
+
+func (n *inNode) eval(ctx Context) (interface{}, error) {
+	x, err := n.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range n.list {
+		y, err := e.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := compareValues("==", x, y)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return !n.not, nil
+		}
+	}
+	return n.not, nil
+}
+
+type funcNode struct {
+	name string
+	args []exprNode
+}
+
+func (n *funcNode) eval(ctx Context) (interface{}, error) {
+	var args []string
+	for _, a := range n.args {
+		v, err := a.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, toString(v))
+	}
+	switch n.name {
+	case "prop":
+		if ctx.Has(args[0]) {
+			return ctx.Prop(args[0]), nil
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+		return "", nil
+	case "has":
+		return ctx.Has(args[0]), nil
+	case "bean":
+		beans, err := ctx.Find(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return len(beans) > 0, nil
+	case "profile":
+		for _, s := range strings.Split(ctx.Prop("spring.profiles.active"), ",") {
+			for _, a := range args {
+				if strings.TrimSpace(s) == a {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	return nil, fmt.Errorf("未知的函数 %s", n.name)
+}
+
+// funcArgs 函数的参数个数范围，-1 表示不限制。
+var funcArgs = map[string][2]int{
+	"prop":    {1, 2},
+	"has":     {1, 1},
+	"bean":    {1, 1},
+	"profile": {1, -1},
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, fmt.Errorf("%q 不是 bool 值", x)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("%v 不是 bool 值", v)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func evalBool(ctx Context, n exprNode) (bool, error) {
+	v, err := n.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+// compareValues 比较两个值，两边都是数字时按照数值比较，有一边是 bool 时按照 bool
+// 比较，否则按照字符串比较。
+func compareValues(op string, x, y interface{}) (bool, error) {
+	var r int
+	_, xIsBool := x.(bool)
+	_, yIsBool := y.(bool)
+	fx, xIsNum := toNumber(x)
+	fy, yIsNum := toNumber(y)
+	switch {
+	case xIsBool || yIsBool:
+		bx, err := toBool(x)
+		if err != nil {
+			return false, err
+		}
+		by, err := toBool(y)
+		if err != nil {
+			return false, err
+		}
+		switch op {
+		case "==":
+			return bx == by, nil
+		case "!=":
+			return bx != by, nil
+		}
+		return false, fmt.Errorf("bool 值不支持 %s 操作", op)
+	case xIsNum && yIsNum:
+		switch {
+		case fx < fy:
+			r = -1
+		case fx > fy:
+			r = 1
+		}
+	default:
+		r = strings.Compare(toString(x), toString(y))
+	}
+	switch op {
+	case "==":
+		return r == 0, nil
+	case "!=":
+		return r != 0, nil
+	case "<":
+		return r < 0, nil
+	case "<=":
+		return r <= 0, nil
+	case ">":
+		return r > 0, nil
+	case ">=":
+		return r >= 0, nil
+	}
+	return false, fmt.Errorf("未知的操作符 %s", op)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenIdent
+	tokenOp
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize 将表达式分解为 token 列表。
+func tokenize(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for ; j < len(s) && s[j] != s[i]; j++ {
+				if s[j] == '\\' && c == '"' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("位置 %d 字符串没有结束", i)
+			}
+			text := s[i+1 : j]
+			if c == '"' {
+				var err error
+				if text, err = strconv.Unquote(s[i : j+1]); err != nil {
+					return nil, fmt.Errorf("位置 %d 字符串错误: %v", i, err)
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: text, pos: i})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: s[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: s[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("位置 %d 非法字符 %q", i, c)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// exprParser 递归下降的表达式解析器。
+type exprParser struct {
+	tokens []exprToken
+	i      int
+}
+
+// parseExpression 解析表达式，返回语法树。
+func parseExpression(s string) (exprNode, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("位置 %d 多余的内容 %q", t.pos, t.text)
+	}
+	return n, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.i]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept 当前 token 是 text 时前进并返回 true 。
+func (p *exprParser) accept(text ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, s := range text {
+		if t.text == s {
+			p.i++
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		return fmt.Errorf("位置 %d 需要 %q 但是得到 %q", t.pos, text, t.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return x, nil
+		}
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicNode{op: "||", x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return x, nil
+		}
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicNode{op: "&&", x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<=", ">=", "<", ">"); ok {
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, x: x, y: y}, nil
+	}
+	not := false
+	if t := p.peek(); t.kind == tokenIdent && t.text == "not" {
+		if n := p.tokens[p.i+1]; n.kind == tokenIdent && n.text == "in" {
+			p.i++
+			not = true
+		}
+	}
+	if _, ok := p.accept("in"); ok {
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{not: not, x: x, list: list}, nil
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseList() ([]exprNode, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var list []exprNode
+	if _, ok := p.accept("]"); ok {
+		return list, nil
+	}
+	for {
+		n, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return &literalNode{v: t.text}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d 数字错误 %q", t.pos, t.text)
+		}
+		return &literalNode{v: f}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{v: true}, nil
+		case "false":
+			return &literalNode{v: false}, nil
+		}
+		return p.parseFunc(t)
+	case tokenOp:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	case tokenEOF:
+		return nil, errors.New("表达式意外结束")
+	}
+	return nil, fmt.Errorf("位置 %d 非法的内容 %q", t.pos, t.text)
+}
+
+func (p *exprParser) parseFunc(t exprToken) (exprNode, error) {
+	n, ok := funcArgs[t.text]
+	if !ok {
+		return nil, fmt.Errorf("位置 %d 未知的函数 %q", t.pos, t.text)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	f := &funcNode{name: t.text}
+	if _, ok = p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			f.args = append(f.args, arg)
+			if _, ok = p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(f.args) < n[0] || (n[1] >= 0 && len(f.args) > n[1]) {
+		return nil, fmt.Errorf("位置 %d 函数 %s 的参数个数错误", t.pos, t.text)
+	}
+	return f, nil
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cond
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// exprContext 表达式测试使用的 Context ，bean 选择器为字符串时按照名称匹配
+type exprContext struct {
+	props map[string]string
+	beans map[string]bool
+}
+
+func (c *exprContext) Has(key string) bool {
+	_, ok := c.props[key]
+	return ok
+}
+
+func (c *exprContext) Prop(key string, opts ...conf.GetOption) string {
+	return c.props[key]
+}
+
+func (c *exprContext) Find(selector BeanSelector) ([]BeanDefinition, error) {
+	s := fmt.Sprint(selector)
+	if s == "error" {
+		return nil, errors.New("find error")
+	}
+	if c.beans[s] {
+		return make([]BeanDefinition, 1), nil
+	}
+	return nil, nil
+}
+
+func TestExpression(t *testing.T) {
+	ctx := &exprContext{
+		props: map[string]string{
+			"port":                   "8080",
+			"version":                "10",
+			"mode":                   "debug",
+			"enabled":                "true",
+			"name":                   "a\"b",
+			"spring.profiles.active": "dev, test",
+		},
+		beans: map[string]bool{"*redis.Client": true},
+	}
+
+	testcases := []struct {
+		expr string
+		want bool
+	}{
+		// 优先级: not > and > or
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`!false && false`, false},
+		{`not true or true`, true},
+		{`not (true or true)`, false},
+		{`!!true`, true},
+		{`false or false and true`, false},
+		// in 与 not in
+		{`prop("mode") in ["debug", "mock"]`, true},
+		{`prop("mode") not in ["debug", "mock"]`, false},
+		{`prop("mode") in []`, false},
+		{`prop("mode") not in []`, true},
+		{`prop("port") in [80, 8080.0]`, true},
+		{`not prop("mode") in ["release"]`, true},
+		// 字符串转义，单引号字符串不处理转义
+		{`prop("name") == "a\"b"`, true},
+		{`"\t" == "	"`, true},
+		{`'a\b' == "a\\b"`, true},
+		{`"中" == '中'`, true},
+		// 两边都是数字时按照数值比较，否则按照字符串比较
+		{`prop("version") > 9`, true},
+		{`prop("version") > "9"`, true},
+		{`prop("version") > "9a"`, false},
+		{`"10" > "9"`, true},
+		{`"10" < "9a"`, true},
+		{`1.50 == 1.5`, true},
+		{`-1 < 0`, true},
+		{`prop("port") >= 8080 && prop("port") <= 8080`, true},
+		{`prop("port") != 8080`, false},
+		// bool 比较
+		{`prop("enabled") == true`, true},
+		{`prop("enabled") != true`, false},
+		{`prop("enabled")`, true},
+		// 函数
+		{`prop("missing") == ""`, true},
+		{`prop("missing", "def") == "def"`, true},
+		{`prop("mode", "def") == "debug"`, true},
+		{`has("port") and !has("missing")`, true},
+		{`bean("*redis.Client") && !bean("*gorm.DB")`, true},
+		{`profile("test")`, true},
+		{`profile("prod", "dev")`, true},
+		{`profile("prod")`, false},
+	}
+	for _, c := range testcases {
+		n, err := parseExpression(c.expr)
+		assert.Nil(t, err, c.expr)
+		ok, err := evalBool(ctx, n)
+		assert.Nil(t, err, c.expr)
+		assert.Equal(t, ok, c.want, c.expr)
+	}
+}
+
+func TestExpression_ParseError(t *testing.T) {
+	testcases := []struct {
+		expr string
+		err  string
+	}{
+		{``, "表达式意外结束"},
+		{`true &&`, "表达式意外结束"},
+		{`"abc`, "位置 0 字符串没有结束"},
+		{`true == 'abc`, "位置 8 字符串没有结束"},
+		{`"\x"`, "位置 0 字符串错误"},
+		{`true # false`, "位置 5 非法字符 '#'"},
+		{`true false`, `位置 5 多余的内容 "false"`},
+		{`(true`, `位置 5 需要 "\)" 但是得到 ""`},
+		{`1.2.3 == 1`, `位置 0 数字错误 "1.2.3"`},
+		{`== 1`, `位置 0 非法的内容 "=="`},
+		{`foo("a")`, `位置 0 未知的函数 "foo"`},
+		{`has "a"`, `位置 4 需要 "\(" 但是得到 "a"`},
+		{`prop("mode") in "a"`, `位置 16 需要 "\[" 但是得到 "a"`},
+		{`prop("mode") in ["a" "b"]`, `位置 21 需要 "]" 但是得到 "b"`},
+		// 函数参数个数
+		{`prop()`, "位置 0 函数 prop 的参数个数错误"},
+		{`prop("a", "b", "c")`, "位置 0 函数 prop 的参数个数错误"},
+		{`true && has("a", "b")`, "位置 8 函数 has 的参数个数错误"},
+		{`bean()`, "位置 0 函数 bean 的参数个数错误"},
+		{`profile()`, "位置 0 函数 profile 的参数个数错误"},
+	}
+	for _, c := range testcases {
+		_, err := parseExpression(c.expr)
+		assert.Error(t, err, c.err)
+	}
+}
+
+func TestExpression_EvalError(t *testing.T) {
+	ctx := &exprContext{props: map[string]string{"mode": "debug"}}
+	testcases := []struct {
+		expr string
+		err  string
+	}{
+		{`prop("mode")`, `"debug" 不是 bool 值`},
+		{`1 && true`, "1 不是 bool 值"},
+		{`true < false`, "bool 值不支持 < 操作"},
+		{`prop("mode") == true`, `"debug" 不是 bool 值`},
+		{`bean("error")`, "find error"},
+	}
+	for _, c := range testcases {
+		n, err := parseExpression(c.expr)
+		assert.Nil(t, err, c.expr)
+		_, err = evalBool(ctx, n)
+		assert.Error(t, err, c.err)
+	}
+}
+
+func TestOnExpression(t *testing.T) {
+	ctx := &exprContext{props: map[string]string{"mode": "debug"}}
+
+	ok, err := OnExpression(`prop("mode") == "debug"`).Matches(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 表达式在注册时解析，可以并发计算
+	c := OnExpression(`prop("mode") in ["debug"]`)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := c.Matches(ctx)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+
+	_, err = OnExpression(`prop("mode")`).Matches(ctx)
+	assert.Error(t, err, `表达式 "prop\(\\"mode\\"\)" 计算错误: "debug" 不是 bool 值`)
+
+	// 语法错误在注册时发现，在条件判断时返回
+	c = OnExpression(`true &&`)
+	_, err = c.Matches(ctx)
+	assert.Error(t, err, `表达式 "true &&" 解析错误: 表达式意外结束`)
+}
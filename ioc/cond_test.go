@@ -0,0 +1,40 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/cond"
+)
+
+func TestContainer_OnExpression(t *testing.T) {
+
+	c := ioc.New()
+	c.Property("mode", "debug")
+	c.Object(new(int)).Name("debug").On(cond.OnExpression(`prop("mode") == "debug"`))
+	c.Object(new(int)).Name("release").On(cond.OnExpression(`prop("mode") == "release"`))
+	assert.Nil(t, c.Refresh())
+
+	// 表达式的语法错误带有 bean 注册的位置
+	c = ioc.New()
+	c.Object(new(int)).On(cond.OnExpression(`prop("mode") ==`))
+	err := c.Refresh()
+	assert.Error(t, err, `cond_test.go:\d+ 条件判断异常: 表达式 "prop\(\\"mode\\"\) ==" 解析错误`)
+}
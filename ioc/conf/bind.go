@@ -188,7 +188,10 @@ func getSliceValue(p *Properties, et reflect.Type, param BindParam) (*Properties
 	primitive := IsPrimitiveValueType(et)
 
 	if p.Has(param.Key) {
-		strVal = p.Get(param.Key)
+		var err error
+		if strVal, _, err = decrypt(p.m[param.Key]); err != nil {
+			return nil, util.Errorf(code.FileLine(), "property %q 解密异常 %v", param.Key, err)
+		}
 	} else {
 		if !param.Tag.HasDef {
 			return nil, util.Errorf(code.FileLine(), "property %q %w", param.Key, ErrNotExist)
@@ -489,9 +492,17 @@ func resolveString(p *Properties, s string) (string, error) {
 }
 
 // resolve 解析 ${key:=def} 字符串，返回 key 对应的属性值，如果没有找到则返回
-// def 值，如果 def 存在引用则递归解析直到获取最终的属性值。
+// def 值，如果 def 存在引用则递归解析直到获取最终的属性值。加密的属性值会被解密。
 func resolve(p *Properties, param BindParam) (string, error) {
 	if val, ok := p.m[param.Key]; ok {
+		// 解密后的属性值不再解析其中的引用
+		s, encrypted, err := decrypt(val)
+		if err != nil {
+			return "", util.Errorf(code.FileLine(), "property %q 解密异常 %v", param.Key, err)
+		}
+		if encrypted {
+			return s, nil
+		}
 		return resolveString(p, val)
 	}
 	if param.Tag.HasDef {
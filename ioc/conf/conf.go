@@ -81,6 +81,12 @@ func RegisterReader(r Reader, ext ...string) {
 	}
 }
 
+// HasReader 返回是否注册了支持扩展名 ext 的属性列表解析器。
+func HasReader(ext string) bool {
+	_, ok := readers[ext]
+	return ok
+}
+
 // RegisterSplitter 注册字符串分割器。
 func RegisterSplitter(name string, fn Splitter) {
 	splitters[name] = fn
@@ -121,6 +127,7 @@ func RegisterConverter(fn interface{}) {
 type Properties struct {
 	m map[string]string      // 一维，存储 key 和 value。
 	t map[string]interface{} // 树形，存储 key 的节点路由。
+	s map[string]string      // 存储 key 的来源，例如文件名、env、args 等。
 }
 
 // New 返回一个空的属性列表。
@@ -128,6 +135,7 @@ func New() *Properties {
 	return &Properties{
 		m: make(map[string]string),
 		t: make(map[string]interface{}),
+		s: make(map[string]string),
 	}
 }
 
@@ -152,13 +160,13 @@ func Load(file string) (*Properties, error) {
 }
 
 // Load 从属性文件加载属性列表，file 可以是绝对路径，也可以是相对路径。该方法会覆盖
-// 已有的属性值。
+// 已有的属性值，并将 file 记录为这些属性的来源。
 func (p *Properties) Load(file string) error {
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
 		return err
 	}
-	return p.Bytes(b, filepath.Ext(file))
+	return p.bytes(b, filepath.Ext(file), file)
 }
 
 // Read 返回一个由 io.Reader 创建的属性列表，ext 是文件扩展名，如 .yaml、.toml 等。
@@ -182,6 +190,10 @@ func Bytes(b []byte, ext string) (*Properties, error) {
 // Bytes 从 []byte 加载属性列表，ext 是文件扩展名，如 .yaml、.toml 等。该方法会覆
 // 盖已有的属性值。
 func (p *Properties) Bytes(b []byte, ext string) error {
+	return p.bytes(b, ext, "")
+}
+
+func (p *Properties) bytes(b []byte, ext string, source string) error {
 	r, ok := readers[ext]
 	if !ok {
 		return fmt.Errorf("unsupported file type %s", ext)
@@ -196,7 +208,7 @@ func (p *Properties) Bytes(b []byte, ext string) error {
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		err = p.Set(k, m[k])
+		err = p.set(k, m[k], source)
 		if err != nil {
 			return err
 		}
@@ -325,10 +337,16 @@ func Def(v string) GetOption {
 // Get 获取 key 对应的属性值，注意 key 是大小写敏感的。当 key 对应的属性值存在时，
 // 或者 key 对应的属性值不存在但设置了默认值时，Get 方法返回 string 类型的数据，
 // 当 key 对应的属性值不存在且没有设置默认值时 Get 方法返回 nil。因此可以通过判断
-// Get 方法的返回值是否为 nil 来判断 key 对应的属性值是否存在。
+// Get 方法的返回值是否为 nil 来判断 key 对应的属性值是否存在。ENC(...) 格式的属
+// 性值会被解密后返回，解密失败时不返回密文而是返回空字符串，需要获取解密错误时使用
+// Resolve 或者 Bind 。
 func (p *Properties) Get(key string, opts ...GetOption) string {
 	if val, ok := p.m[key]; ok {
-		return val
+		s, _, err := decrypt(val)
+		if err != nil {
+			return ""
+		}
+		return s
 	}
 	arg := getArg{}
 	for _, opt := range opts {
@@ -337,6 +355,50 @@ func (p *Properties) Get(key string, opts ...GetOption) string {
 	return arg.def
 }
 
+// CheckEncrypted 检查 keys 对应的 ENC(...) 格式的属性值是否都能解密，keys 为空时
+// 检查全部属性，返回第一个解密失败的错误。
+func (p *Properties) CheckEncrypted(keys ...string) error {
+	if len(keys) == 0 {
+		keys = p.Keys()
+	}
+	for _, key := range keys {
+		val, ok := p.m[key]
+		if !ok {
+			continue
+		}
+		if _, _, err := decrypt(val); err != nil {
+			return fmt.Errorf("属性 %s 解密异常: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Raw 返回 key 对应的原始属性值，加密的属性值不会被解密，用于复制属性等不需要明文的场景。
+func (p *Properties) Raw(key string) string {
+	return p.m[key]
+}
+
+// Source 返回 key 对应属性的来源，例如文件名、env、args 等，来源未知时返回空字符串。
+func (p *Properties) Source(key string) string {
+	return p.s[key]
+}
+
+// SetSource 设置 key 对应属性的来源，key 不存在时忽略。复制属性时需要同时复制属性
+// 的来源，以便校验失败时能够指出属性来自哪里。
+func (p *Properties) SetSource(key string, source string) {
+	if _, ok := p.m[key]; ok {
+		p.setSource(key, source)
+	}
+}
+
+func (p *Properties) setSource(key string, source string) {
+	if source == "" {
+		delete(p.s, key)
+	} else {
+		p.s[key] = source
+	}
+}
+
 // Set 设置 key 对应的属性值，如果 key 对应的属性值已经存在则 Set 方法会覆盖旧
 // 值。Set 方法除了支持 string 类型的属性值，还支持 int、uint、bool 等其他基础
 // 数据类型的属性值。特殊情况下，Set 方法也支持 slice 、map 与基础数据类型组合构
@@ -344,6 +406,11 @@ func (p *Properties) Get(key string, opts ...GetOption) string {
 // 点的路径就是属性的 key，叶子结点的值就是属性的值。注意: conf 的配置文件是补充
 // 关系，而不是替换关系，这一条原则我也经常会搞混，尤其在和其他配置库相比较的时候。
 func (p *Properties) Set(key string, val interface{}) error {
+	return p.set(key, val, "")
+}
+
+// set 设置属性值，同时记录属性的来源，source 为空时清除属性原来的来源。
+func (p *Properties) set(key string, val interface{}, source string) error {
 	switch v := reflect.ValueOf(val); v.Kind() {
 	case reflect.Map:
 		exist, err := p.checkKey(key, true)
@@ -352,18 +419,20 @@ func (p *Properties) Set(key string, val interface{}) error {
 		}
 		if v.Len() == 0 && !exist {
 			p.m[key] = ""
+			p.setSource(key, source)
 			return nil
 		}
 		for _, k := range v.MapKeys() {
 			mapValue := v.MapIndex(k).Interface()
 			mapKey := cast.ToString(k.Interface())
-			err = p.Set(key+"."+mapKey, mapValue)
+			err = p.set(key+"."+mapKey, mapValue, source)
 			if err != nil {
 				return err
 			}
 		}
 		if _, ok := p.m[key]; ok {
 			delete(p.m, key)
+			delete(p.s, key)
 		}
 	case reflect.Array, reflect.Slice:
 		exist, err := p.checkKey(key, true)
@@ -372,18 +441,20 @@ func (p *Properties) Set(key string, val interface{}) error {
 		}
 		if v.Len() == 0 && !exist {
 			p.m[key] = ""
+			p.setSource(key, source)
 			return nil
 		}
 		for i := 0; i < v.Len(); i++ {
 			subKey := fmt.Sprintf("%s[%d]", key, i)
 			subValue := v.Index(i).Interface()
-			err := p.Set(subKey, subValue)
+			err := p.set(subKey, subValue, source)
 			if err != nil {
 				return err
 			}
 		}
 		if _, ok := p.m[key]; ok {
 			delete(p.m, key)
+			delete(p.s, key)
 		}
 	default:
 		_, err := p.checkKey(key, false)
@@ -391,6 +462,7 @@ func (p *Properties) Set(key string, val interface{}) error {
 			return err
 		}
 		p.m[key] = cast.ToString(val)
+		p.setSource(key, source)
 	}
 	return nil
 }
@@ -430,6 +502,7 @@ func Tag(tag string) BindOption {
 // 二是可以省略属性名而只有默认值，即 ${:=b}，原因是某些情况下属性名可能没想好或
 // 者不太重要，比如，得益于字符串差值的实现，这种语法可以用于动态生成新的属性值，
 // 也有人认为这是一种对 Golang 缺少默认值语法的补充，Bug is Feature。
+// 绑定完成后使用 validate 标签以及 Validator 接口进行校验，参考 Validate 的解释。
 func (p *Properties) Bind(i interface{}, opts ...BindOption) error {
 
 	var v reflect.Value
@@ -459,5 +532,11 @@ func (p *Properties) Bind(i interface{}, opts ...BindOption) error {
 	if err := param.BindTag(arg.tag); err != nil {
 		return err
 	}
-	return BindValue(p, v, param)
+	if err := CheckRules(t); err != nil {
+		return err
+	}
+	if err := BindValue(p, v, param); err != nil {
+		return err
+	}
+	return Validate(p, v, param)
 }
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// EncryptKeyEnv 保存 AES 密钥的环境变量，密钥为 base64 编码的 16、24 或 32 字节。
+	EncryptKeyEnv = "CONFIG_ENCRYPT_KEY"
+
+	// EncryptKeyFileEnv 保存 AES 密钥文件路径的环境变量，文件内容为 base64 编码的密钥。
+	EncryptKeyFileEnv = "CONFIG_ENCRYPT_KEY_FILE"
+)
+
+// Decryptor 属性值解密器，可以实现该接口对接 KMS 等密钥管理服务。
+type Decryptor interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+var (
+	decryptMutex    sync.RWMutex
+	decryptor       Decryptor
+	decryptorInited bool
+	encryptedPrefix = "ENC("
+	encryptedSuffix = ")"
+	errNoDecryptor  = errors.New("未注册属性值解密器")
+
+	// decryptCache 缓存密文对应的明文，避免对接 KMS 的解密器在每次读取属性时都发起
+	// 请求，注册解密器或者修改密文格式时清空。
+	decryptCache sync.Map
+)
+
+func clearDecryptCache() {
+	decryptCache.Range(func(key, _ interface{}) bool {
+		decryptCache.Delete(key)
+		return true
+	})
+}
+
+// RegisterDecryptor 注册属性值解密器，ENC(...) 格式的属性值在 Get 和 Bind 时使用
+// 该解密器解密。未注册时如果设置了 CONFIG_ENCRYPT_KEY 或 CONFIG_ENCRYPT_KEY_FILE
+// 环境变量则使用 AES-GCM 解密器。
+func RegisterDecryptor(d Decryptor) {
+	decryptMutex.Lock()
+	defer decryptMutex.Unlock()
+	decryptor = d
+	decryptorInited = true
+	clearDecryptCache()
+}
+
+// SetEncryptedFormat 设置加密属性值的前缀和后缀，默认为 ENC( 和 ) 。
+func SetEncryptedFormat(prefix, suffix string) {
+	decryptMutex.Lock()
+	defer decryptMutex.Unlock()
+	encryptedPrefix = prefix
+	encryptedSuffix = suffix
+	clearDecryptCache()
+}
+
+// IsEncrypted 返回属性值是否是加密的。
+func IsEncrypted(s string) bool {
+	decryptMutex.RLock()
+	defer decryptMutex.RUnlock()
+	return isEncrypted(s)
+}
+
+func isEncrypted(s string) bool {
+	return len(s) >= len(encryptedPrefix)+len(encryptedSuffix) &&
+		strings.HasPrefix(s, encryptedPrefix) && strings.HasSuffix(s, encryptedSuffix)
+}
+
+// getDecryptor 返回注册的解密器，第一次调用时尝试使用环境变量中的密钥创建解密器。
+func getDecryptor() (Decryptor, error) {
+	decryptMutex.RLock()
+	if decryptorInited {
+		defer decryptMutex.RUnlock()
+		return decryptor, nil
+	}
+	decryptMutex.RUnlock()
+
+	decryptMutex.Lock()
+	defer decryptMutex.Unlock()
+	if !decryptorInited {
+		d, err := envDecryptor()
+		if err != nil {
+			return nil, err
+		}
+		decryptor = d
+		decryptorInited = true
+	}
+	return decryptor, nil
+}
+
+// envDecryptor 使用环境变量中的密钥创建 AES-GCM 解密器，没有设置密钥时返回 nil 。
+func envDecryptor() (Decryptor, error) {
+	s, ok := os.LookupEnv(EncryptKeyEnv)
+	if !ok {
+		file, ok := os.LookupEnv(EncryptKeyFileEnv)
+		if !ok {
+			return nil, nil
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s = string(b)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("解析AES密钥异常: %w", err)
+	}
+	return NewAESDecryptor(key)
+}
+
+// decrypt 解密 ENC(...) 格式的属性值，返回解密后的值以及属性值是否是加密的。解密
+// 成功的结果会被缓存，同一个密文只解密一次。
+func decrypt(s string) (string, bool, error) {
+	decryptMutex.RLock()
+	prefix, suffix := encryptedPrefix, encryptedSuffix
+	encrypted := isEncrypted(s)
+	decryptMutex.RUnlock()
+	if !encrypted {
+		return s, false, nil
+	}
+	if v, ok := decryptCache.Load(s); ok {
+		return v.(string), true, nil
+	}
+	d, err := getDecryptor()
+	if err != nil {
+		return "", true, err
+	}
+	if d == nil {
+		return "", true, errNoDecryptor
+	}
+	v, err := d.Decrypt(s[len(prefix) : len(s)-len(suffix)])
+	if err != nil {
+		return "", true, err
+	}
+	decryptCache.Store(s, v)
+	return v, true, nil
+}
+
+// aesDecryptor 使用 AES-GCM 解密属性值，密文为 base64 编码的 nonce 与加密结果的拼接。
+type aesDecryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESDecryptor 创建 AES-GCM 解密器，key 的长度必须为 16、24 或 32 字节。
+func NewAESDecryptor(key []byte) (Decryptor, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesDecryptor{aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (d *aesDecryptor) Decrypt(ciphertext string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	n := d.aead.NonceSize()
+	if len(b) < n {
+		return "", errors.New("密文长度错误")
+	}
+	plaintext, err := d.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// AESEncrypt 使用 AES-GCM 加密 plaintext ，返回可以写入配置文件的 ENC(...) 格式的属性值。
+func AESEncrypt(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	b := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	decryptMutex.RLock()
+	defer decryptMutex.RUnlock()
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(b) + encryptedSuffix, nil
+}
@@ -0,0 +1,475 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huazai2008101/stark/base/util"
+)
+
+// Validator 自定义校验接口，绑定的结构体实现该接口后在属性绑定完成后调用。IoC 容器
+// 中的 bean 只有通过 value 标签绑定的结构体字段才会调用，bean 本身的 Validate 方法
+// 不会被调用，避免误调用业务对象上同名的方法。
+type Validator interface {
+	Validate() error
+}
+
+// ValidateRule 校验规则，v 为绑定后的字段值，arg 为规则的参数，例如 min=1 中的 1 。
+type ValidateRule func(v reflect.Value, arg string) error
+
+var rules = map[string]ValidateRule{}
+
+func init() {
+	RegisterValidateRule("required", validateRequired)
+	RegisterValidateRule("min", validateMin)
+	RegisterValidateRule("max", validateMax)
+	RegisterValidateRule("oneof", validateOneOf)
+	RegisterValidateRule("regex", validateRegex)
+	RegisterValidateRule("url", validateURL)
+	RegisterValidateRule("hostport", validateHostPort)
+}
+
+// RegisterValidateRule 注册校验规则，可以覆盖内置的规则，需要在属性绑定之前注册。
+func RegisterValidateRule(name string, fn ValidateRule) {
+	rules[name] = fn
+}
+
+// Violation 违反校验规则的属性，Key 为属性名，Path 为字段的路径，Source 为属性的
+// 来源，例如文件名、env、args 等，属性不存在或者来源未知时为空。
+type Violation struct {
+	Key     string
+	Path    string
+	Source  string
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Source == "" {
+		return fmt.Sprintf("%s(%s) %s: %s", v.Key, v.Path, v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s(%s) from %s %s: %s", v.Key, v.Path, v.Source, v.Rule, v.Message)
+}
+
+// ValidationError 一个对象的全部校验错误。
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var ss []string
+	for _, v := range e.Violations {
+		ss = append(ss, v.String())
+	}
+	return "属性校验失败: " + strings.Join(ss, "; ")
+}
+
+// Validate 使用 validate 标签以及 Validator 接口校验已经完成属性绑定的 v ，返回
+// 包含全部校验错误的 *ValidationError 。validate 标签的语法为 validate:"required,min=1"，
+// 多个规则使用逗号分隔，regex 规则的参数可以包含逗号，因此必须是最后一个规则。p 为
+// 绑定时使用的属性列表，用于记录违反规则的属性的来源，可以为 nil 。
+func Validate(p *Properties, v reflect.Value, param BindParam) error {
+	var violations []Violation
+	validateValue(p, v, param, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// ValidateBean 校验 IoC 容器中的 bean ，与 Validate 的区别是只校验使用 value 标签
+// 绑定的字段，因为 bean 中没有标签的字段不会进行属性绑定。bean 本身以及匿名字段不
+// 是属性绑定的目标，因此不会调用它们的 Validator 接口。
+func ValidateBean(p *Properties, v reflect.Value, param BindParam) error {
+	var violations []Violation
+	validateStruct(p, v, param, true, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+func validateValue(p *Properties, v reflect.Value, param BindParam, violations *[]Violation) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if converters[v.Type()] == nil {
+			validateStruct(p, v, param, false, violations)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			subParam := BindParam{
+				Type: v.Type().Elem(),
+				Key:  fmt.Sprintf("%s[%d]", param.Key, i),
+				Path: fmt.Sprintf("%s[%d]", param.Path, i),
+			}
+			validateValue(p, v.Index(i), subParam, violations)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			s := fmt.Sprint(k.Interface())
+			subParam := BindParam{
+				Type: v.Type().Elem(),
+				Key:  param.Key + "." + s,
+				Path: param.Path + "[" + s + "]",
+			}
+			validateValue(p, v.MapIndex(k), subParam, violations)
+		}
+	}
+}
+
+// validateStruct 按照 bindStruct 的规则计算字段的属性名，然后校验字段，tagged 表示
+// 只校验具有 value 标签的字段以及匿名字段，此时 v 不是属性绑定的目标，不调用 Validator 接口。
+func validateStruct(p *Properties, v reflect.Value, param BindParam, tagged bool, violations *[]Violation) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		fv := v.Field(i)
+
+		if !fv.CanInterface() {
+			fv = util.PatchValue(fv)
+			if !fv.CanInterface() {
+				continue
+			}
+		}
+
+		subParam := BindParam{
+			Type: ft.Type,
+			Key:  param.Key,
+			Path: param.Path + "." + ft.Name,
+		}
+
+		if tag, ok := ft.Tag.Lookup("value"); ok {
+			if err := subParam.BindTag(tag); err != nil {
+				continue
+			}
+		} else if ft.Anonymous {
+			if tagged {
+				if ft.Type.Kind() == reflect.Struct {
+					validateStruct(p, fv, subParam, true, violations)
+				}
+				continue
+			}
+		} else if tagged {
+			continue
+		} else {
+			if subParam.Key == "" {
+				subParam.Key = ft.Name
+			} else {
+				subParam.Key = subParam.Key + "." + ft.Name
+			}
+		}
+
+		if tag, ok := ft.Tag.Lookup("validate"); ok {
+			validateField(p, fv, subParam, tag, violations)
+		}
+		validateValue(p, fv, subParam, violations)
+	}
+
+	if tagged {
+		return
+	}
+
+	i := v.Interface()
+	if v.CanAddr() {
+		i = v.Addr().Interface()
+	}
+	if r, ok := i.(Validator); ok {
+		if err := r.Validate(); err != nil {
+			*violations = append(*violations, Violation{
+				Key:     param.Key,
+				Path:    param.Path,
+				Rule:    "Validator",
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+// validateField 依次执行 tag 中的校验规则。
+func validateField(p *Properties, v reflect.Value, param BindParam, tag string, violations *[]Violation) {
+	var source string
+	if p != nil {
+		source = p.Source(param.Key)
+	}
+	addViolation := func(rule string, err error) {
+		*violations = append(*violations, Violation{
+			Key:     param.Key,
+			Path:    param.Path,
+			Source:  source,
+			Rule:    rule,
+			Message: err.Error(),
+		})
+	}
+	rs, err := parseRules(tag)
+	if err != nil {
+		addViolation(tag, err)
+		return
+	}
+	for _, r := range rs {
+		if err = r.fn(v, r.arg); err != nil {
+			addViolation(r.rule, err)
+		}
+	}
+}
+
+// validateRule 解析后的校验规则。
+type validateRule struct {
+	rule string // 原始的规则，例如 min=1
+	arg  string
+	fn   ValidateRule
+}
+
+// parseRules 解析 validate 标签，标签中存在未知的校验规则时返回 error 。
+func parseRules(tag string) ([]validateRule, error) {
+	var ret []validateRule
+	for _, s := range splitRules(tag) {
+		name, arg := s, ""
+		if i := strings.Index(s, "="); i > 0 {
+			name, arg = s[:i], s[i+1:]
+		}
+		fn, ok := rules[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的校验规则 %q", name)
+		}
+		ret = append(ret, validateRule{rule: s, arg: arg, fn: fn})
+	}
+	return ret, nil
+}
+
+// checkedTypes 已经检查过 validate 标签的类型。
+var checkedTypes sync.Map
+
+// CheckRules 检查类型 t 以及字段类型中的 validate 标签，存在未知的校验规则时返回
+// error ，在属性绑定之前调用，这样标签中的错误不必等到属性有值之后才能发现。
+func CheckRules(t reflect.Type) error {
+	return checkRules(t, map[reflect.Type]bool{})
+}
+
+func checkRules(t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice ||
+		t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	if _, ok := checkedTypes.Load(t); ok {
+		return nil
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if tag, ok := ft.Tag.Lookup("validate"); ok {
+			if _, err := parseRules(tag); err != nil {
+				return fmt.Errorf("%s.%s %w", t.Name(), ft.Name, err)
+			}
+		}
+		if err := checkRules(ft.Type, visited); err != nil {
+			return err
+		}
+	}
+	checkedTypes.Store(t, true)
+	return nil
+}
+
+// splitRules 使用逗号分割校验规则，regex 规则之后的内容都作为 regex 的参数。
+func splitRules(tag string) []string {
+	var ret []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(ret, tag)
+		}
+		i := strings.Index(tag, ",")
+		if i < 0 {
+			return append(ret, strings.TrimSpace(tag))
+		}
+		if s := strings.TrimSpace(tag[:i]); s != "" {
+			ret = append(ret, s)
+		}
+		tag = strings.TrimSpace(tag[i+1:])
+	}
+	return ret
+}
+
+func validateRequired(v reflect.Value, arg string) error {
+	if v.IsZero() {
+		return errors.New("不能为空")
+	}
+	return nil
+}
+
+// compare 比较 v 与 arg 的大小，数值类型比较值，time.Duration 比较时长，字符
+// 串、切片和 map 比较长度。
+func compare(v reflect.Value, arg string) (int, error) {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, err
+		}
+		return compareInt(v.Int(), int64(d)), nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(arg, 0, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compareInt(v.Int(), i), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case v.Uint() < u:
+			return -1, nil
+		case v.Uint() > u:
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case v.Float() < f:
+			return -1, nil
+		case v.Float() > f:
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		i, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, err
+		}
+		return compareInt(int64(v.Len()), int64(i)), nil
+	}
+	return 0, fmt.Errorf("类型 %s 不支持该规则", v.Type())
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func validateMin(v reflect.Value, arg string) error {
+	r, err := compare(v, arg)
+	if err != nil {
+		return err
+	}
+	if r < 0 {
+		return fmt.Errorf("%v 小于 %s", v.Interface(), arg)
+	}
+	return nil
+}
+
+func validateMax(v reflect.Value, arg string) error {
+	r, err := compare(v, arg)
+	if err != nil {
+		return err
+	}
+	if r > 0 {
+		return fmt.Errorf("%v 大于 %s", v.Interface(), arg)
+	}
+	return nil
+}
+
+// validateOneOf 校验值是否是 arg 中使用空格分隔的值之一。
+func validateOneOf(v reflect.Value, arg string) error {
+	s := fmt.Sprint(v.Interface())
+	for _, a := range strings.Fields(arg) {
+		if s == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 不是 [%s] 之一", s, arg)
+}
+
+func validateRegex(v reflect.Value, arg string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("类型 %s 不支持该规则", v.Type())
+	}
+	r, err := regexp.Compile(arg)
+	if err != nil {
+		return err
+	}
+	if !r.MatchString(v.String()) {
+		return fmt.Errorf("%q 不匹配 %s", v.String(), arg)
+	}
+	return nil
+}
+
+// validateURL 校验值是否是包含 scheme 和 host 的 url ，空字符串不校验。
+func validateURL(v reflect.Value, arg string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("类型 %s 不支持该规则", v.Type())
+	}
+	if v.String() == "" {
+		return nil
+	}
+	u, err := url.Parse(v.String())
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q 不是合法的url", v.String())
+	}
+	return nil
+}
+
+// validateHostPort 校验值是否是 host:port 格式，多个地址使用逗号分隔，空字符串不校验。
+func validateHostPort(v reflect.Value, arg string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("类型 %s 不支持该规则", v.Type())
+	}
+	if v.String() == "" {
+		return nil
+	}
+	for _, s := range strings.Split(v.String(), ",") {
+		_, port, err := net.SplitHostPort(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+			return fmt.Errorf("%q 端口错误", s)
+		}
+	}
+	return nil
+}
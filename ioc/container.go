@@ -44,6 +44,7 @@ const (
 	Unrefreshed = refreshState(iota) // 未刷新
 	Refreshing                       // 正在刷新
 	Refreshed                        // 已刷新
+	Closed                           // 已关闭
 )
 
 type Container interface {
@@ -52,6 +53,7 @@ type Container interface {
 	Object(i interface{}) *BeanDefinition
 	Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition
 	Refresh(opts ...internal.RefreshOption) error
+	RefreshProperties(p *conf.Properties) error
 	Go(fn func(ctx context.Context))
 	Close()
 }
@@ -74,14 +76,14 @@ type Context interface {
 	Wire(objOrCtor interface{}, ctorArgs ...arg.Arg) (interface{}, error)
 	Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, error)
 	Go(fn func(ctx context.Context))
+	Beans() []*BeanDefinition
+	BeanGraph() *BeanGraph
 }
 
 type tempContainer struct {
-	p               *conf.Properties
-	beans           []*BeanDefinition
-	beansByName     map[string][]*BeanDefinition
-	beansByType     map[reflect.Type][]*BeanDefinition
-	mapOfOnProperty map[string]interface{}
+	beans       []*BeanDefinition
+	beansByName map[string][]*BeanDefinition
+	beansByType map[reflect.Type][]*BeanDefinition
 }
 
 // container 是 stark 框架的基石，实现了 Martin Fowler 在 << Inversion
@@ -96,23 +98,46 @@ type container struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	destroyers []func()
-	state      refreshState
+	state      refreshState // 在 pMutex 的保护下修改，属性更新时需要并发读取
 	wg         sync.WaitGroup
+	// 刷新完成后保留的 bean 元数据，用于运行时查看容器内容。
+	beanDefs []*BeanDefinition
+	// 属性在容器刷新完成后依然保留，运行时可能被属性源更新。
+	p               *conf.Properties
+	mapOfOnProperty map[string]interface{}
+	pMutex          sync.RWMutex
+	// 属性更新时需要刷新的动态属性以及属性变化的校验器。
+	dynamic     []*dynamicField
+	validators  []PropertyValidator
+	updateMutex sync.Mutex
+	// 是否注入了 provider ，provider 在运行时按需获取 bean 。
+	hasProvider bool
+	// 各个自定义作用域的 bean 的数量，容器关闭时从全局计数中减去。
+	scopedBeans map[BeanScope]int
+	// 刷新作用域当前的周期，属性发生变化时替换。
+	refreshStore *ScopeStore
+	refreshMutex sync.Mutex
+	bus          *eventBus
+	// 刷新时用来替换其他 bean 的 bean ，主要用于测试。
+	replaces []beanReplace
 }
 
 // New 创建 IoC 容器。
 func New() Container {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &container{
-		ctx:    ctx,
-		cancel: cancel,
+	c := &container{
+		ctx:             ctx,
+		cancel:          cancel,
+		p:               conf.New(),
+		mapOfOnProperty: make(map[string]interface{}),
+		refreshStore:    NewScopeStore(),
 		tempContainer: &tempContainer{
-			p:               conf.New(),
-			beansByName:     make(map[string][]*BeanDefinition),
-			beansByType:     make(map[reflect.Type][]*BeanDefinition),
-			mapOfOnProperty: make(map[string]interface{}),
+			beansByName: make(map[string][]*BeanDefinition),
+			beansByType: make(map[reflect.Type][]*BeanDefinition),
 		},
 	}
+	c.bus = newEventBus(c)
+	return c
 }
 
 // Context 返回 IoC 容器的 ctx 对象。
@@ -131,24 +156,90 @@ func validOnProperty(fn interface{}) error {
 	return nil
 }
 
-// OnProperty 当 key 对应的属性值准备好后发送一个通知。
+// OnProperty 当 key 对应的属性值准备好后发送一个通知，运行时 key 或者它的子属性
+// 被属性源更新后也会再次通知。
 func (c *container) OnProperty(key string, fn interface{}) {
 	err := validOnProperty(fn)
 	util.Panic(err).When(err != nil)
+	c.pMutex.Lock()
+	defer c.pMutex.Unlock()
 	c.mapOfOnProperty[key] = fn
 }
 
+// callOnProperty 绑定 key 对应的属性值并调用 fn 。
+func callOnProperty(p *conf.Properties, key string, fn interface{}) error {
+	t := reflect.TypeOf(fn)
+	in := reflect.New(t.In(0)).Elem()
+	if err := p.Bind(in, conf.Key(key)); err != nil {
+		return err
+	}
+	reflect.ValueOf(fn).Call([]reflect.Value{in})
+	return nil
+}
+
+// properties 返回当前的属性。
+func (c *container) properties() *conf.Properties {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
+	return c.p
+}
+
+// diffProperties 返回值不相同的 key 的列表，包括新增和删除的 key 。
+func diffProperties(a, b *conf.Properties) []string {
+	var changed []string
+	for _, k := range a.Keys() {
+		if !b.Has(k) || a.Raw(k) != b.Raw(k) {
+			changed = append(changed, k)
+		}
+	}
+	for _, k := range b.Keys() {
+		if !a.Has(k) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// keysContain 返回 keys 中是否包含 key 或者 key 的子属性，key 为空时表示根属性。
+func keysContain(keys []string, key string) bool {
+	if key == "" {
+		return len(keys) > 0
+	}
+	for _, k := range keys {
+		if k == key || strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
 // Property 设置 key 对应的属性值，如果 key 对应的属性值已经存在则 Set 方法会
 // 覆盖旧值。Set 方法除了支持 string 类型的属性值，还支持 int、uint、bool 等
 // 其他基础数据类型的属性值。特殊情况下，Set 方法也支持 slice 、map 与基础数据
 // 类型组合构成的属性值，其处理方式是将组合结构层层展开，可以将组合结构看成一棵树，
 // 那么叶子结点的路径就是属性的 key，叶子结点的值就是属性的值。
 func (c *container) Property(key string, value interface{}) {
+	c.pMutex.Lock()
+	defer c.pMutex.Unlock()
 	c.p.Set(key, value)
 }
 
+// getState 返回容器的刷新状态，可以在运行时并发调用。
+func (c *container) getState() refreshState {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
+	return c.state
+}
+
+func (c *container) setState(state refreshState) {
+	c.pMutex.Lock()
+	defer c.pMutex.Unlock()
+	c.state = state
+}
+
 func (c *container) register(b *BeanDefinition) *BeanDefinition {
-	if c.state != Unrefreshed {
+	if c.getState() != Unrefreshed {
 		panic(errors.New("should call before Refresh"))
 	}
 	c.beans = append(c.beans, b)
@@ -165,6 +256,58 @@ func (c *container) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return c.register(NewBean(ctor, args...))
 }
 
+type beanReplace struct {
+	selector BeanSelector
+	bean     *BeanDefinition
+}
+
+// replace 注册 bean 并在刷新时替换 selector 选中的 bean ，被替换的 bean 标记为已
+// 删除，新的 bean 继承其名称、主版本标记以及自身实现了的导出接口。
+func (c *container) replace(selector BeanSelector, b *BeanDefinition) *BeanDefinition {
+	c.register(b)
+	c.replaces = append(c.replaces, beanReplace{selector: selector, bean: b})
+	return b
+}
+
+// replaceBeans 使用替换的 bean 删除被替换的 bean 。
+func (c *container) replaceBeans() error {
+	for _, r := range c.replaces {
+		match := selectorMatcher(r.selector)
+		exports := r.bean.exports
+		// (*error)(nil) 形式的选择器
+		if t := reflect.TypeOf(r.selector); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+			exports = append(exports, t.Elem())
+		}
+		var found []*BeanDefinition
+		for _, b := range c.beans {
+			if b == r.bean || b.status == Deleted || !match(b) {
+				continue
+			}
+			b.status = Deleted
+			b.condition = "replaced by " + r.bean.String()
+			exports = append(exports, b.exports...)
+			if b.Type() != r.bean.Type() {
+				exports = append(exports, b.Type())
+			}
+			found = append(found, b)
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("can't find bean to replace, bean:%q", toWireTag(r.selector))
+		}
+		if len(found) == 1 {
+			r.bean.name = found[0].name
+			r.bean.primary = found[0].primary
+		}
+		r.bean.exports = nil
+		for _, t := range exports {
+			if t.Kind() == reflect.Interface && r.bean.Type().Implements(t) {
+				r.bean.export(t)
+			}
+		}
+	}
+	return nil
+}
+
 // destroyer 保存具有销毁函数的 bean 以及销毁函数的调用顺序。
 type destroyer struct {
 	current *BeanDefinition
@@ -205,6 +348,7 @@ type lazyField struct {
 	v    reflect.Value
 	path string
 	tag  string
+	bean *BeanDefinition // 字段所属的 bean
 }
 
 // wiringStack 记录 bean 的注入路径。
@@ -213,12 +357,16 @@ type wiringStack struct {
 	destroyerMap map[string]*destroyer
 	beans        []*BeanDefinition
 	lazyFields   []lazyField
+	dependKind   string // 栈顶 bean 依赖下一个 bean 的方式
+	// 作用域 bean 注入时使用的属性快照，此时动态属性只绑定当前值，不随属性变化刷新。
+	p *conf.Properties
 }
 
 func newWiringStack() *wiringStack {
 	return &wiringStack{
 		destroyers:   list.New(),
 		destroyerMap: make(map[string]*destroyer),
+		dependKind:   AutowireDependency,
 	}
 }
 
@@ -236,12 +384,22 @@ func (s *wiringStack) popBack() {
 	log.Tracef(context.Background(), "pop %s %s", b, getStatusString(b.status))
 }
 
+// circle 返回从 b 第一次出现到栈顶的依赖环。
+func (s *wiringStack) circle(b *BeanDefinition) []*BeanDefinition {
+	for i, v := range s.beans {
+		if v == b {
+			return append([]*BeanDefinition{}, s.beans[i:]...)
+		}
+	}
+	return []*BeanDefinition{b}
+}
+
 // path 返回 bean 的注入路径。
 func (s *wiringStack) path() (path string) {
 	for _, b := range s.beans {
 		path += fmt.Sprintf("=> %s ↩\n", b)
 	}
-	return path[:len(path)-1]
+	return strings.TrimSuffix(path, "\n")
 }
 
 // saveDestroyer 记录具有销毁函数的 bean ，因为可能有多个依赖，因此需要排重处理。
@@ -286,6 +444,10 @@ func (s *wiringStack) sortDestroyers() []func() {
 }
 
 func (c *container) clear() {
+	// provider 在运行时需要通过容器查找 bean ，因此需要保留 bean 的索引。
+	if c.hasProvider {
+		return
+	}
 	c.tempContainer = nil
 }
 
@@ -293,15 +455,12 @@ func (c *container) clear() {
 func (c *container) Refresh(opts ...internal.RefreshOption) (err error) {
 
 	for key, f := range c.mapOfOnProperty {
-		t := reflect.TypeOf(f)
-		in := reflect.New(t.In(0)).Elem()
-		if err = c.p.Bind(in, conf.Key(key)); err != nil {
+		if err = callOnProperty(c.p, key, f); err != nil {
 			return err
 		}
-		reflect.ValueOf(f).Call([]reflect.Value{in})
 	}
 
-	if c.state != Unrefreshed {
+	if c.getState() != Unrefreshed {
 		return errors.New("container already refreshed")
 	}
 
@@ -313,7 +472,12 @@ func (c *container) Refresh(opts ...internal.RefreshOption) (err error) {
 	}
 
 	c.Object(c).Export((*Context)(nil))
-	c.state = Refreshing
+	c.Object(c.bus).Export((*EventBus)(nil))
+	c.setState(Refreshing)
+
+	if err = c.replaceBeans(); err != nil {
+		return err
+	}
 
 	for _, b := range c.beans {
 		c.registerBean(b)
@@ -346,8 +510,10 @@ func (c *container) Refresh(opts ...internal.RefreshOption) (err error) {
 
 	defer func() {
 		if err != nil || len(stack.beans) > 0 {
-			err = fmt.Errorf("%v ↩\n%s", err, stack.path())
-			log.Error(c.ctx, err)
+			// 错误由调用方报告，App 启动失败时只在最外层输出一次分析报告
+			err = &WiringError{Err: err, Beans: append([]*BeanDefinition{}, stack.beans...)}
+			// 保留刷新失败时的 bean 元数据，便于排查问题
+			c.beanDefs = append([]*BeanDefinition{}, c.beans...)
 		}
 	}()
 
@@ -369,13 +535,31 @@ func (c *container) Refresh(opts ...internal.RefreshOption) (err error) {
 	// 处理被标记为延迟注入的那些 bean 字段
 	for _, f := range stack.lazyFields {
 		tag := strings.TrimSuffix(f.tag, ",lazy")
+		if f.bean != nil {
+			stack.pushBack(f.bean)
+			stack.dependKind = LazyDependency
+		}
 		if err := c.wireByTag(f.v, tag, stack); err != nil {
-			return fmt.Errorf("%q wired error: %s", f.path, err.Error())
+			return fmt.Errorf("%q wired error: %w", f.path, err)
+		}
+		if f.bean != nil {
+			stack.popBack()
+			stack.dependKind = AutowireDependency
 		}
 	}
 
 	c.destroyers = stack.sortDestroyers()
-	c.state = Refreshed
+	c.collectPropertyValidators()
+	c.collectEventListeners()
+	c.beanDefs = append([]*BeanDefinition{}, c.beans...)
+	c.scopedBeans = make(map[BeanScope]int)
+	for _, b := range beansById {
+		if b.isScoped() {
+			c.scopedBeans[b.scope]++
+		}
+	}
+	addScopedBeans(c.scopedBeans, 1)
+	c.setState(Refreshed)
 
 	cost := time.Now().Sub(start)
 	log.Infof(c.ctx, "refresh %d beans cost %v", len(beansById), cost)
@@ -385,6 +569,7 @@ func (c *container) Refresh(opts ...internal.RefreshOption) (err error) {
 	}
 
 	log.Info(c.ctx, "container refreshed successfully")
+	c.publishEvent(&ContainerRefreshedEvent{})
 	return nil
 }
 
@@ -401,7 +586,7 @@ func (c *container) registerBean(b *BeanDefinition) {
 // resolveBean 判断 bean 的有效性，如果 bean 是无效的则被标记为已删除。
 func (c *container) resolveBean(b *BeanDefinition) error {
 
-	if b.status >= Resolving {
+	if b.status >= Resolving || b.status == Deleted {
 		return nil
 	}
 
@@ -426,6 +611,7 @@ func (c *container) resolveBean(b *BeanDefinition) error {
 			msg = msg[:len(msg)-2] + "]"
 			return errors.New(msg)
 		} else if n == 0 {
+			b.condition = "parent bean not found"
 			b.status = Deleted
 			return nil
 		}
@@ -433,11 +619,14 @@ func (c *container) resolveBean(b *BeanDefinition) error {
 
 	if b.cond != nil {
 		if ok, err := b.cond.Matches(c); err != nil {
-			return err
+			b.condition = "error: " + err.Error()
+			return fmt.Errorf("%s 条件判断异常: %w", b, err)
 		} else if !ok {
+			b.condition = "not matched"
 			b.status = Deleted
 			return nil
 		}
+		b.condition = "matched"
 	}
 
 	b.status = Resolved
@@ -533,12 +722,18 @@ func (c *container) findBean(selector BeanSelector) ([]*BeanDefinition, error) {
 		return result, nil
 	}
 
+	return finder(selectorMatcher(selector))
+}
+
+// selectorMatcher 返回判断 bean 是否符合选择器的函数。
+func selectorMatcher(selector BeanSelector) func(*BeanDefinition) bool {
+
 	switch selector.(type) {
 	case string, BeanDefinition, *BeanDefinition:
 		tag := toWireTag(selector)
-		return finder(func(b *BeanDefinition) bool {
+		return func(b *BeanDefinition) bool {
 			return b.Match(tag.typeName, tag.beanName)
-		})
+		}
 	}
 
 	t := reflect.TypeOf(selector)
@@ -548,7 +743,7 @@ func (c *container) findBean(selector BeanSelector) ([]*BeanDefinition, error) {
 		}
 	}
 
-	return finder(func(b *BeanDefinition) bool {
+	return func(b *BeanDefinition) bool {
 		if b.Type() == t {
 			return true
 		}
@@ -558,7 +753,7 @@ func (c *container) findBean(selector BeanSelector) ([]*BeanDefinition, error) {
 			}
 		}
 		return false
-	})
+	}
 }
 
 // wireBean 对 bean 进行属性绑定和依赖注入，同时追踪其注入路径。如果 bean 有初始
@@ -571,10 +766,16 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 	}
 
 	// 运行时 Get 或者 Wire 会出现下面这种情况。
-	if c.state == Refreshed && b.status == Wired {
+	if c.getState() == Refreshed && b.status == Wired {
 		return nil
 	}
 
+	// 记录栈顶 bean 对当前 bean 的依赖，当前 bean 的依赖项默认为注入方式。
+	if n := len(stack.beans); n > 0 {
+		stack.beans[n-1].addDependency(b, stack.dependKind)
+	}
+	stack.dependKind = AutowireDependency
+
 	haveDestroy := false
 
 	defer func() {
@@ -583,8 +784,15 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 		}
 	}()
 
+	// 自定义作用域的 bean 在作用域周期内初始化和销毁，刷新作用域由容器管理。
+	if b.isScoped() && b.scope != RefreshBeanScope {
+		if _, ok := getScope(b.scope); !ok {
+			return fmt.Errorf("%s scope %s not registered", b, b.scope)
+		}
+	}
+
 	// 记录注入路径上的销毁函数及其执行的先后顺序。
-	if _, ok := b.Interface().(BeanDestroy); ok || b.destroy != nil {
+	if _, ok := b.Interface().(BeanDestroy); (ok || b.destroy != nil) && !b.isScoped() {
 		haveDestroy = true
 		d := stack.saveDestroyer(b)
 		if i := stack.destroyers.Back(); i != nil {
@@ -598,7 +806,7 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 	if b.status == Creating && b.f != nil {
 		prev := stack.beans[len(stack.beans)-2]
 		if prev.status == Creating {
-			return errors.New("found circle autowire")
+			return &CircularDependencyError{Beans: stack.circle(b)}
 		}
 	}
 
@@ -616,6 +824,7 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 			return err
 		}
 		for _, d := range beans {
+			stack.dependKind = DependsOnDependency
 			err = c.wireBean(d, stack)
 			if err != nil {
 				return err
@@ -623,6 +832,18 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 		}
 	}
 
+	// 自定义作用域的 bean 在每个作用域周期内执行构造函数和注入，这里不创建。
+	if b.isScoped() {
+		for _, typ := range b.exports {
+			if !b.Type().Implements(typ) {
+				return fmt.Errorf("%s doesn't implement interface %s", b, typ)
+			}
+		}
+		b.status = Wired
+		stack.popBack()
+		return nil
+	}
+
 	v, err := c.getBeanValue(b, stack)
 	if err != nil {
 		return err
@@ -671,7 +892,7 @@ func (a *argContext) Matches(c cond.Condition) (bool, error) {
 }
 
 func (a *argContext) Bind(v reflect.Value, tag string) error {
-	return a.c.p.Bind(v, conf.Tag(tag))
+	return a.c.wiringProperties(a.stack).Bind(v, conf.Tag(tag))
 }
 
 func (a *argContext) Wire(v reflect.Value, tag string) error {
@@ -685,6 +906,13 @@ func (c *container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 		return b.Value(), nil
 	}
 
+	return c.callConstructor(b, b.Value(), stack)
+}
+
+// callConstructor 执行 bean 的构造函数并将结果保存到 v 中，v 的类型与 b.Value()
+// 相同，自定义作用域的 bean 在每个作用域周期内使用新的 v 保存构造函数的结果。
+func (c *container) callConstructor(b *BeanDefinition, v reflect.Value, stack *wiringStack) (reflect.Value, error) {
+
 	out, err := b.f.Call(&argContext{c: c, stack: stack})
 	if err != nil {
 		return reflect.Value{}, err /* fmt.Errorf("%s:%s return error: %v", b.getClass(), b.ID(), err) */
@@ -694,21 +922,23 @@ func (c *container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 	if val := out[0]; internal.IsBeanType(val.Type()) {
 		// 如果实现接口的是值类型，那么需要转换成指针类型然后再赋值给接口。
 		if !val.IsNil() && val.Kind() == reflect.Interface && conf.IsValueType(val.Elem().Type()) {
-			v := reflect.New(val.Elem().Type())
-			v.Elem().Set(val.Elem())
-			b.Value().Set(v)
+			r := reflect.New(val.Elem().Type())
+			r.Elem().Set(val.Elem())
+			v.Set(r)
 		} else {
-			b.Value().Set(val)
+			v.Set(val)
 		}
 	} else {
-		b.Value().Elem().Set(val)
+		if v.IsNil() {
+			v.Set(reflect.New(val.Type()))
+		}
+		v.Elem().Set(val)
 	}
 
-	if b.Value().IsNil() {
+	if v.IsNil() {
 		return reflect.Value{}, fmt.Errorf("%s:%q return nil", b.getClass(), b.FileLine())
 	}
 
-	v := b.Value()
 	// 结果以接口类型返回时需要将原始值取出来才能进行注入。
 	if b.Type().Kind() == reflect.Interface {
 		v = v.Elem()
@@ -734,8 +964,22 @@ func (c *container) wireBeanValue(v reflect.Value, t reflect.Type, stack *wiring
 		typeName = t.String()
 	}
 
+	if err := conf.CheckRules(t); err != nil {
+		return err
+	}
 	param := conf.BindParam{Type: t, Path: typeName}
-	return c.wireStruct(v, param, stack)
+	if err := c.wireStruct(v, param, stack); err != nil {
+		return err
+	}
+	return conf.ValidateBean(c.wiringProperties(stack), v, param)
+}
+
+// wiringProperties 返回注入时使用的属性。
+func (c *container) wiringProperties(stack *wiringStack) *conf.Properties {
+	if stack.p != nil {
+		return stack.p
+	}
+	return c.p
 }
 
 // wireStruct 对结构体进行依赖注入，需要注意的是这里不需要进行属性绑定。
@@ -762,6 +1006,9 @@ func (c *container) wireStruct(v reflect.Value, opt conf.BindParam, stack *wirin
 		if ok {
 			if strings.HasSuffix(tag, ",lazy") {
 				f := lazyField{v: fv, path: fieldPath, tag: tag}
+				if n := len(stack.beans); n > 0 {
+					f.bean = stack.beans[n-1]
+				}
 				stack.lazyFields = append(stack.lazyFields, f)
 			} else {
 				if err := c.wireByTag(fv, tag, stack); err != nil {
@@ -781,12 +1028,20 @@ func (c *container) wireStruct(v reflect.Value, opt conf.BindParam, stack *wirin
 			if err := subParam.BindTag(tag); err != nil {
 				return err
 			}
-			if ft.Anonymous {
+			if d, ok := dynamicValue(fv); ok && stack.p != nil {
+				if err := d.Refresh(stack.p, subParam); err != nil {
+					return err
+				}
+			} else if ok {
+				if err := c.bindDynamic(d, subParam); err != nil {
+					return err
+				}
+			} else if ft.Anonymous {
 				if err := c.wireStruct(fv, subParam, stack); err != nil {
 					return err
 				}
 			} else {
-				if err := conf.BindValue(c.p, fv, subParam); err != nil {
+				if err := conf.BindValue(c.wiringProperties(stack), fv, subParam); err != nil {
 					return err
 				}
 			}
@@ -806,7 +1061,7 @@ func (c *container) wireByTag(v reflect.Value, tag string, stack *wiringStack) e
 
 	// tag 预处理，可能通过属性值进行指定。
 	if strings.HasPrefix(tag, "${") {
-		s, err := c.p.Resolve(tag)
+		s, err := c.wiringProperties(stack).Resolve(tag)
 		if err != nil {
 			return err
 		}
@@ -825,6 +1080,15 @@ func (c *container) wireByTag(v reflect.Value, tag string, stack *wiringStack) e
 }
 
 func (c *container) autowire(v reflect.Value, tags []wireTag, stack *wiringStack) error {
+	if v.Type() == providerType {
+		c.hasProvider = true
+		p := Provider{c: c}
+		for _, tag := range tags {
+			p.selectors = append(p.selectors, tag.String())
+		}
+		v.Set(reflect.ValueOf(p))
+		return nil
+	}
 	switch v.Kind() {
 	case reflect.Map, reflect.Slice, reflect.Array:
 		return c.collectBeans(v, tags, stack)
@@ -849,6 +1113,37 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 		return fmt.Errorf("%s is not valid receiver type", t.String())
 	}
 
+	// 没有注册该类型的 bean 时 provider 类型的字段注入一个按需获取 bean 的函数。
+	if isProvider(t) && len(c.beansByType[t]) == 0 {
+		c.hasProvider = true
+		v.Set(c.newProvider(t, tag))
+		return nil
+	}
+
+	result, err := c.findWiredBean(t, tag, stack)
+	if err != nil || result == nil {
+		return err
+	}
+
+	if result.isScoped() {
+		return fmt.Errorf("%s 的作用域为 %s ，只能通过 func(context.Context) (%s, error) 注入", result, result.scope, t)
+	}
+
+	// 如果是单例模式直接赋值
+	if result.scope == SingletonBeanScope {
+		v.Set(assignableValue(result.Value(), t))
+		return nil
+	}
+
+	// 如果是原型模式则重新拷贝一个对象
+	c.copyValue(v, result)
+	return nil
+}
+
+// findWiredBean 查找 tag 对应的唯一的 bean 并确保其已经完成依赖注入，tag 允许为
+// 空并且没有找到时返回 nil 。
+func (c *container) findWiredBean(t reflect.Type, tag wireTag, stack *wiringStack) (*BeanDefinition, error) {
+
 	var foundBeans []*BeanDefinition
 
 	for _, b := range c.beansByType[t] {
@@ -890,9 +1185,15 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 
 	if len(foundBeans) == 0 {
 		if tag.nullable {
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
+		// 说明 bean 是被条件删除的，便于排查问题
+		for _, b := range c.beansByType[t] {
+			if b.status == Deleted && b.Match(tag.typeName, tag.beanName) {
+				return nil, &BeanNotFoundError{Selector: tag.String(), Type: t, Deleted: b}
+			}
+		}
+		return nil, &BeanNotFoundError{Selector: tag.String(), Type: t}
 	}
 
 	// 优先使用设置成主版本的 bean
@@ -905,21 +1206,11 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 	}
 
 	if len(primaryBeans) > 1 {
-		msg := fmt.Sprintf("found %d primary beans, bean:%q type:%q [", len(primaryBeans), tag, t)
-		for _, b := range primaryBeans {
-			msg += "( " + b.String() + " ), "
-		}
-		msg = msg[:len(msg)-2] + "]"
-		return errors.New(msg)
+		return nil, &AmbiguousBeanError{Selector: tag.String(), Type: t, Candidates: primaryBeans, Primary: true}
 	}
 
 	if len(primaryBeans) == 0 && len(foundBeans) > 1 {
-		msg := fmt.Sprintf("found %d beans, bean:%q type:%q [", len(foundBeans), tag, t)
-		for _, b := range foundBeans {
-			msg += "( " + b.String() + " ), "
-		}
-		msg = msg[:len(msg)-2] + "]"
-		return errors.New(msg)
+		return nil, &AmbiguousBeanError{Selector: tag.String(), Type: t, Candidates: foundBeans}
 	}
 
 	var result *BeanDefinition
@@ -930,28 +1221,24 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 	}
 
 	// 确保找到的 bean 已经完成依赖注入。
-	err := c.wireBean(result, stack)
-	if err != nil {
-		return err
+	if err := c.wireBean(result, stack); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// 如果是单例模式直接赋值
-	if result.scope == SingletonBeanScope {
-		v.Set(result.Value())
-		return nil
+// assignableValue 返回可以赋值给 t 的值，接口类型的 bean 导出其他接口时需要取出
+// 接口中保存的真实值。
+func assignableValue(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Kind() == reflect.Interface && !v.Type().AssignableTo(t) {
+		return v.Elem()
 	}
-
-	// 如果是原型模式则重新拷贝一个对象
-	c.copyValue(v, result)
-	return nil
+	return v
 }
 
 // 浅拷贝bean对象
 func (c *container) copyValue(v reflect.Value, bean *BeanDefinition) {
-	srcType := bean.Type().Elem()
-	dstValue := reflect.New(srcType)
-	dstValue.Elem().Set(bean.Value().Elem())
-	v.Set(dstValue)
+	v.Set(cloneBean(bean))
 }
 
 // filterBean 返回 tag 对应的 bean 在数组中的索引，找不到返回 -1。
@@ -969,12 +1256,11 @@ func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, erro
 	}
 
 	if len(found) > 1 {
-		msg := fmt.Sprintf("found %d beans, bean:%q type:%q [", len(found), tag, t)
+		var candidates []*BeanDefinition
 		for _, i := range found {
-			msg += "( " + beans[i].String() + " ), "
+			candidates = append(candidates, beans[i])
 		}
-		msg = msg[:len(msg)-2] + "]"
-		return -1, errors.New(msg)
+		return -1, &AmbiguousBeanError{Selector: tag.String(), Type: t, Candidates: candidates}
 	}
 
 	if len(found) > 0 {
@@ -986,7 +1272,7 @@ func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, erro
 		return -1, nil
 	}
 
-	return -1, fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
+	return -1, &BeanNotFoundError{Selector: tag.String(), Type: t}
 }
 
 type byOrder []*BeanDefinition
@@ -1007,7 +1293,14 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 		return fmt.Errorf("%s is not valid receiver type", t.String())
 	}
 
-	beans := c.beansByType[et]
+	// 排除已经被删除的 bean
+	var beans []*BeanDefinition
+	for _, b := range c.beansByType[et] {
+		if b.status != Deleted {
+			beans = append(beans, b)
+		}
+	}
+
 	if len(tags) > 0 {
 
 		var (
@@ -1071,6 +1364,9 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 	}
 
 	for _, b := range beans {
+		if b.isScoped() {
+			return fmt.Errorf("%s 的作用域为 %s ，不能收集到集合中", b, b.scope)
+		}
 		if err := c.wireBean(b, stack); err != nil {
 			return err
 		}
@@ -1082,12 +1378,12 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 		sort.Sort(byOrder(beans))
 		ret = reflect.MakeSlice(t, 0, 0)
 		for _, b := range beans {
-			ret = reflect.Append(ret, b.Value())
+			ret = reflect.Append(ret, assignableValue(b.Value(), et))
 		}
 	case reflect.Map:
 		ret = reflect.MakeMap(t)
 		for _, b := range beans {
-			ret.SetMapIndex(reflect.ValueOf(b.name), b.Value())
+			ret.SetMapIndex(reflect.ValueOf(b.name), assignableValue(b.Value(), et))
 		}
 	}
 	v.Set(ret)
@@ -1098,11 +1394,23 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, stack *wiringS
 // 号，然后等待所有 goroutine 结束，最后按照被依赖先销毁的原则执行所有的销毁函数。
 func (c *container) Close() {
 
+	// 等待正在进行的属性更新结束，之后的更新会被拒绝
+	c.updateMutex.Lock()
+	c.setState(Closed)
+	c.updateMutex.Unlock()
+
+	addScopedBeans(c.scopedBeans, -1)
+	c.scopedBeans = nil
+
 	c.cancel()
 	c.wg.Wait()
 
 	log.Info(c.ctx, "goroutines exited")
 
+	c.refreshMutex.Lock()
+	c.refreshStore.Close()
+	c.refreshMutex.Unlock()
+
 	for _, f := range c.destroyers {
 		f()
 	}
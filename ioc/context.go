@@ -28,18 +28,26 @@ import (
 )
 
 func (c *container) Keys() []string {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
 	return c.p.Keys()
 }
 
 func (c *container) Has(key string) bool {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
 	return c.p.Has(key)
 }
 
 func (c *container) Prop(key string, opts ...conf.GetOption) string {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
 	return c.p.Get(key, opts...)
 }
 
 func (c *container) Bind(i interface{}, opts ...conf.BindOption) error {
+	c.pMutex.RLock()
+	defer c.pMutex.RUnlock()
 	return c.p.Bind(i, opts...)
 }
 
@@ -143,3 +151,9 @@ func (c *container) Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, erro
 	}
 	return a, nil
 }
+
+// Beans 返回容器中注册的所有 bean 元数据，包括被条件删除的 bean，该方法只能在
+// 容器刷新完成之后调用。
+func (c *container) Beans() []*BeanDefinition {
+	return c.beanDefs
+}
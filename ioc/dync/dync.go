@@ -0,0 +1,377 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dync 提供可以动态刷新的属性类型，使用 value 标签绑定到 bean 的字段后，
+// 属性源更新属性时会自动刷新字段的值，读取和刷新是并发安全的。
+package dync
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// Value 可以动态刷新的属性值。Validate 在属性生效之前校验新的属性，返回 error 时
+// 整个更新被拒绝；Refresh 使用新的属性刷新值。
+type Value interface {
+	Refresh(p *conf.Properties, param conf.BindParam) error
+	Validate(p *conf.Properties, param conf.BindParam) error
+}
+
+// bind 将 param 对应的属性绑定到类型为 t 的新值上，并使用 validate 标签进行校验。
+func bind(p *conf.Properties, param conf.BindParam, t reflect.Type) (reflect.Value, error) {
+	param.Type = t
+	if err := conf.CheckRules(t); err != nil {
+		return reflect.Value{}, err
+	}
+	v := reflect.New(t).Elem()
+	if err := conf.BindValue(p, v, param); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := conf.Validate(p, v, param); err != nil {
+		return reflect.Value{}, err
+	}
+	return v, nil
+}
+
+// Bool 动态 bool 属性。
+type Bool struct {
+	v         uint32
+	validator func(v bool) error
+}
+
+func (x *Bool) Value() bool {
+	return atomic.LoadUint32(&x.v) == 1
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *Bool) OnValidate(fn func(v bool) error) {
+	x.validator = fn
+}
+
+func (x *Bool) get(p *conf.Properties, param conf.BindParam) (bool, error) {
+	v, err := bind(p, param, reflect.TypeOf(false))
+	if err != nil {
+		return false, err
+	}
+	b := v.Bool()
+	if x.validator != nil {
+		if err = x.validator(b); err != nil {
+			return false, err
+		}
+	}
+	return b, nil
+}
+
+func (x *Bool) Refresh(p *conf.Properties, param conf.BindParam) error {
+	b, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	var u uint32
+	if b {
+		u = 1
+	}
+	atomic.StoreUint32(&x.v, u)
+	return nil
+}
+
+func (x *Bool) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// Int64 动态 int64 属性。
+type Int64 struct {
+	v         int64
+	validator func(v int64) error
+}
+
+func (x *Int64) Value() int64 {
+	return atomic.LoadInt64(&x.v)
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *Int64) OnValidate(fn func(v int64) error) {
+	x.validator = fn
+}
+
+func (x *Int64) get(p *conf.Properties, param conf.BindParam) (int64, error) {
+	v, err := bind(p, param, reflect.TypeOf(int64(0)))
+	if err != nil {
+		return 0, err
+	}
+	i := v.Int()
+	if x.validator != nil {
+		if err = x.validator(i); err != nil {
+			return 0, err
+		}
+	}
+	return i, nil
+}
+
+func (x *Int64) Refresh(p *conf.Properties, param conf.BindParam) error {
+	i, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&x.v, i)
+	return nil
+}
+
+func (x *Int64) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// Uint64 动态 uint64 属性。
+type Uint64 struct {
+	v         uint64
+	validator func(v uint64) error
+}
+
+func (x *Uint64) Value() uint64 {
+	return atomic.LoadUint64(&x.v)
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *Uint64) OnValidate(fn func(v uint64) error) {
+	x.validator = fn
+}
+
+func (x *Uint64) get(p *conf.Properties, param conf.BindParam) (uint64, error) {
+	v, err := bind(p, param, reflect.TypeOf(uint64(0)))
+	if err != nil {
+		return 0, err
+	}
+	u := v.Uint()
+	if x.validator != nil {
+		if err = x.validator(u); err != nil {
+			return 0, err
+		}
+	}
+	return u, nil
+}
+
+func (x *Uint64) Refresh(p *conf.Properties, param conf.BindParam) error {
+	u, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&x.v, u)
+	return nil
+}
+
+func (x *Uint64) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// Float64 动态 float64 属性。
+type Float64 struct {
+	v         uint64
+	validator func(v float64) error
+}
+
+func (x *Float64) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&x.v))
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *Float64) OnValidate(fn func(v float64) error) {
+	x.validator = fn
+}
+
+func (x *Float64) get(p *conf.Properties, param conf.BindParam) (float64, error) {
+	v, err := bind(p, param, reflect.TypeOf(float64(0)))
+	if err != nil {
+		return 0, err
+	}
+	f := v.Float()
+	if x.validator != nil {
+		if err = x.validator(f); err != nil {
+			return 0, err
+		}
+	}
+	return f, nil
+}
+
+func (x *Float64) Refresh(p *conf.Properties, param conf.BindParam) error {
+	f, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&x.v, math.Float64bits(f))
+	return nil
+}
+
+func (x *Float64) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// String 动态 string 属性。
+type String struct {
+	v         atomic.Value
+	validator func(v string) error
+}
+
+func (x *String) Value() string {
+	s, _ := x.v.Load().(string)
+	return s
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *String) OnValidate(fn func(v string) error) {
+	x.validator = fn
+}
+
+func (x *String) get(p *conf.Properties, param conf.BindParam) (string, error) {
+	v, err := bind(p, param, reflect.TypeOf(""))
+	if err != nil {
+		return "", err
+	}
+	s := v.String()
+	if x.validator != nil {
+		if err = x.validator(s); err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+func (x *String) Refresh(p *conf.Properties, param conf.BindParam) error {
+	s, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	x.v.Store(s)
+	return nil
+}
+
+func (x *String) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// Duration 动态 time.Duration 属性。
+type Duration struct {
+	v         int64
+	validator func(v time.Duration) error
+}
+
+func (x *Duration) Value() time.Duration {
+	return time.Duration(atomic.LoadInt64(&x.v))
+}
+
+// OnValidate 设置校验函数，需要在容器刷新之前设置。
+func (x *Duration) OnValidate(fn func(v time.Duration) error) {
+	x.validator = fn
+}
+
+func (x *Duration) get(p *conf.Properties, param conf.BindParam) (time.Duration, error) {
+	v, err := bind(p, param, reflect.TypeOf(time.Duration(0)))
+	if err != nil {
+		return 0, err
+	}
+	d := v.Interface().(time.Duration)
+	if x.validator != nil {
+		if err = x.validator(d); err != nil {
+			return 0, err
+		}
+	}
+	return d, nil
+}
+
+func (x *Duration) Refresh(p *conf.Properties, param conf.BindParam) error {
+	d, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&x.v, int64(d))
+	return nil
+}
+
+func (x *Duration) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
+
+// Ref 动态的复合类型属性，例如结构体、切片和 map 。使用前需要调用 Init 传入值的
+// 类型，例如 Init(new(Config)) ，Value 返回的是每次刷新时创建的新值，不要修改。
+type Ref struct {
+	mutex     sync.RWMutex
+	t         reflect.Type
+	v         interface{}
+	validator func(v interface{}) error
+}
+
+// Init 设置值的类型，i 必须是指针，需要在容器刷新之前调用。
+func (x *Ref) Init(i interface{}) {
+	x.t = reflect.TypeOf(i).Elem()
+}
+
+// Value 返回当前值的指针，类型与 Init 传入的类型相同。
+func (x *Ref) Value() interface{} {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return x.v
+}
+
+// OnValidate 设置校验函数，函数的参数与 Value 的返回值相同，需要在容器刷新之前设置。
+func (x *Ref) OnValidate(fn func(v interface{}) error) {
+	x.validator = fn
+}
+
+func (x *Ref) get(p *conf.Properties, param conf.BindParam) (interface{}, error) {
+	if x.t == nil {
+		return nil, fmt.Errorf("%s dync.Ref 未调用 Init 设置类型", param.Path)
+	}
+	v, err := bind(p, param, x.t)
+	if err != nil {
+		return nil, err
+	}
+	ptr := reflect.New(x.t)
+	ptr.Elem().Set(v)
+	i := ptr.Interface()
+	if x.validator != nil {
+		if err = x.validator(i); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+func (x *Ref) Refresh(p *conf.Properties, param conf.BindParam) error {
+	i, err := x.get(p, param)
+	if err != nil {
+		return err
+	}
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.v = i
+	return nil
+}
+
+func (x *Ref) Validate(p *conf.Properties, param conf.BindParam) error {
+	_, err := x.get(p, param)
+	return err
+}
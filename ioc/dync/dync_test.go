@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dync_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc/conf"
+	"github.com/huazai2008101/stark/ioc/dync"
+)
+
+func newParam(t *testing.T, tag string) conf.BindParam {
+	var param conf.BindParam
+	assert.Nil(t, param.BindTag(tag))
+	param.Path = "Test"
+	return param
+}
+
+func newProperties(m map[string]interface{}) *conf.Properties {
+	p := conf.New()
+	for k, v := range m {
+		p.Set(k, v)
+	}
+	return p
+}
+
+func TestBool(t *testing.T) {
+	var x dync.Bool
+	param := newParam(t, "${enabled:=false}")
+	assert.Nil(t, x.Refresh(conf.New(), param))
+	assert.False(t, x.Value())
+	assert.Nil(t, x.Refresh(newProperties(map[string]interface{}{"enabled": true}), param))
+	assert.True(t, x.Value())
+	err := x.Validate(newProperties(map[string]interface{}{"enabled": "maybe"}), param)
+	assert.Error(t, err, "maybe")
+	assert.True(t, x.Value())
+}
+
+func TestInt64(t *testing.T) {
+	var x dync.Int64
+	x.OnValidate(func(v int64) error {
+		if v <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	param := newParam(t, "${size}")
+	p := newProperties(map[string]interface{}{"size": 3})
+	assert.Nil(t, x.Refresh(p, param))
+	assert.Equal(t, x.Value(), int64(3))
+
+	// 校验失败时不修改当前值
+	p = newProperties(map[string]interface{}{"size": -1})
+	assert.Error(t, x.Validate(p, param), "must be positive")
+	assert.Error(t, x.Refresh(p, param), "must be positive")
+	assert.Equal(t, x.Value(), int64(3))
+
+	// 属性不存在并且没有默认值
+	assert.Error(t, x.Validate(conf.New(), param), "size")
+}
+
+func TestUint64(t *testing.T) {
+	var x dync.Uint64
+	param := newParam(t, "${size:=8}")
+	assert.Nil(t, x.Refresh(conf.New(), param))
+	assert.Equal(t, x.Value(), uint64(8))
+	assert.Error(t, x.Validate(newProperties(map[string]interface{}{"size": "-1"}), param), "-1")
+}
+
+func TestFloat64(t *testing.T) {
+	var x dync.Float64
+	param := newParam(t, "${rate}")
+	assert.Nil(t, x.Refresh(newProperties(map[string]interface{}{"rate": 0.25}), param))
+	assert.Equal(t, x.Value(), 0.25)
+}
+
+func TestString(t *testing.T) {
+	var x dync.String
+	x.OnValidate(func(v string) error {
+		if v == "" {
+			return errors.New("empty")
+		}
+		return nil
+	})
+	param := newParam(t, "${mode:=}")
+	assert.Error(t, x.Refresh(conf.New(), param), "empty")
+	assert.Nil(t, x.Refresh(newProperties(map[string]interface{}{"mode": "fast"}), param))
+	assert.Equal(t, x.Value(), "fast")
+}
+
+func TestDuration(t *testing.T) {
+	var x dync.Duration
+	param := newParam(t, "${timeout:=1s}")
+	assert.Nil(t, x.Refresh(conf.New(), param))
+	assert.Equal(t, x.Value(), time.Second)
+	assert.Nil(t, x.Refresh(newProperties(map[string]interface{}{"timeout": "150ms"}), param))
+	assert.Equal(t, x.Value(), 150*time.Millisecond)
+}
+
+type refConfig struct {
+	Hosts []string `value:"${hosts}"`
+	Port  int      `value:"${port:=80}" validate:"min=1,max=65535"`
+}
+
+func TestRef(t *testing.T) {
+	var x dync.Ref
+	param := newParam(t, "${server}")
+	assert.Error(t, x.Refresh(conf.New(), param), "未调用 Init 设置类型")
+
+	x.Init(new(refConfig))
+	p := newProperties(map[string]interface{}{"server.hosts": []string{"a", "b"}})
+	assert.Nil(t, x.Refresh(p, param))
+	c := x.Value().(*refConfig)
+	assert.Equal(t, c, &refConfig{Hosts: []string{"a", "b"}, Port: 80})
+
+	// 每次刷新创建新的值，之前获取的值不受影响
+	p = newProperties(map[string]interface{}{"server.hosts": []string{"c"}, "server.port": 8080})
+	assert.Nil(t, x.Refresh(p, param))
+	assert.Equal(t, x.Value(), &refConfig{Hosts: []string{"c"}, Port: 8080})
+	assert.Equal(t, c, &refConfig{Hosts: []string{"a", "b"}, Port: 80})
+
+	// validate 标签校验失败时不修改当前值
+	p = newProperties(map[string]interface{}{"server.hosts": []string{"d"}, "server.port": 0})
+	assert.Error(t, x.Validate(p, param), "server.port")
+	assert.Equal(t, x.Value(), &refConfig{Hosts: []string{"c"}, Port: 8080})
+}
+
+func TestConcurrent(t *testing.T) {
+	var x dync.Int64
+	param := newParam(t, "${n}")
+	assert.Nil(t, x.Refresh(newProperties(map[string]interface{}{"n": 0}), param))
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = x.Refresh(newProperties(map[string]interface{}{"n": i}), param)
+		}(i)
+		go func() {
+			defer wg.Done()
+			v := x.Value()
+			assert.True(t, v >= 0 && v <= 4)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/huazai2008101/stark/base/log"
+)
+
+// EventBus 应用内的事件总线，用于在 bean 之间发布领域事件和框架事件。
+//
+// 实现了 EventListener 接口的单例 bean 中名称以 On 开头并且签名为
+// func(context.Context, E) 或者 func(context.Context, E) error 的方法在容器刷新
+// 时自动注册为监听器，其中 E 为结构体或者结构体指针，监听 E 以及可以赋值给 E 的事
+// 件。事件与 E 互为指针和值时自动转换，因此 func(context.Context, AppStartedEvent)
+// 也能收到以指针形式发布的 *AppStartedEvent ，值形式的监听器收到的是事件的拷贝。
+// 监听器按照所属 bean 的 Order 顺序调用。
+type EventBus interface {
+
+	// Publish 同步发布事件，监听器返回 error 时停止调用后续监听器并返回该 error 。
+	Publish(ctx context.Context, event interface{}) error
+
+	// PublishAsync 通过 Go 在新的 goroutine 中发布事件，监听器返回的 error 只记
+	// 录日志。监听器收到的 ctx 保留了 ctx 中的值，但是生命周期跟随容器。
+	PublishAsync(ctx context.Context, event interface{})
+
+	// Subscribe 注册 func(context.Context, E) 或者 func(context.Context, E) error
+	// 形式的监听函数，order 为调用顺序。
+	Subscribe(fn interface{}, order float32) error
+}
+
+// EventListener 事件监听器的标记接口，只有实现该接口的 bean 才会自动注册监听方法，
+// 避免业务对象上恰好符合签名的 On 方法被误注册为监听器。
+type EventListener interface {
+	EventListener()
+}
+
+// ContainerRefreshedEvent 容器刷新完成的事件。
+type ContainerRefreshedEvent struct{}
+
+// AppStartedEvent 应用启动完成的事件，在所有 AppEvent.OnAppStart 执行之后发布。
+type AppStartedEvent struct{}
+
+// ShutdownRequestedEvent 应用收到停止请求的事件，在容器关闭之前发布。
+type ShutdownRequestedEvent struct {
+	Reason string
+}
+
+// PropertiesChangedEvent 运行时属性被更新的事件，在动态属性刷新之后发布。实现了
+// PropertyChangeListener 接口的 bean 也是通过该事件收到通知的，两者与其他监听器一
+// 起按照 Order 顺序调用，只关心属性变化的 bean 实现 PropertyChangeListener 即可。
+type PropertiesChangedEvent struct {
+	*PropertyChangeEvent
+}
+
+type eventListener struct {
+	name  string
+	order float32
+	t     reflect.Type  // 监听的事件类型
+	fn    reflect.Value // 监听函数
+}
+
+// eventListenerType 返回 fn 监听的事件类型，fn 不是监听函数时返回 false 。
+func eventListenerType(fn reflect.Type) (reflect.Type, bool) {
+	if fn.NumIn() != 2 || fn.In(0) != contextType {
+		return nil, false
+	}
+	if fn.NumOut() > 1 || (fn.NumOut() == 1 && fn.Out(0) != errorType) {
+		return nil, false
+	}
+	t := fn.In(1)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	return fn.In(1), true
+}
+
+// accept 返回监听器是否接收 t 类型的事件，t 与监听的类型互为指针和值时也接收。
+func (l *eventListener) accept(t reflect.Type) bool {
+	switch {
+	case t.AssignableTo(l.t):
+		return true
+	case t.Kind() == reflect.Ptr && t.Elem().AssignableTo(l.t):
+		return true
+	case l.t.Kind() == reflect.Ptr && t.AssignableTo(l.t.Elem()):
+		return true
+	}
+	return false
+}
+
+// convert 将事件转换为监听的类型。
+func (l *eventListener) convert(event interface{}) reflect.Value {
+	v := reflect.ValueOf(event)
+	switch {
+	case v.Type().AssignableTo(l.t):
+		return v
+	case v.Kind() == reflect.Ptr:
+		return v.Elem()
+	default:
+		p := reflect.New(l.t.Elem())
+		p.Elem().Set(v)
+		return p
+	}
+}
+
+func (l *eventListener) call(ctx context.Context, event interface{}) error {
+	out := l.fn.Call([]reflect.Value{reflect.ValueOf(ctx), l.convert(event)})
+	if len(out) > 0 && !out[0].IsNil() {
+		return out[0].Interface().(error)
+	}
+	return nil
+}
+
+type eventBus struct {
+	c         *container
+	mutex     sync.RWMutex
+	listeners []*eventListener
+}
+
+func newEventBus(c *container) *eventBus {
+	return &eventBus{c: c}
+}
+
+func (b *eventBus) add(l *eventListener) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.listeners = append(b.listeners, l)
+	sort.SliceStable(b.listeners, func(i, j int) bool {
+		return b.listeners[i].order < b.listeners[j].order
+	})
+}
+
+func (b *eventBus) Subscribe(fn interface{}, order float32) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return errors.New("fn should be a func(context.Context, E) error")
+	}
+	t, ok := eventListenerType(v.Type())
+	if !ok {
+		return errors.New("fn should be a func(context.Context, E) error")
+	}
+	b.add(&eventListener{name: v.Type().String(), order: order, t: t, fn: v})
+	return nil
+}
+
+// subscribeBean 注册 bean 中的监听方法，没有实现 EventListener 接口的 bean 直接忽略。
+func (b *eventBus) subscribeBean(bd *BeanDefinition) {
+	if _, ok := bd.Interface().(EventListener); !ok {
+		return
+	}
+	v := bd.Value()
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	for i := 0; i < v.NumMethod(); i++ {
+		name := v.Type().Method(i).Name
+		if !strings.HasPrefix(name, "On") {
+			continue
+		}
+		m := v.Method(i)
+		t, ok := eventListenerType(m.Type())
+		if !ok {
+			continue
+		}
+		log.Debugf(b.c.ctx, "subscribe %s.%s for %s", bd.ID(), name, t)
+		b.add(&eventListener{name: bd.ID() + "." + name, order: bd.order, t: t, fn: m})
+	}
+}
+
+// match 返回监听 t 类型事件的监听器。
+func (b *eventBus) match(t reflect.Type) []*eventListener {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	var ret []*eventListener
+	for _, l := range b.listeners {
+		if l.accept(t) {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func (b *eventBus) Publish(ctx context.Context, event interface{}) error {
+	if event == nil {
+		return errors.New("event can't be nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := reflect.TypeOf(event)
+	if t.Kind() == reflect.Ptr && reflect.ValueOf(event).IsNil() {
+		return errors.New("event can't be nil")
+	}
+	for _, l := range b.match(t) {
+		if err := l.call(ctx, event); err != nil {
+			return fmt.Errorf("%s 处理事件 %T 异常: %w", l.name, event, err)
+		}
+	}
+	return nil
+}
+
+// asyncContext 保留发布时 ctx 中的值，生命周期跟随容器。
+type asyncContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *asyncContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+func (b *eventBus) PublishAsync(ctx context.Context, event interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b.c.Go(func(c context.Context) {
+		if err := b.Publish(&asyncContext{Context: c, values: ctx}, event); err != nil {
+			log.Error(ctx, err)
+		}
+	})
+}
+
+// subscribePropertyListener 将 PropertyChangeListener 注册为 PropertiesChangedEvent
+// 的监听器。
+func (b *eventBus) subscribePropertyListener(bd *BeanDefinition, l PropertyChangeListener) {
+	fn := func(ctx context.Context, e *PropertiesChangedEvent) {
+		l.OnPropertyChange(e.PropertyChangeEvent)
+	}
+	b.add(&eventListener{
+		name:  bd.ID() + ".OnPropertyChange",
+		order: bd.order,
+		t:     reflect.TypeOf((*PropertiesChangedEvent)(nil)),
+		fn:    reflect.ValueOf(fn),
+	})
+}
+
+// collectEventListeners 注册单例 bean 中的监听方法以及属性变化的监听器。
+func (c *container) collectEventListeners() {
+	for _, b := range c.beans {
+		if b.status == Deleted || b.scope != SingletonBeanScope {
+			continue
+		}
+		c.bus.subscribeBean(b)
+		if l, ok := b.Interface().(PropertyChangeListener); ok {
+			c.bus.subscribePropertyListener(b, l)
+		}
+	}
+}
+
+// publishEvent 同步发布框架事件，监听器返回的 error 只记录日志。
+func (c *container) publishEvent(event interface{}) {
+	if err := c.bus.Publish(c.ctx, event); err != nil {
+		log.Error(c.ctx, err)
+	}
+}
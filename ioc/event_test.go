@@ -0,0 +1,209 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+type orderCreated struct {
+	OrderID int64
+}
+
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) add(s string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, s)
+}
+
+func (r *eventRecorder) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = nil
+}
+
+func (r *eventRecorder) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.events...)
+}
+
+type stockListener struct {
+	Recorder *eventRecorder `autowire:""`
+	err      error
+}
+
+func (l *stockListener) EventListener() {}
+
+func (l *stockListener) OnOrderCreated(ctx context.Context, e *orderCreated) error {
+	l.Recorder.add("stock")
+	return l.err
+}
+
+// OnContainerRefreshed 值形式的监听器也能收到以指针形式发布的框架事件
+func (l *stockListener) OnContainerRefreshed(ctx context.Context, e ioc.ContainerRefreshedEvent) {
+	l.Recorder.add("refreshed")
+}
+
+type pointListener struct {
+	Recorder *eventRecorder `autowire:""`
+}
+
+func (l *pointListener) EventListener() {}
+
+func (l *pointListener) OnOrderCreated(ctx context.Context, e *orderCreated) {
+	l.Recorder.add("point")
+}
+
+// orderPublisher 没有实现 EventListener 接口，同样签名的方法不会注册为监听器
+type orderPublisher struct {
+	Recorder *eventRecorder `autowire:""`
+	Bus      ioc.EventBus   `autowire:""`
+}
+
+func (s *orderPublisher) OnOrderCreated(ctx context.Context, e *orderCreated) error {
+	s.Recorder.add("service")
+	return nil
+}
+
+func newEventContainer(t *testing.T) (*orderPublisher, *stockListener, *eventRecorder) {
+	c := ioc.New()
+	r := new(eventRecorder)
+	c.Object(r)
+	stock := new(stockListener)
+	c.Object(stock).Order(2)
+	c.Object(new(pointListener)).Order(1)
+	s := new(orderPublisher)
+	c.Object(s)
+	assert.Nil(t, c.Refresh())
+	t.Cleanup(c.Close)
+	return s, stock, r
+}
+
+func TestEventBus_Publish(t *testing.T) {
+	s, stock, r := newEventContainer(t)
+	assert.Equal(t, r.get(), []string{"refreshed"})
+
+	err := s.Bus.Subscribe(func(ctx context.Context, e *orderCreated) { r.add("func") }, 1.5)
+	assert.Nil(t, err)
+	err = s.Bus.Subscribe(func(e *orderCreated) {}, 0)
+	assert.Error(t, err, "fn should be a func\\(context.Context, E\\) error")
+
+	// 按照 order 顺序同步调用监听器
+	r.reset()
+	assert.Nil(t, s.Bus.Publish(context.Background(), &orderCreated{OrderID: 1}))
+	assert.Equal(t, r.get(), []string{"point", "func", "stock"})
+
+	// 事件与监听的类型互为指针和值时自动转换
+	r.reset()
+	assert.Nil(t, s.Bus.Publish(context.Background(), orderCreated{OrderID: 1}))
+	assert.Equal(t, r.get(), []string{"point", "func", "stock"})
+
+	// 只有类型匹配的监听器才会被调用
+	r.reset()
+	assert.Nil(t, s.Bus.Publish(context.Background(), &ioc.AppStartedEvent{}))
+	assert.Equal(t, r.get(), []string{})
+
+	// 监听器返回 error 时停止调用后续监听器
+	r.reset()
+	stock.err = errors.New("out of stock")
+	assert.Nil(t, s.Bus.Subscribe(func(ctx context.Context, e *orderCreated) { r.add("last") }, 3))
+	err = s.Bus.Publish(context.Background(), &orderCreated{OrderID: 2})
+	assert.Error(t, err, "stockListener:.*处理事件 \\*ioc_test.orderCreated 异常: out of stock")
+	assert.True(t, errors.Is(err, stock.err))
+	assert.Equal(t, r.get(), []string{"point", "func", "stock"})
+
+	assert.Error(t, s.Bus.Publish(context.Background(), nil), "event can't be nil")
+	assert.Error(t, s.Bus.Publish(context.Background(), (*orderCreated)(nil)), "event can't be nil")
+}
+
+type traceKey struct{}
+
+func TestEventBus_PublishAsync(t *testing.T) {
+	s, _, r := newEventContainer(t)
+
+	ch := make(chan interface{}, 1)
+	err := s.Bus.Subscribe(func(ctx context.Context, e *orderCreated) {
+		ch <- ctx.Value(traceKey{})
+	}, 0)
+	assert.Nil(t, err)
+
+	// 异步发布时保留 ctx 中的值，发布的 ctx 取消后依然可以处理事件
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "trace-1"))
+	s.Bus.PublishAsync(ctx, &orderCreated{OrderID: 1})
+	cancel()
+	select {
+	case v := <-ch:
+		assert.Equal(t, v, "trace-1")
+	case <-time.After(3 * time.Second):
+		t.Fatal("async event timeout")
+	}
+	for i := 0; i < 100 && len(r.get()) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, r.get(), []string{"refreshed", "point", "stock"})
+}
+
+// configListener 通过 PropertyChangeListener 接口收到属性变化
+type configListener struct {
+	Recorder *eventRecorder `autowire:""`
+}
+
+func (l *configListener) OnPropertyChange(e *ioc.PropertyChangeEvent) {
+	l.Recorder.add("listener " + e.Keys[0])
+}
+
+// configEventListener 通过事件总线收到属性变化
+type configEventListener struct {
+	Recorder *eventRecorder `autowire:""`
+}
+
+func (l *configEventListener) EventListener() {}
+
+func (l *configEventListener) OnPropertiesChanged(ctx context.Context, e ioc.PropertiesChangedEvent) {
+	l.Recorder.add("event " + e.Keys[0])
+}
+
+func TestEventBus_PropertiesChanged(t *testing.T) {
+	c := ioc.New()
+	c.Property("a", "1")
+	r := new(eventRecorder)
+	c.Object(r)
+	c.Object(new(configEventListener)).Order(1)
+	c.Object(new(configListener)).Order(2)
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+
+	// 两种监听方式都通过事件总线按照 order 顺序调用
+	p := conf.New()
+	_ = p.Set("a", "2")
+	assert.Nil(t, c.RefreshProperties(p))
+	assert.Equal(t, r.get(), []string{"event a", "listener a"})
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import "time"
+
+// DebounceTimer 手动触发的配置文件监听定时器，Resets 在每次 Reset 时接收延迟时间，
+// 向 C 发送值触发配置文件的重新加载。
+type DebounceTimer struct {
+	C      chan time.Time
+	Resets chan time.Duration
+}
+
+func (t *DebounceTimer) Chan() <-chan time.Time {
+	return t.C
+}
+
+func (t *DebounceTimer) Reset(d time.Duration) bool {
+	select {
+	case t.Resets <- d:
+	default:
+	}
+	return true
+}
+
+func (t *DebounceTimer) Stop() bool {
+	return true
+}
+
+// SetDebounceTimer 使用 t 作为配置文件监听的定时器，返回恢复原定时器的函数。
+func SetDebounceTimer(t *DebounceTimer) (reset func()) {
+	old := newDebounceTimer
+	newDebounceTimer = func() debounceTimer { return t }
+	return func() { newDebounceTimer = old }
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+// BeanNotFoundError 找不到匹配的 bean 。
+type BeanNotFoundError struct {
+	Selector string
+	Type     reflect.Type
+	// 匹配但是被条件删除的 bean ，可能为 nil
+	Deleted *BeanDefinition
+}
+
+func (e *BeanNotFoundError) Error() string {
+	if e.Deleted != nil {
+		return fmt.Sprintf("can't find bean, bean:%q type:%q, %s 已被删除，条件判断结果: %s", e.Selector, e.Type, e.Deleted, e.Deleted.condition)
+	}
+	return fmt.Sprintf("can't find bean, bean:%q type:%q", e.Selector, e.Type)
+}
+
+// AmbiguousBeanError 找到多个匹配的 bean 。
+type AmbiguousBeanError struct {
+	Selector   string
+	Type       reflect.Type
+	Candidates []*BeanDefinition
+	// 是否是多个 bean 都设置成了主版本
+	Primary bool
+}
+
+func (e *AmbiguousBeanError) Error() string {
+	kind := "beans"
+	if e.Primary {
+		kind = "primary beans"
+	}
+	msg := fmt.Sprintf("found %d %s, bean:%q type:%q [", len(e.Candidates), kind, e.Selector, e.Type)
+	for _, b := range e.Candidates {
+		msg += "( " + b.String() + " ), "
+	}
+	return msg[:len(msg)-2] + "]"
+}
+
+// CircularDependencyError 构造函数之间存在循环依赖。
+type CircularDependencyError struct {
+	// 依赖环上的 bean ，首尾是同一个 bean
+	Beans []*BeanDefinition
+}
+
+func (e *CircularDependencyError) Error() string {
+	return "found circle autowire"
+}
+
+// WiringError 注入失败时携带注入路径的错误，最后一个 bean 是出错的 bean 。
+type WiringError struct {
+	Err   error
+	Beans []*BeanDefinition
+}
+
+func (e *WiringError) Error() string {
+	var path string
+	for _, b := range e.Beans {
+		path += fmt.Sprintf("=> %s ↩\n", b)
+	}
+	return fmt.Sprintf("%v ↩\n%s", e.Err, strings.TrimSuffix(path, "\n"))
+}
+
+func (e *WiringError) Unwrap() error {
+	return e.Err
+}
+
+// Bean 返回出错的 bean 。
+func (e *WiringError) Bean() *BeanDefinition {
+	if len(e.Beans) == 0 {
+		return nil
+	}
+	return e.Beans[len(e.Beans)-1]
+}
+
+// FailureAnalysis 启动失败的分析报告。
+type FailureAnalysis struct {
+	Kind        string   // 失败类型
+	Description string   // 失败描述
+	Bean        string   // 出错的 bean 及其注册位置
+	Candidates  []string // 候选 bean
+	Action      string   // 修复建议
+	Cause       error    // 原始错误
+}
+
+func (a *FailureAnalysis) String() string {
+	var b strings.Builder
+	b.WriteString("\n***************************\n")
+	b.WriteString("应用启动失败\n")
+	b.WriteString("***************************\n\n")
+	b.WriteString("失败类型: " + a.Kind + "\n")
+	b.WriteString("描述: " + a.Description + "\n")
+	if a.Bean != "" {
+		b.WriteString("出错的 bean: " + a.Bean + "\n")
+	}
+	if len(a.Candidates) > 0 {
+		b.WriteString("候选 bean:\n")
+		for _, c := range a.Candidates {
+			b.WriteString("\t- " + c + "\n")
+		}
+	}
+	b.WriteString("修复建议: " + a.Action + "\n")
+	return b.String()
+}
+
+// FailureAnalyzer 分析启动错误，无法分析时返回 nil 。
+type FailureAnalyzer interface {
+	Analyze(err error) *FailureAnalysis
+}
+
+// FailureAnalyzerFunc 函数形式的 FailureAnalyzer 。
+type FailureAnalyzerFunc func(err error) *FailureAnalysis
+
+func (f FailureAnalyzerFunc) Analyze(err error) *FailureAnalysis {
+	return f(err)
+}
+
+var (
+	analyzerMutex sync.RWMutex
+	analyzers     = []FailureAnalyzer{
+		FailureAnalyzerFunc(analyzeCircularDependency),
+		FailureAnalyzerFunc(analyzeAmbiguousBean),
+		FailureAnalyzerFunc(analyzeBeanNotFound),
+		FailureAnalyzerFunc(analyzePropertyNotFound),
+		FailureAnalyzerFunc(analyzeTypeConversion),
+		FailureAnalyzerFunc(analyzePortInUse),
+	}
+)
+
+// RegisterFailureAnalyzer 注册启动错误分析器，后注册的分析器优先使用。
+func RegisterFailureAnalyzer(a FailureAnalyzer) {
+	analyzerMutex.Lock()
+	defer analyzerMutex.Unlock()
+	analyzers = append([]FailureAnalyzer{a}, analyzers...)
+}
+
+// AnalyzeFailure 使用注册的分析器分析启动错误，返回第一个分析结果，无法分析时返回 nil 。
+func AnalyzeFailure(err error) *FailureAnalysis {
+	if err == nil {
+		return nil
+	}
+	analyzerMutex.RLock()
+	defer analyzerMutex.RUnlock()
+	for _, a := range analyzers {
+		if r := a.Analyze(err); r != nil {
+			if r.Cause == nil {
+				r.Cause = err
+			}
+			return r
+		}
+	}
+	return nil
+}
+
+// ReportFailure 输出启动错误的分析报告，无法分析时输出原始错误。启动错误应该只在最
+// 外层报告一次，App 的 Run 和 Start 已经报告了自身的启动错误。
+func ReportFailure(ctx context.Context, err error) {
+	if r := AnalyzeFailure(err); r != nil {
+		log.Error(ctx, r)
+		return
+	}
+	log.Errorf(ctx, "应用启动失败:%+v", err)
+}
+
+// failedBean 返回注入路径上出错的 bean 的描述。
+func failedBean(err error) string {
+	var e *WiringError
+	if errors.As(err, &e) {
+		if b := e.Bean(); b != nil {
+			return b.String()
+		}
+	}
+	return ""
+}
+
+func beanStrings(beans []*BeanDefinition) []string {
+	var r []string
+	for _, b := range beans {
+		r = append(r, b.String())
+	}
+	return r
+}
+
+func analyzeCircularDependency(err error) *FailureAnalysis {
+	var e *CircularDependencyError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	var names []string
+	for _, b := range e.Beans {
+		names = append(names, fmt.Sprintf("%q", b.BeanName()))
+	}
+	return &FailureAnalysis{
+		Kind:        "循环依赖",
+		Description: "构造函数之间存在循环依赖: " + strings.Join(names, " -> "),
+		Bean:        failedBean(err),
+		Candidates:  beanStrings(e.Beans[:len(e.Beans)-1]),
+		Action:      `将依赖环上某个 bean 的构造函数参数改为字段注入，并使用 autowire:",lazy" 延迟注入`,
+	}
+}
+
+func analyzeAmbiguousBean(err error) *FailureAnalysis {
+	var e *AmbiguousBeanError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	action := "使用 Name(...) 为 bean 命名并在注入处通过 autowire:\"name\" 指定，或者对其中一个 bean 调用 Primary()"
+	if e.Primary {
+		action = "只能对其中一个 bean 调用 Primary()，或者在注入处通过 autowire:\"name\" 指定 bean 的名称"
+	}
+	return &FailureAnalysis{
+		Kind:        "存在多个候选 bean",
+		Description: fmt.Sprintf("注入 %q 类型 %s 时找到 %d 个候选 bean", e.Selector, e.Type, len(e.Candidates)),
+		Bean:        failedBean(err),
+		Candidates:  beanStrings(e.Candidates),
+		Action:      action,
+	}
+}
+
+func analyzeBeanNotFound(err error) *FailureAnalysis {
+	var e *BeanNotFoundError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	r := &FailureAnalysis{
+		Kind:        "找不到 bean",
+		Description: fmt.Sprintf("注入 %q 类型 %s 时找不到匹配的 bean", e.Selector, e.Type),
+		Bean:        failedBean(err),
+		Action:      fmt.Sprintf("注册类型为 %s 的 bean (按接口注入时调用 Export 导出接口)，或者使用 autowire:\"?\" 声明为可选依赖", e.Type),
+	}
+	if e.Deleted != nil {
+		r.Candidates = []string{e.Deleted.String()}
+		r.Action = fmt.Sprintf("候选 bean 因为条件不满足已被删除(%s)，检查该 bean 的 On(...) 条件及相关属性", e.Deleted.condition)
+	}
+	return r
+}
+
+var propertyNotExistRegexp = regexp.MustCompile(`property "([^"]+)" ` + conf.ErrNotExist.Error())
+
+func analyzePropertyNotFound(err error) *FailureAnalysis {
+	if !errors.Is(err, conf.ErrNotExist) {
+		return nil
+	}
+	key := "?"
+	if m := propertyNotExistRegexp.FindStringSubmatch(err.Error()); m != nil {
+		key = m[1]
+	}
+	return &FailureAnalysis{
+		Kind:        "属性不存在",
+		Description: fmt.Sprintf("属性 %q 不存在", key),
+		Bean:        failedBean(err),
+		Action:      fmt.Sprintf("在配置文件、环境变量或者命令行中设置属性 %s ，或者在标签中指定默认值，例如 value:\"${%s:=}\"", key, key),
+	}
+}
+
+var bindKeyRegexp = regexp.MustCompile(`Key:(\S+) Path:`)
+
+func analyzeTypeConversion(err error) *FailureAnalysis {
+	var e *strconv.NumError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	key := "?"
+	if m := bindKeyRegexp.FindStringSubmatch(err.Error()); m != nil {
+		key = m[1]
+	}
+	return &FailureAnalysis{
+		Kind:        "类型转换失败",
+		Description: fmt.Sprintf("属性 %q 的值 %q 无法转换为目标类型(%s: %v)", key, e.Num, e.Func, e.Err),
+		Bean:        failedBean(err),
+		Action:      fmt.Sprintf("修改属性 %s 的值，使其符合绑定字段的类型", key),
+	}
+}
+
+func analyzePortInUse(err error) *FailureAnalysis {
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		return nil
+	}
+	desc := "端口已被其他进程占用"
+	var e *net.OpError
+	if errors.As(err, &e) && e.Addr != nil {
+		desc = fmt.Sprintf("监听地址 %s 已被其他进程占用", e.Addr)
+	}
+	return &FailureAnalysis{
+		Kind:        "端口被占用",
+		Description: desc,
+		Bean:        failedBean(err),
+		Action:      "停止占用该端口的进程，或者修改应用的端口配置",
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/cond"
+)
+
+type failureDB struct{}
+
+type failureRepo struct {
+	DB *failureDB `autowire:""`
+}
+
+type failureConfig struct {
+	Port int `value:"${failure.port}"`
+}
+
+type failureServiceA struct{}
+
+type failureServiceB struct{}
+
+func refreshError(t *testing.T, fn func(c ioc.Container)) error {
+	c := ioc.New()
+	fn(c)
+	err := c.Refresh()
+	assert.True(t, err != nil)
+	return err
+}
+
+func TestAnalyzeFailure(t *testing.T) {
+
+	t.Run("ambiguous", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Object(new(failureDB)).Name("order")
+			c.Object(new(failureDB)).Name("stock")
+			c.Object(new(failureRepo))
+		})
+		var e *ioc.AmbiguousBeanError
+		assert.True(t, errors.As(err, &e))
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "存在多个候选 bean")
+		assert.Equal(t, r.Description, `注入 "" 类型 *ioc_test.failureDB 时找到 2 个候选 bean`)
+		assert.True(t, strings.Contains(r.Bean, "failureRepo"))
+		assert.Equal(t, len(r.Candidates), 2)
+		assert.True(t, strings.Contains(r.Action, "Primary()"))
+		assert.Equal(t, r.Cause, err)
+	})
+
+	t.Run("ambiguous primary", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Object(new(failureDB)).Name("order").Primary()
+			c.Object(new(failureDB)).Name("stock").Primary()
+			c.Object(new(failureRepo))
+		})
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "存在多个候选 bean")
+		assert.True(t, strings.HasPrefix(r.Action, "只能对其中一个 bean 调用 Primary()"))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Object(new(failureRepo))
+		})
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "找不到 bean")
+		assert.Equal(t, r.Description, `注入 "" 类型 *ioc_test.failureDB 时找不到匹配的 bean`)
+		assert.True(t, strings.Contains(r.Bean, "failureRepo"))
+		assert.Equal(t, len(r.Candidates), 0)
+	})
+
+	t.Run("missing deleted", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Object(new(failureDB)).On(cond.OnProperty("db.enabled"))
+			c.Object(new(failureRepo))
+		})
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "找不到 bean")
+		assert.Equal(t, len(r.Candidates), 1)
+		assert.True(t, strings.HasPrefix(r.Action, "候选 bean 因为条件不满足已被删除(not matched)"))
+	})
+
+	t.Run("circular", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Provide(func(b *failureServiceB) *failureServiceA { return new(failureServiceA) }).Name("a")
+			c.Provide(func(a *failureServiceA) *failureServiceB { return new(failureServiceB) }).Name("b")
+		})
+		var e *ioc.CircularDependencyError
+		assert.True(t, errors.As(err, &e))
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "循环依赖")
+		assert.True(t, strings.HasPrefix(r.Description, "构造函数之间存在循环依赖: "))
+		assert.True(t, strings.Contains(r.Description, `"a" -> "b" -> "a"`) || strings.Contains(r.Description, `"b" -> "a" -> "b"`))
+		assert.Equal(t, len(r.Candidates), 2)
+	})
+
+	t.Run("property", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Object(new(failureConfig))
+		})
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "属性不存在")
+		assert.Equal(t, r.Description, `属性 "failure.port" 不存在`)
+		assert.True(t, strings.Contains(r.Bean, "failureConfig"))
+		assert.True(t, strings.Contains(r.Action, `value:"${failure.port:=}"`))
+	})
+
+	t.Run("conversion", func(t *testing.T) {
+		err := refreshError(t, func(c ioc.Container) {
+			c.Property("failure.port", "abc")
+			c.Object(new(failureConfig))
+		})
+		r := ioc.AnalyzeFailure(err)
+		assert.Equal(t, r.Kind, "类型转换失败")
+		assert.True(t, strings.Contains(r.Description, `的值 "abc" 无法转换为目标类型`))
+		assert.True(t, strings.Contains(r.Bean, "failureConfig"))
+	})
+
+	t.Run("port", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer l.Close()
+		_, err = net.Listen("tcp", l.Addr().String())
+		assert.True(t, err != nil)
+		r := ioc.AnalyzeFailure(fmt.Errorf("监听服务端口异常:%w", err))
+		assert.Equal(t, r.Kind, "端口被占用")
+		assert.Equal(t, r.Description, fmt.Sprintf("监听地址 %s 已被其他进程占用", l.Addr()))
+		assert.Equal(t, r.Bean, "")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		assert.True(t, ioc.AnalyzeFailure(nil) == nil)
+		assert.True(t, ioc.AnalyzeFailure(errors.New("unknown")) == nil)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		errLicense := errors.New("license expired")
+		ioc.RegisterFailureAnalyzer(ioc.FailureAnalyzerFunc(func(err error) *ioc.FailureAnalysis {
+			if !errors.Is(err, errLicense) {
+				return nil
+			}
+			return &ioc.FailureAnalysis{Kind: "授权失败", Description: err.Error(), Action: "检查 license.key 属性"}
+		}))
+		r := ioc.AnalyzeFailure(fmt.Errorf("start: %w", errLicense))
+		assert.Equal(t, r.Kind, "授权失败")
+		s := r.String()
+		assert.True(t, strings.Contains(s, "应用启动失败"))
+		assert.True(t, strings.Contains(s, "失败类型: 授权失败\n"))
+		assert.True(t, strings.Contains(s, "修复建议: 检查 license.key 属性\n"))
+	})
+}
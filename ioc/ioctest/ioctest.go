@@ -0,0 +1,189 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ioctest 提供基于 IoC 容器的测试工具。每次调用 New 都会创建一个独立的应
+// 用，可以只注册需要测试的部分组件，使用 mock 对象替换 bean ，直接设置属性，并在
+// httptest 或者 bufconn 这样的内存监听器上启动 http 和 grpc 服务，测试结束时自动
+// 停止应用并关闭服务，因此同一个测试程序中可以同时运行多个应用。完整的 stark web
+// 应用可以通过 From 包装 app.Instance ，并把 Listen 返回的内存监听器设置为实例的
+// Listener 。
+//
+//	func TestOrderService(t *testing.T) {
+//		app := ioctest.New(t, order.Register)
+//		app.Property("order.timeout", "1s")
+//		app.Replace((*StockClient)(nil), &mockStockClient{})
+//		app.Start()
+//
+//		var s *order.Service
+//		app.Get(&s)
+//	}
+package ioctest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/arg"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// App 测试应用，方法执行失败时调用 t.Fatal 结束测试。
+type App struct {
+	t       testing.TB
+	app     *ioc.App
+	started bool
+}
+
+// New 创建一个独立的测试应用，registers 为各个模块注册 bean 的函数，测试结束时自
+// 动停止应用。
+func New(t testing.TB, registers ...func(app *ioc.App)) *App {
+	t.Helper()
+	a := From(t, ioc.NewApp())
+	for _, fn := range registers {
+		fn(a.app)
+	}
+	return a
+}
+
+// From 使用已经创建的应用创建测试应用，例如 app.NewInstance 创建的 stark 应用实例，
+// 测试结束时自动停止应用。
+func From(t testing.TB, app *ioc.App) *App {
+	a := &App{t: t, app: app}
+	t.Cleanup(a.stop)
+	return a
+}
+
+// App 返回被测试的应用。
+func (a *App) App() *ioc.App {
+	return a.app
+}
+
+// Property 参考 App.Property 的解释。
+func (a *App) Property(key string, value interface{}) *App {
+	a.app.Property(key, value)
+	return a
+}
+
+// Object 参考 App.Object 的解释。
+func (a *App) Object(i interface{}) *ioc.BeanDefinition {
+	return a.app.Register(ioc.NewBean(reflect.ValueOf(i)))
+}
+
+// Provide 参考 App.Provide 的解释。
+func (a *App) Provide(ctor interface{}, args ...arg.Arg) *ioc.BeanDefinition {
+	return a.app.Register(ioc.NewBean(ctor, args...))
+}
+
+// Replace 使用 obj 替换 selector 选中的 bean ，参考 App.Replace 的解释。
+func (a *App) Replace(selector ioc.BeanSelector, obj interface{}) *ioc.BeanDefinition {
+	return a.app.ReplaceBean(selector, ioc.NewBean(reflect.ValueOf(obj)))
+}
+
+// Start 启动应用，启动失败时结束测试。
+func (a *App) Start() *App {
+	a.t.Helper()
+	if err := a.app.Start(); err != nil {
+		a.t.Fatalf("start app error: %v", err)
+	}
+	a.started = true
+	return a
+}
+
+func (a *App) stop() {
+	if a.started {
+		a.started = false
+		a.app.Stop()
+	}
+}
+
+// Context 返回应用的 Context ，需要在 Start 之后调用。
+func (a *App) Context() ioc.Context {
+	return a.app.Context()
+}
+
+// Get 参考 Context.Get 的解释，获取失败时结束测试。
+func (a *App) Get(i interface{}, selectors ...ioc.BeanSelector) {
+	a.t.Helper()
+	if err := a.app.Context().Get(i, selectors...); err != nil {
+		a.t.Fatalf("get bean error: %v", err)
+	}
+}
+
+// ServeHTTP 在 httptest 服务器上运行 h ，例如 *gin.Engine 或者 *echo.Echo ，测
+// 试结束时关闭服务器。
+func (a *App) ServeHTTP(h http.Handler) *httptest.Server {
+	s := httptest.NewServer(h)
+	a.t.Cleanup(s.Close)
+	return s
+}
+
+// ServeGRPC 在内存监听器上运行 s ，返回连接到 s 的客户端连接，测试结束时关闭连接
+// 并停止服务。
+func (a *App) ServeGRPC(s *grpc.Server) *grpc.ClientConn {
+	a.t.Helper()
+	l := a.Listen()
+	go s.Serve(l)
+	a.t.Cleanup(s.Stop)
+	return l.DialGRPC()
+}
+
+// Listener 内存监听器，可以代替端口监听器启动 http 和 grpc 服务，例如设置为
+// app.Instance 的 Listener ，然后通过 Client 和 DialGRPC 访问服务。
+type Listener struct {
+	*bufconn.Listener
+	t testing.TB
+}
+
+// Listen 创建一个内存监听器，测试结束时关闭。
+func (a *App) Listen() *Listener {
+	l := &Listener{Listener: bufconn.Listen(1024 * 1024), t: a.t}
+	a.t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// Client 返回通过内存监听器访问 http 服务的客户端，请求地址中的 host 可以任意填写。
+func (l *Listener) Client() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return l.DialContext(ctx)
+			},
+		},
+	}
+}
+
+// DialGRPC 返回通过内存监听器连接 grpc 服务的客户端连接，测试结束时关闭连接。
+func (l *Listener) DialGRPC() *grpc.ClientConn {
+	l.t.Helper()
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		l.t.Fatalf("dial grpc server error: %v", err)
+	}
+	l.t.Cleanup(func() { conn.Close() })
+	return conn
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioctest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/ioctest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type Greeter interface {
+	Greet(name string) string
+}
+
+type defaultGreeter struct{}
+
+func (g *defaultGreeter) Greet(name string) string {
+	return "hello " + name
+}
+
+type mockGreeter struct{}
+
+func (g *mockGreeter) Greet(name string) string {
+	return "mock " + name
+}
+
+type GreetService struct {
+	Greeter Greeter `autowire:""`
+	Suffix  string  `value:"${greet.suffix:=!}"`
+	stopped *bool
+}
+
+func (s *GreetService) Greet(name string) string {
+	return s.Greeter.Greet(name) + s.Suffix
+}
+
+func (s *GreetService) OnDestroy() {
+	*s.stopped = true
+}
+
+func register(stopped *bool) func(app *ioc.App) {
+	return func(app *ioc.App) {
+		app.Object(new(defaultGreeter)).Name("greeter").Export((*Greeter)(nil))
+		app.Object(&GreetService{stopped: stopped})
+	}
+}
+
+func TestApp(t *testing.T) {
+	var stopped bool
+	t.Run("replace", func(t *testing.T) {
+		app := ioctest.New(t, register(&stopped))
+		app.Property("greet.suffix", "?")
+		app.Replace("greeter", &mockGreeter{})
+		app.Start()
+
+		var s *GreetService
+		app.Get(&s)
+		assert.Equal(t, s.Greet("stark"), "mock stark?")
+		assert.Equal(t, app.Context().Prop("greet.suffix"), "?")
+		assert.False(t, stopped)
+	})
+	// 测试结束时自动停止应用
+	assert.True(t, stopped)
+
+	// 没有启动的应用不需要停止
+	stopped = false
+	t.Run("not started", func(t *testing.T) {
+		ioctest.New(t, register(&stopped))
+	})
+	assert.False(t, stopped)
+}
+
+func TestApp_Concurrent(t *testing.T) {
+	// 同一个测试程序中的多个应用互不影响
+	var wg sync.WaitGroup
+	results := make([]string, 2)
+	for i, suffix := range []string{"!", "."} {
+		wg.Add(1)
+		app := ioctest.New(t, register(new(bool)))
+		app.Property("greet.suffix", suffix)
+		if i == 1 {
+			app.Replace("greeter", &mockGreeter{})
+		}
+		app.Start()
+		go func(i int, app *ioctest.App) {
+			defer wg.Done()
+			var s *GreetService
+			app.Get(&s)
+			results[i] = s.Greet("stark")
+		}(i, app)
+	}
+	wg.Wait()
+	assert.Equal(t, results, []string{"hello stark!", "mock stark."})
+}
+
+func TestApp_Serve(t *testing.T) {
+	app := ioctest.New(t)
+	app.Start()
+
+	s := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+	conn := app.ServeGRPC(s)
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	// 内存监听器上的 http 服务
+	l := app.Listen()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	r, err := l.Client().Get("http://stark/ping")
+	assert.Nil(t, err)
+	defer r.Body.Close()
+	b, err := ioutil.ReadAll(r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "pong")
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/ioc/conf"
+	"github.com/huazai2008101/stark/ioc/dync"
+)
+
+// PropertyChangeEvent 属性变化事件，Keys 为发生变化的 key 的列表，包括新增和删除
+// 的 key ，Old 和 New 分别为变化前后的全部属性。
+type PropertyChangeEvent struct {
+	Keys []string
+	Old  *conf.Properties
+	New  *conf.Properties
+}
+
+// Changed 返回 key 或者 key 的子属性是否发生了变化。
+func (e *PropertyChangeEvent) Changed(key string) bool {
+	return keysContain(e.Keys, key)
+}
+
+// PropertyValidator 属性变化的校验器，在新的属性生效之前调用，返回 error 时整个更
+// 新被拒绝，容器继续使用原来的属性。
+type PropertyValidator interface {
+	ValidateProperties(e *PropertyChangeEvent) error
+}
+
+// PropertyChangeListener 属性变化的监听器，在新的属性生效并且动态属性刷新之后调用，
+// 单例 bean 实现该接口后通过事件总线订阅 PropertiesChangedEvent ，与其他事件监听器
+// 一起按照 Order 顺序调用。
+type PropertyChangeListener interface {
+	OnPropertyChange(e *PropertyChangeEvent)
+}
+
+// dynamicField 绑定到 bean 字段的动态属性。
+type dynamicField struct {
+	v     dync.Value
+	param conf.BindParam
+}
+
+// dynamicValue 返回字段是否是动态属性。
+func dynamicValue(v reflect.Value) (dync.Value, bool) {
+	if !v.CanAddr() {
+		return nil, false
+	}
+	d, ok := v.Addr().Interface().(dync.Value)
+	return d, ok
+}
+
+// bindDynamic 使用当前的属性初始化动态属性，并在属性更新时刷新。
+func (c *container) bindDynamic(v dync.Value, param conf.BindParam) error {
+	if err := v.Refresh(c.p, param); err != nil {
+		return err
+	}
+	c.dynamic = append(c.dynamic, &dynamicField{v: v, param: param})
+	return nil
+}
+
+// collectPropertyValidators 收集实现了属性变化校验器接口的 bean ，自定义作用域的
+// bean 在作用域周期内才创建，因此不参与。属性变化的监听器通过事件总线注册。
+func (c *container) collectPropertyValidators() {
+	for _, b := range c.beans {
+		if b.status == Deleted || b.isScoped() {
+			continue
+		}
+		if v, ok := b.Interface().(PropertyValidator); ok {
+			c.validators = append(c.validators, v)
+		}
+	}
+}
+
+// RefreshProperties 使用 p 替换容器当前的属性。新的属性先经过动态属性和属性校验器
+// 的校验，校验失败时返回 error 并保留原来的属性；校验通过后刷新动态属性，然后通知
+// OnProperty 注册的监听函数以及属性变化的监听器。配置文件、配置中心等任何属性源都
+// 可以通过该方法触发属性的更新。
+func (c *container) RefreshProperties(p *conf.Properties) error {
+	_, err := c.updateProperties(p)
+	return err
+}
+
+// updateProperties 参考 RefreshProperties 的解释，返回发生变化的 key 的列表。
+func (c *container) updateProperties(p *conf.Properties) ([]string, error) {
+	c.updateMutex.Lock()
+	defer c.updateMutex.Unlock()
+
+	if state := c.getState(); state == Closed {
+		return nil, fmt.Errorf("container closed")
+	} else if state != Refreshed {
+		return nil, fmt.Errorf("container not refreshed")
+	}
+
+	old := c.properties()
+	changed := diffProperties(old, p)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	// 发生变化的属性值无法解密时直接拒绝，避免更新后才发现属性不可用
+	if err := p.CheckEncrypted(changed...); err != nil {
+		return nil, err
+	}
+	e := &PropertyChangeEvent{Keys: changed, Old: old, New: p}
+
+	var fields []*dynamicField
+	for _, f := range c.dynamic {
+		if !e.Changed(f.param.Key) {
+			continue
+		}
+		if err := f.v.Validate(p, f.param); err != nil {
+			return nil, fmt.Errorf("属性 %s 校验失败: %w", f.param.Key, err)
+		}
+		fields = append(fields, f)
+	}
+	for _, v := range c.validators {
+		if err := v.ValidateProperties(e); err != nil {
+			return nil, fmt.Errorf("属性校验失败: %w", err)
+		}
+	}
+
+	c.pMutex.Lock()
+	c.p = p
+	onProperty := make(map[string]interface{})
+	for key, fn := range c.mapOfOnProperty {
+		if e.Changed(key) {
+			onProperty[key] = fn
+		}
+	}
+	c.pMutex.Unlock()
+
+	// 刷新作用域的 bean 在下次获取时使用新的属性重新创建
+	c.refreshScope()
+
+	// 在锁外刷新和通知，监听函数中可以读取属性。
+	for _, f := range fields {
+		if err := f.v.Refresh(p, f.param); err != nil {
+			log.Errorf(c.ctx, "动态属性 %s 刷新异常:%+v", f.param.Path, err)
+		}
+	}
+	for key, fn := range onProperty {
+		if err := callOnProperty(p, key, fn); err != nil {
+			log.Errorf(c.ctx, "属性 %s 更新通知异常:%+v", key, err)
+		}
+	}
+	c.publishEvent(&PropertiesChangedEvent{PropertyChangeEvent: e})
+	return changed, nil
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc/conf"
+	"github.com/huazai2008101/stark/ioc/dync"
+)
+
+type dynamicConfig struct {
+	Port dync.Int64  `value:"${server.port}"`
+	Mode dync.String `value:"${server.mode:=dev}"`
+}
+
+type modeValidator struct{}
+
+func (v *modeValidator) ValidateProperties(e *PropertyChangeEvent) error {
+	if e.Changed("server.mode") && e.New.Get("server.mode") == "bad" {
+		return errors.New("bad mode")
+	}
+	return nil
+}
+
+// propertyRecorder 记录收到通知时动态属性的值，验证通知发生在刷新之后
+type propertyRecorder struct {
+	Config *dynamicConfig `autowire:""`
+	keys   [][]string
+	ports  []int64
+}
+
+func (r *propertyRecorder) OnPropertyChange(e *PropertyChangeEvent) {
+	r.keys = append(r.keys, e.Keys)
+	r.ports = append(r.ports, r.Config.Port.Value())
+}
+
+func newProperties(m map[string]string) *conf.Properties {
+	p := conf.New()
+	for k, v := range m {
+		_ = p.Set(k, v)
+	}
+	return p
+}
+
+func TestRefreshProperties(t *testing.T) {
+	cfg := new(dynamicConfig)
+	cfg.Port.OnValidate(func(v int64) error {
+		if v <= 0 || v > 65535 {
+			return errors.New("invalid port")
+		}
+		return nil
+	})
+	recorder := new(propertyRecorder)
+
+	c := New().(*container)
+	c.Property("server.port", 8080)
+	c.Object(cfg)
+	c.Object(new(modeValidator))
+	c.Object(recorder)
+	var ports []int
+	c.OnProperty("server.port", func(port int) { ports = append(ports, port) })
+	assert.Nil(t, c.Refresh())
+	assert.Equal(t, cfg.Port.Value(), int64(8080))
+	assert.Equal(t, cfg.Mode.Value(), "dev")
+	assert.Equal(t, ports, []int{8080})
+
+	// 校验 -> 替换 -> 刷新 -> 通知
+	err := c.RefreshProperties(newProperties(map[string]string{"server.port": "9090", "server.mode": "prod"}))
+	assert.Nil(t, err)
+	assert.Equal(t, cfg.Port.Value(), int64(9090))
+	assert.Equal(t, cfg.Mode.Value(), "prod")
+	assert.Equal(t, c.Prop("server.port"), "9090")
+	assert.Equal(t, ports, []int{8080, 9090})
+	assert.Equal(t, recorder.keys, [][]string{{"server.mode", "server.port"}})
+	assert.Equal(t, recorder.ports, []int64{9090})
+
+	// 没有变化时不通知
+	err = c.RefreshProperties(newProperties(map[string]string{"server.port": "9090", "server.mode": "prod"}))
+	assert.Nil(t, err)
+	assert.Equal(t, len(recorder.keys), 1)
+
+	// 动态属性校验失败时回滚，保留原来的属性
+	err = c.RefreshProperties(newProperties(map[string]string{"server.port": "70000", "server.mode": "test"}))
+	assert.Error(t, err, "属性 server.port 校验失败: invalid port")
+	assert.Equal(t, cfg.Port.Value(), int64(9090))
+	assert.Equal(t, cfg.Mode.Value(), "prod")
+	assert.Equal(t, c.Prop("server.mode"), "prod")
+
+	// 属性校验器拒绝时回滚
+	err = c.RefreshProperties(newProperties(map[string]string{"server.port": "7070", "server.mode": "bad"}))
+	assert.Error(t, err, "属性校验失败: bad mode")
+	assert.Equal(t, cfg.Port.Value(), int64(9090))
+	assert.Equal(t, c.Prop("server.port"), "9090")
+	assert.Equal(t, ports, []int{8080, 9090})
+	assert.Equal(t, len(recorder.keys), 1)
+
+	// 无法解密的属性值被拒绝
+	err = c.RefreshProperties(newProperties(map[string]string{"server.port": "9090", "server.mode": "ENC(YWJj)"}))
+	assert.Error(t, err, "属性 server.mode 解密异常")
+	assert.Equal(t, c.Prop("server.mode"), "prod")
+
+	// 容器关闭后拒绝更新，并发的更新和关闭不会产生数据竞争
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.RefreshProperties(newProperties(map[string]string{"server.port": "6060", "server.mode": "prod"}))
+	}()
+	c.Close()
+	<-done
+	err = c.RefreshProperties(newProperties(map[string]string{"server.port": "5050", "server.mode": "prod"}))
+	assert.Error(t, err, "container closed")
+}
+
+// refreshClient 刷新作用域的 bean ，属性变化后重新创建
+type refreshClient struct {
+	Addr   string `value:"${client.addr}"`
+	closed bool
+}
+
+func (c *refreshClient) OnDestroy() {
+	c.closed = true
+}
+
+type refreshHandler struct {
+	Client func() (*refreshClient, error) `autowire:""`
+}
+
+func TestRefreshBeanScope(t *testing.T) {
+	c := New().(*container)
+	c.Property("client.addr", "127.0.0.1:8080")
+	b := c.Object(new(refreshClient))
+	b.SetScope(RefreshBeanScope)
+	h := new(refreshHandler)
+	c.Object(h)
+	assert.Nil(t, c.Refresh())
+
+	c1, err := h.Client()
+	assert.Nil(t, err)
+	assert.Equal(t, c1.Addr, "127.0.0.1:8080")
+	c2, err := h.Client()
+	assert.Nil(t, err)
+	assert.True(t, c1 == c2)
+
+	// 属性没有变化时不重新创建
+	err = c.RefreshProperties(newProperties(map[string]string{"client.addr": "127.0.0.1:8080"}))
+	assert.Nil(t, err)
+	c2, _ = h.Client()
+	assert.True(t, c1 == c2)
+
+	// 属性变化后销毁旧的对象，下次获取时使用新的属性创建
+	err = c.RefreshProperties(newProperties(map[string]string{"client.addr": "127.0.0.1:9090"}))
+	assert.Nil(t, err)
+	assert.True(t, c1.closed)
+	c2, err = h.Client()
+	assert.Nil(t, err)
+	assert.Equal(t, c2.Addr, "127.0.0.1:9090")
+	assert.False(t, c2.closed)
+
+	// 容器关闭时销毁当前周期的对象
+	c.Close()
+	assert.True(t, c2.closed)
+	_, err = h.Client()
+	assert.Error(t, err, "scope already closed")
+}
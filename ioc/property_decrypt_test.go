@@ -0,0 +1,194 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+var aesKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestAESDecryptor(t *testing.T) {
+
+	_, err := conf.NewAESDecryptor([]byte("short"))
+	assert.Error(t, err, "invalid key size 5")
+
+	s, err := conf.AESEncrypt(aesKey, "secret")
+	assert.Nil(t, err)
+	assert.True(t, conf.IsEncrypted(s))
+	ciphertext := strings.TrimSuffix(strings.TrimPrefix(s, "ENC("), ")")
+
+	d, err := conf.NewAESDecryptor(aesKey)
+	assert.Nil(t, err)
+	v, err := d.Decrypt(ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, v, "secret")
+
+	// 每次加密使用不同的 nonce
+	s2, err := conf.AESEncrypt(aesKey, "secret")
+	assert.Nil(t, err)
+	assert.False(t, s == s2)
+
+	d, err = conf.NewAESDecryptor([]byte("fedcba9876543210fedcba9876543210"))
+	assert.Nil(t, err)
+	_, err = d.Decrypt(ciphertext)
+	assert.Error(t, err, "message authentication failed")
+
+	_, err = d.Decrypt("YWJj")
+	assert.Error(t, err, "密文长度错误")
+
+	_, err = d.Decrypt("not base64")
+	assert.Error(t, err, "illegal base64 data")
+}
+
+func TestProperties_Encrypted(t *testing.T) {
+	d, err := conf.NewAESDecryptor(aesKey)
+	assert.Nil(t, err)
+	conf.RegisterDecryptor(d)
+	defer conf.RegisterDecryptor(nil)
+
+	s, err := conf.AESEncrypt(aesKey, "secret")
+	assert.Nil(t, err)
+
+	p := conf.New()
+	_ = p.Set("db.password", s)
+	_ = p.Set("db.url", "mysql://${db.password}")
+	assert.Nil(t, p.CheckEncrypted())
+	assert.Equal(t, p.Get("db.password"), "secret")
+	assert.Equal(t, p.Raw("db.password"), s)
+
+	var db struct {
+		URL      string `value:"${db.url}"`
+		Password string `value:"${db.password}"`
+	}
+	assert.Nil(t, p.Bind(&db))
+	assert.Equal(t, db.Password, "secret")
+	assert.Equal(t, db.URL, "mysql://secret")
+
+	t.Run("decrypt failed", func(t *testing.T) {
+		p := conf.New()
+		_ = p.Set("db.password", "ENC(YWJj)")
+		// 解密失败时不返回密文
+		assert.Equal(t, p.Get("db.password"), "")
+		assert.Error(t, p.CheckEncrypted(), "属性 db.password 解密异常: 密文长度错误")
+		var db struct {
+			Password string `value:"${db.password}"`
+		}
+		assert.Error(t, p.Bind(&db), "property \"db.password\" 解密异常 密文长度错误")
+		var hosts struct {
+			Hosts []string `value:"${db.password}"`
+		}
+		assert.Error(t, p.Bind(&hosts), "property \"db.password\" 解密异常 密文长度错误")
+		_, err := p.Resolve("${db.password}")
+		assert.Error(t, err, "property \"db.password\" 解密异常 密文长度错误")
+	})
+
+	t.Run("no decryptor", func(t *testing.T) {
+		conf.RegisterDecryptor(nil)
+		p := conf.New()
+		_ = p.Set("db.password", s)
+		assert.Equal(t, p.Get("db.password"), "")
+		assert.Error(t, p.CheckEncrypted(), "属性 db.password 解密异常: 未注册属性值解密器")
+	})
+}
+
+// countDecryptor 记录解密次数的解密器
+type countDecryptor struct {
+	mutex sync.Mutex
+	count map[string]int
+}
+
+func (d *countDecryptor) Decrypt(ciphertext string) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.count[ciphertext]++
+	if ciphertext == "bad" {
+		return "", errors.New("bad ciphertext")
+	}
+	return strings.ToUpper(ciphertext), nil
+}
+
+func TestProperties_DecryptCache(t *testing.T) {
+	d := &countDecryptor{count: map[string]int{}}
+	conf.RegisterDecryptor(d)
+	defer conf.RegisterDecryptor(nil)
+
+	p := conf.New()
+	_ = p.Set("a", "ENC(abc)")
+	_ = p.Set("b", "ENC(abc)")
+	_ = p.Set("c", "ENC(bad)")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, p.Get("a"), "ABC")
+		assert.Equal(t, p.Get("b"), "ABC")
+		var v struct {
+			A string `value:"${a}"`
+		}
+		assert.Nil(t, p.Bind(&v))
+		assert.Equal(t, v.A, "ABC")
+		assert.Equal(t, p.Get("c"), "")
+	}
+	// 解密成功的结果被缓存，失败的结果不缓存
+	assert.Equal(t, d.count, map[string]int{"abc": 1, "bad": 3})
+
+	// 只检查指定的属性
+	assert.Nil(t, p.CheckEncrypted("a", "b", "none"))
+	assert.Error(t, p.CheckEncrypted(), "属性 c 解密异常: bad ciphertext")
+
+	// 重新注册解密器时清空缓存
+	conf.RegisterDecryptor(d)
+	assert.Equal(t, p.Get("a"), "ABC")
+	assert.Equal(t, d.count["abc"], 2)
+}
+
+func TestApp_EncryptedProperty(t *testing.T) {
+	conf.RegisterDecryptor(nil)
+
+	// 默认在使用时才解密，没有使用的属性不影响启动
+	app := ioc.NewApp()
+	app.Property("db.password", "ENC(YWJj)")
+	assert.Nil(t, app.Start())
+	app.Stop()
+
+	// 开启 fail-fast 后启动时发现无法解密的属性值
+	app = ioc.NewApp()
+	app.Property("spring.config.decrypt.fail-fast", true)
+	app.Property("db.password", "ENC(YWJj)")
+	err := app.Start()
+	assert.Error(t, err, "属性 db.password 解密异常: 未注册属性值解密器")
+
+	d, err := conf.NewAESDecryptor(aesKey)
+	assert.Nil(t, err)
+	conf.RegisterDecryptor(d)
+	defer conf.RegisterDecryptor(nil)
+
+	s, err := conf.AESEncrypt(aesKey, "secret")
+	assert.Nil(t, err)
+	app = ioc.NewApp()
+	app.Property("db.password", s)
+	passwords := make(chan string, 1)
+	app.OnProperty("db.password", func(password string) { passwords <- password })
+	assert.Nil(t, app.Start())
+	defer app.Stop()
+	assert.Equal(t, <-passwords, "secret")
+}
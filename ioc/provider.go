@@ -0,0 +1,117 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/huazai2008101/stark/ioc/internal"
+)
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	providerType = reflect.TypeOf(Provider{})
+)
+
+// isProvider 返回 t 是否是 provider 类型，支持 func() T 、func() (T, error) 以及
+// func(context.Context) (T, error) 三种形式，其中 T 为 bean 类型。
+func isProvider(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.IsVariadic() || t.NumIn() > 1 {
+		return false
+	}
+	if t.NumIn() == 1 && (t.In(0) != contextType || t.NumOut() != 2) {
+		return false
+	}
+	switch t.NumOut() {
+	case 1:
+	case 2:
+		if t.Out(1) != errorType {
+			return false
+		}
+	default:
+		return false
+	}
+	return internal.IsBeanType(t.Out(0))
+}
+
+// newProvider 创建类型为 t 的 provider ，每次调用时通过容器获取 tag 对应的 bean ，
+// 原型 bean 每次返回新的对象，自定义作用域的 bean 从 ctx 所在的作用域周期中获取。
+// 没有 error 返回值的 provider 在获取失败时 panic 。
+func (c *container) newProvider(t reflect.Type, tag wireTag) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		var ctx context.Context
+		if len(in) > 0 {
+			ctx, _ = in[0].Interface().(context.Context)
+		}
+		out := reflect.New(t.Out(0)).Elem()
+		v, err := c.provide(ctx, t.Out(0), tag)
+		if err == nil && v.IsValid() {
+			out.Set(assignableValue(v, out.Type()))
+		}
+		if t.NumOut() == 1 {
+			if err != nil {
+				panic(err)
+			}
+			return []reflect.Value{out}
+		}
+		errValue := reflect.New(errorType).Elem()
+		if err != nil {
+			errValue.Set(reflect.ValueOf(&err).Elem())
+		}
+		return []reflect.Value{out, errValue}
+	})
+}
+
+// provide 获取 tag 对应的 bean ，tag 允许为空并且没有找到时返回无效值。
+func (c *container) provide(ctx context.Context, t reflect.Type, tag wireTag) (reflect.Value, error) {
+	stack := newWiringStack()
+	b, err := c.findWiredBean(t, tag, stack)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("provider %s: %w", t, err)
+	}
+	if b == nil {
+		return reflect.Value{}, nil
+	}
+	return c.scopedValue(ctx, b)
+}
+
+// Provider 按需获取 bean 的句柄。注入时只记录 bean 选择器而不获取 bean ，每次调
+// 用 Get 时才通过容器获取，原型 bean 每次获取到的都是新的对象，因此可以用于打破循
+// 环依赖或者延迟获取 bean 。Provider 在容器刷新完成后可以并发使用。
+//
+//	type Service struct {
+//		repo ioc.Provider `autowire:"repo"`
+//	}
+//
+//	var repo *Repository
+//	err := s.repo.Get(&repo)
+type Provider struct {
+	c         *container
+	selectors []BeanSelector
+}
+
+// Get 参考 Context.Get 的解释，选择器为注入时的 tag 。
+func (p Provider) Get(i interface{}) error {
+	if p.c == nil {
+		return errors.New("provider not wired")
+	}
+	return p.c.Get(i, p.selectors...)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/cond"
+)
+
+type providerRepo struct {
+	Name string `value:"${repo.name:=default}"`
+}
+
+type providerProto struct {
+	Count int
+}
+
+type providerMissing struct{}
+
+type providerConsumer struct {
+	Repo         func() *providerRepo                         `autowire:""`
+	RepoErr      func() (*providerRepo, error)                `autowire:""`
+	RepoCtx      func(context.Context) (*providerRepo, error) `autowire:""`
+	Proto        func() (*providerProto, error)               `autowire:""`
+	ProtoHandle  ioc.Provider                                 `autowire:"proto"`
+	Missing      func() (*providerMissing, error)             `autowire:""`
+	MissingPanic func() *providerMissing                      `autowire:""`
+	Nullable     func() (*providerMissing, error)             `autowire:"?"`
+	MissingGet   ioc.Provider                                 `autowire:"missing"`
+}
+
+func TestProvider(t *testing.T) {
+	c := ioc.New()
+	c.Property("repo.name", "order")
+	repo := new(providerRepo)
+	c.Object(repo)
+	b := c.Object(new(providerProto)).Name("proto")
+	b.SetScope(ioc.PrototypeBeanScope)
+	c.Object(new(providerMissing)).Name("missing").On(cond.OnProperty("missing.enabled"))
+	p := new(providerConsumer)
+	c.Object(p)
+	assert.Nil(t, c.Refresh())
+
+	// 三种形式的 provider 获取的都是同一个单例
+	assert.Equal(t, p.Repo(), repo)
+	r, err := p.RepoErr()
+	assert.Nil(t, err)
+	assert.Equal(t, r, repo)
+	r, err = p.RepoCtx(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, r, repo)
+	assert.Equal(t, r.Name, "order")
+
+	// 原型 bean 每次获取到新的对象
+	p1, err := p.Proto()
+	assert.Nil(t, err)
+	p2, err := p.Proto()
+	assert.Nil(t, err)
+	assert.False(t, p1 == p2)
+	p1.Count++
+	assert.Equal(t, p2.Count, 0)
+
+	var h1, h2 *providerProto
+	assert.Nil(t, p.ProtoHandle.Get(&h1))
+	assert.Nil(t, p.ProtoHandle.Get(&h2))
+	assert.False(t, h1 == h2)
+	assert.False(t, h1 == p1)
+
+	// 被条件删除的 bean 返回包含条件结果的错误
+	_, err = p.Missing()
+	var e *ioc.BeanNotFoundError
+	assert.True(t, errors.As(err, &e))
+	assert.Error(t, err, "已被删除，条件判断结果: not matched")
+
+	var m *providerMissing
+	err = p.MissingGet.Get(&m)
+	assert.Error(t, err, "已被删除，条件判断结果: not matched")
+
+	// 可以为空的 provider 没有找到时返回零值
+	m, err = p.Nullable()
+	assert.Nil(t, err)
+	assert.True(t, m == nil)
+
+	// 没有 error 返回值的 provider 获取失败时 panic
+	defer func() {
+		err, ok := recover().(error)
+		assert.True(t, ok)
+		assert.Error(t, err, "已被删除")
+	}()
+	p.MissingPanic()
+	t.Fatal("should panic")
+}
+
+func TestProvider_NotWired(t *testing.T) {
+	var p ioc.Provider
+	var repo *providerRepo
+	assert.Error(t, p.Get(&repo), "provider not wired")
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/huazai2008101/stark/base/log"
+	"github.com/huazai2008101/stark/base/util"
+)
+
+// Scope 自定义的 bean 作用域。作用域内的 bean 在每个作用域周期中只创建一次，周期
+// 结束时销毁，比如请求作用域的周期为一次请求。这类 bean 不能直接注入，需要通过
+// func(context.Context) (T, error) 形式的 provider 从 ctx 所在的周期中获取。
+type Scope interface {
+
+	// Begin 开始一个作用域周期，返回的 ctx 用于获取该周期内的 bean ，end 用于结束
+	// 该周期并销毁周期内的 bean 。
+	Begin(ctx context.Context) (_ context.Context, end func())
+
+	// Store 返回 ctx 所在的作用域周期，ctx 不在作用域周期内时返回 nil 。
+	Store(ctx context.Context) *ScopeStore
+}
+
+var (
+	scopeMutex sync.RWMutex
+	scopes     = map[BeanScope]Scope{
+		RequestBeanScope:   NewContextScope("request"),
+		GoroutineBeanScope: NewContextScope("goroutine"),
+	}
+	scopeNames = map[BeanScope]string{
+		SingletonBeanScope: "singleton",
+		PrototypeBeanScope: "prototype",
+		RequestBeanScope:   "request",
+		GoroutineBeanScope: "goroutine",
+		RefreshBeanScope:   "refresh",
+	}
+	// scopedBeans 记录已经刷新的容器中各个作用域的 bean 的数量。
+	scopedBeans = map[BeanScope]int{}
+)
+
+// RegisterScope 注册自定义作用域并返回作用域的值，名称重复时 panic 。
+func RegisterScope(name string, s Scope) BeanScope {
+	scopeMutex.Lock()
+	defer scopeMutex.Unlock()
+	for _, v := range scopeNames {
+		util.Panic(fmt.Errorf("scope %q already registered", name)).When(v == name)
+	}
+	scope := BeanScope(len(scopeNames))
+	scopes[scope] = s
+	scopeNames[scope] = name
+	return scope
+}
+
+func getScope(scope BeanScope) (Scope, bool) {
+	scopeMutex.RLock()
+	defer scopeMutex.RUnlock()
+	s, ok := scopes[scope]
+	return s, ok
+}
+
+// HasScopedBeans 返回是否有已经刷新的容器注册了 scope 作用域的 bean ，没有时不需要
+// 开启作用域周期，例如 web 服务可以跳过请求作用域，避免每次请求都创建 ScopeStore 。
+func HasScopedBeans(scope BeanScope) bool {
+	scopeMutex.RLock()
+	defer scopeMutex.RUnlock()
+	return scopedBeans[scope] > 0
+}
+
+// addScopedBeans 增加或者减少各个作用域的 bean 的数量。
+func addScopedBeans(m map[BeanScope]int, delta int) {
+	scopeMutex.Lock()
+	defer scopeMutex.Unlock()
+	for scope, n := range m {
+		scopedBeans[scope] += n * delta
+	}
+}
+
+// BeginScope 开始 scope 的一个作用域周期，调用 end 结束该周期，例如：
+//
+//	ctx, end := ioc.BeginScope(ctx, ioc.RequestBeanScope)
+//	defer end()
+func BeginScope(ctx context.Context, scope BeanScope) (_ context.Context, end func()) {
+	s, ok := getScope(scope)
+	util.Panic(fmt.Errorf("scope %s not registered", scope)).When(!ok)
+	return s.Begin(ctx)
+}
+
+// contextScope 将作用域周期保存在 ctx 中的作用域。
+type contextScope struct {
+	name string
+}
+
+// NewContextScope 创建将作用域周期保存在 ctx 中的作用域，name 仅用于区分不同的作用域。
+func NewContextScope(name string) Scope {
+	return &contextScope{name: name}
+}
+
+func (s *contextScope) Begin(ctx context.Context) (context.Context, func()) {
+	store := NewScopeStore()
+	return context.WithValue(ctx, s, store), store.Close
+}
+
+func (s *contextScope) Store(ctx context.Context) *ScopeStore {
+	store, _ := ctx.Value(s).(*ScopeStore)
+	return store
+}
+
+var errScopeClosed = errors.New("scope already closed")
+
+type scopeEntry struct {
+	once sync.Once
+	v    reflect.Value
+	err  error
+}
+
+// ScopeStore 保存一个作用域周期内的 bean ，周期结束时按照创建的逆序销毁。
+type ScopeStore struct {
+	mutex      sync.Mutex
+	beans      map[string]*scopeEntry
+	destroyers []func()
+	closed     bool
+}
+
+func NewScopeStore() *ScopeStore {
+	return &ScopeStore{beans: make(map[string]*scopeEntry)}
+}
+
+// get 返回 id 对应的 bean ，不存在时调用 create 创建，并发调用时只创建一次。
+func (s *ScopeStore) get(id string, create func() (reflect.Value, func(), error)) (reflect.Value, error) {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return reflect.Value{}, errScopeClosed
+	}
+	e, ok := s.beans[id]
+	if !ok {
+		e = &scopeEntry{}
+		s.beans[id] = e
+	}
+	s.mutex.Unlock()
+
+	e.once.Do(func() {
+		var destroy func()
+		e.v, destroy, e.err = create()
+		if e.err != nil {
+			return
+		}
+		// 创建期间周期可能已经结束，此时立即销毁新创建的 bean
+		s.mutex.Lock()
+		closed := s.closed
+		if !closed && destroy != nil {
+			s.destroyers = append(s.destroyers, destroy)
+		}
+		s.mutex.Unlock()
+		if closed {
+			if destroy != nil {
+				destroy()
+			}
+			e.v, e.err = reflect.Value{}, errScopeClosed
+		}
+	})
+	return e.v, e.err
+}
+
+// Close 结束作用域周期并销毁周期内的 bean ，重复调用只销毁一次。
+func (s *ScopeStore) Close() {
+	s.mutex.Lock()
+	destroyers := s.destroyers
+	s.destroyers, s.closed = nil, true
+	s.mutex.Unlock()
+	for i := len(destroyers) - 1; i >= 0; i-- {
+		destroyers[i]()
+	}
+}
+
+// isScoped 返回 bean 是否属于自定义作用域。
+func (d *BeanDefinition) isScoped() bool {
+	return d.scope != SingletonBeanScope && d.scope != PrototypeBeanScope
+}
+
+// cloneBean 浅拷贝 bean 的值，非指针类型的 bean 直接返回。
+func cloneBean(b *BeanDefinition) reflect.Value {
+	v := b.Value()
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Ptr {
+		return b.Value()
+	}
+	r := reflect.New(v.Type().Elem())
+	r.Elem().Set(v.Elem())
+	return r
+}
+
+// scopedValue 返回 ctx 所在作用域周期中的 bean ，不存在时创建一个。
+func (c *container) scopedValue(ctx context.Context, b *BeanDefinition) (reflect.Value, error) {
+	switch b.scope {
+	case SingletonBeanScope:
+		return b.Value(), nil
+	case PrototypeBeanScope:
+		return cloneBean(b), nil
+	case RefreshBeanScope:
+		return c.refreshScopedValue(b)
+	}
+	s, ok := getScope(b.scope)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%s scope %s not registered", b, b.scope)
+	}
+	var store *ScopeStore
+	if ctx != nil {
+		store = s.Store(ctx)
+	}
+	if store == nil {
+		return reflect.Value{}, fmt.Errorf("%s 不在 %s 作用域周期内", b, b.scope)
+	}
+	return store.get(b.ID(), func() (reflect.Value, func(), error) {
+		return c.newScopedBean(b)
+	})
+}
+
+// refreshScopedValue 返回当前刷新周期中的 bean ，不存在时创建一个。创建期间属性
+// 发生变化时周期已经结束，此时在新的周期中重新创建。
+func (c *container) refreshScopedValue(b *BeanDefinition) (reflect.Value, error) {
+	for {
+		c.refreshMutex.Lock()
+		store := c.refreshStore
+		c.refreshMutex.Unlock()
+		v, err := store.get(b.ID(), func() (reflect.Value, func(), error) {
+			return c.newScopedBean(b)
+		})
+		if err == errScopeClosed && c.getState() == Refreshed {
+			continue
+		}
+		return v, err
+	}
+}
+
+// refreshScope 属性发生变化后开始新的刷新周期，销毁上一个周期中的 bean 。
+func (c *container) refreshScope() {
+	c.refreshMutex.Lock()
+	store := c.refreshStore
+	c.refreshStore = NewScopeStore()
+	c.refreshMutex.Unlock()
+	store.Close()
+}
+
+// newScopedBean 创建作用域内的 bean ，构造函数 bean 重新执行构造函数，对象 bean
+// 浅拷贝注册的对象，然后进行属性绑定和依赖注入并执行初始化函数，返回的销毁函数在作
+// 用域周期结束时执行。
+func (c *container) newScopedBean(b *BeanDefinition) (reflect.Value, func(), error) {
+	stack := newWiringStack()
+	stack.p = c.properties()
+
+	var v, r reflect.Value
+	if b.f != nil {
+		v = reflect.New(b.Value().Type()).Elem()
+		var err error
+		if r, err = c.callConstructor(b, v, stack); err != nil {
+			return reflect.Value{}, nil, err
+		}
+	} else {
+		v = cloneBean(b)
+		r = v
+	}
+
+	if err := c.wireBeanValue(r, r.Type(), stack); err != nil {
+		return reflect.Value{}, nil, err
+	}
+	for _, f := range stack.lazyFields {
+		tag := strings.TrimSuffix(f.tag, ",lazy")
+		if err := c.wireByTag(f.v, tag, stack); err != nil {
+			return reflect.Value{}, nil, fmt.Errorf("%q wired error: %w", f.path, err)
+		}
+	}
+
+	if b.init != nil {
+		out := reflect.ValueOf(b.init).Call([]reflect.Value{v})
+		if len(out) > 0 && !out[0].IsNil() {
+			return reflect.Value{}, nil, out[0].Interface().(error)
+		}
+	}
+
+	if f, ok := v.Interface().(BeanInit); ok {
+		if err := f.OnInit(c); err != nil {
+			return reflect.Value{}, nil, err
+		}
+	}
+
+	var destroy func()
+	if b.destroy != nil {
+		destroy = func() {
+			out := reflect.ValueOf(b.destroy).Call([]reflect.Value{v})
+			if len(out) > 0 && !out[0].IsNil() {
+				log.Error(c.ctx, out[0].Interface().(error))
+			}
+		}
+	} else if f, ok := v.Interface().(BeanDestroy); ok {
+		destroy = f.OnDestroy
+	}
+	return v, destroy, nil
+}
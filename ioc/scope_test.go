@@ -0,0 +1,237 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+type scopedSession struct {
+	ID        int
+	destroyed *[]int
+}
+
+func (s *scopedSession) OnDestroy() {
+	*s.destroyed = append(*s.destroyed, s.ID)
+}
+
+type sessionHandler struct {
+	Session func(context.Context) (*scopedSession, error) `autowire:""`
+}
+
+func newScopedContainer(t *testing.T, scope ioc.BeanScope, init func(*scopedSession)) (*sessionHandler, *[]int) {
+	destroyed := new([]int)
+	c := ioc.New()
+	b := c.Object(&scopedSession{destroyed: destroyed}).Init(init)
+	b.SetScope(scope)
+	h := new(sessionHandler)
+	c.Object(h)
+	assert.Nil(t, c.Refresh())
+	t.Cleanup(c.Close)
+	return h, destroyed
+}
+
+func TestScope(t *testing.T) {
+
+	for _, scope := range []ioc.BeanScope{ioc.RequestBeanScope, ioc.GoroutineBeanScope} {
+		t.Run(scope.String(), func(t *testing.T) {
+			var (
+				mutex sync.Mutex
+				count int
+			)
+			h, destroyed := newScopedContainer(t, scope, func(s *scopedSession) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				count++
+				s.ID = count
+			})
+
+			// 不在作用域周期内时不能获取
+			_, err := h.Session(context.Background())
+			assert.Error(t, err, "不在 "+scope.String()+" 作用域周期内")
+
+			// 同一个周期内只创建一次，并发获取也是如此
+			ctx1, end1 := ioc.BeginScope(context.Background(), scope)
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s, err := h.Session(ctx1)
+					assert.Nil(t, err)
+					assert.Equal(t, s.ID, 1)
+				}()
+			}
+			wg.Wait()
+
+			// 不同的周期创建不同的对象
+			ctx2, end2 := ioc.BeginScope(context.Background(), scope)
+			s2, err := h.Session(ctx2)
+			assert.Nil(t, err)
+			assert.Equal(t, s2.ID, 2)
+
+			// 周期结束时销毁，重复结束只销毁一次
+			end2()
+			end1()
+			end1()
+			assert.Equal(t, *destroyed, []int{2, 1})
+
+			_, err = h.Session(ctx1)
+			assert.Error(t, err, "scope already closed")
+		})
+	}
+}
+
+func TestScope_CloseWhileCreating(t *testing.T) {
+	var end func()
+	h, destroyed := newScopedContainer(t, ioc.RequestBeanScope, func(s *scopedSession) {
+		s.ID = 1
+		// 创建过程中周期结束，新创建的 bean 立即销毁
+		end()
+	})
+	var ctx context.Context
+	ctx, end = ioc.BeginScope(context.Background(), ioc.RequestBeanScope)
+	_, err := h.Session(ctx)
+	assert.Error(t, err, "scope already closed")
+	assert.Equal(t, *destroyed, []int{1})
+}
+
+func TestScope_Injection(t *testing.T) {
+
+	t.Run("direct", func(t *testing.T) {
+		c := ioc.New()
+		b := c.Object(&scopedSession{})
+		b.SetScope(ioc.RequestBeanScope)
+		c.Object(new(struct {
+			Session *scopedSession `autowire:""`
+		}))
+		err := c.Refresh()
+		assert.Error(t, err, `的作用域为 request ，只能通过 func\(context.Context\) \(\*ioc_test.scopedSession, error\) 注入`)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		c := ioc.New()
+		b := c.Object(&scopedSession{})
+		b.SetScope(ioc.BeanScope(99))
+		err := c.Refresh()
+		assert.Error(t, err, "scope BeanScope\\(99\\) not registered")
+
+		defer func() {
+			assert.Error(t, recover().(error), "scope BeanScope\\(99\\) not registered")
+		}()
+		ioc.BeginScope(context.Background(), ioc.BeanScope(99))
+		t.Fatal("should panic")
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		scope := ioc.RegisterScope("tenant", ioc.NewContextScope("tenant"))
+		assert.Equal(t, scope.String(), "tenant")
+		h, destroyed := newScopedContainer(t, scope, func(s *scopedSession) { s.ID = 1 })
+		ctx, end := ioc.BeginScope(context.Background(), scope)
+		s, err := h.Session(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, s.ID, 1)
+		end()
+		assert.Equal(t, *destroyed, []int{1})
+	})
+}
+
+type scopedCart struct {
+	Items    map[string]int
+	Currency string `value:"${currency:=CNY}"`
+}
+
+type cartHandler struct {
+	Cart func(context.Context) (*scopedCart, error) `autowire:""`
+}
+
+func TestScope_Constructor(t *testing.T) {
+	count := 0
+	c := ioc.New()
+	c.Property("currency", "USD")
+	b := c.Provide(func() *scopedCart {
+		count++
+		return &scopedCart{Items: map[string]int{}}
+	})
+	b.SetScope(ioc.RequestBeanScope)
+	h := new(cartHandler)
+	c.Object(h)
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+
+	// 刷新时不执行作用域 bean 的构造函数
+	assert.Equal(t, count, 0)
+
+	get := func() *scopedCart {
+		ctx, end := ioc.BeginScope(context.Background(), ioc.RequestBeanScope)
+		defer end()
+		cart, err := h.Cart(ctx)
+		assert.Nil(t, err)
+		cart.Items["apple"]++
+		return cart
+	}
+
+	// 每个周期重新执行构造函数，不同周期之间不共享 map 等引用类型的字段
+	c1, c2 := get(), get()
+	assert.Equal(t, count, 2)
+	assert.Equal(t, c1.Items, map[string]int{"apple": 1})
+	assert.Equal(t, c2.Items, map[string]int{"apple": 1})
+	assert.Equal(t, c2.Currency, "USD")
+}
+
+func TestHasScopedBeans(t *testing.T) {
+	scope := ioc.RegisterScope("counted", ioc.NewContextScope("counted"))
+	assert.False(t, ioc.HasScopedBeans(scope))
+
+	c := ioc.New()
+	b := c.Object(&scopedSession{})
+	b.SetScope(scope)
+	assert.Nil(t, c.Refresh())
+	assert.True(t, ioc.HasScopedBeans(scope))
+
+	// 容器关闭后不再需要开启该作用域的周期
+	c.Close()
+	assert.False(t, ioc.HasScopedBeans(scope))
+}
+
+// scopedValidator 实现了属性校验器接口的作用域 bean
+type scopedValidator struct {
+	keys []string
+}
+
+func (v *scopedValidator) ValidateProperties(e *ioc.PropertyChangeEvent) error {
+	v.keys = e.Keys
+	return nil
+}
+
+func TestScope_PropertyValidator(t *testing.T) {
+	c := ioc.New()
+	b := c.Provide(func() *scopedValidator { return new(scopedValidator) })
+	b.SetScope(ioc.RequestBeanScope)
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+	// 作用域 bean 在刷新时没有创建，不能作为属性校验器
+	p := conf.New()
+	_ = p.Set("a", "b")
+	assert.Nil(t, c.RefreshProperties(p))
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/huazai2008101/stark/ioc/conf"
+)
+
+type serverConfig struct {
+	Host    string        `value:"${host:=}" validate:"required"`
+	Port    int           `value:"${port:=0}" validate:"min=1,max=65535"`
+	Mode    string        `value:"${mode:=dev}" validate:"oneof=dev test prod"`
+	Timeout time.Duration `value:"${timeout:=1s}" validate:"max=10s"`
+	Addr    string        `value:"${addr:=}" validate:"hostport"`
+	URL     string        `value:"${url:=}" validate:"url"`
+	Name    string        `value:"${name:=demo}" validate:"regex=^[a-z]{2,8}$"`
+}
+
+// Validate 属性绑定的结构体通过 Validator 接口校验多个字段之间的关系
+func (c *serverConfig) Validate() error {
+	if c.Mode == "prod" && c.URL == "" {
+		return errors.New("prod 模式必须配置 url")
+	}
+	return nil
+}
+
+// orderService 业务对象上的 Validate 方法不是属性校验，容器不能调用
+type orderService struct {
+	Server serverConfig `value:"${server}"`
+	called bool
+}
+
+func (s *orderService) Validate() error {
+	s.called = true
+	return errors.New("should not be called")
+}
+
+// badRuleConfig validate 标签中包含未知的校验规则
+type badRuleConfig struct {
+	Port int `value:"${port:=8080}" validate:"minimum=1"`
+}
+
+type badRuleService struct {
+	Config badRuleConfig `value:"${server}"`
+}
+
+func TestValidate(t *testing.T) {
+
+	t.Run("bind", func(t *testing.T) {
+		p := conf.New()
+		_ = p.Set("server.port", 70000)
+		_ = p.Set("server.mode", "dev,prod")
+		_ = p.Set("server.timeout", "1m")
+		_ = p.Set("server.addr", "127.0.0.1")
+		_ = p.Set("server.url", "localhost")
+		_ = p.Set("server.name", "Demo")
+		var c serverConfig
+		err := p.Bind(&c, conf.Key("server"))
+		var e *conf.ValidationError
+		assert.True(t, errors.As(err, &e))
+		var rules []string
+		for _, v := range e.Violations {
+			rules = append(rules, v.Key+" "+v.Rule)
+		}
+		assert.Equal(t, rules, []string{
+			"server.host required",
+			"server.port max=65535",
+			"server.mode oneof=dev test prod",
+			"server.timeout max=10s",
+			"server.addr hostport",
+			"server.url url",
+			"server.name regex=^[a-z]{2,8}$",
+		})
+		assert.Error(t, err, `server.port\(serverConfig.Port\) max=65535: 70000 大于 65535`)
+	})
+
+	t.Run("validator", func(t *testing.T) {
+		p := conf.New()
+		_ = p.Set("server.host", "localhost")
+		_ = p.Set("server.port", 8080)
+		_ = p.Set("server.mode", "prod")
+		var c serverConfig
+		err := p.Bind(&c, conf.Key("server"))
+		assert.Error(t, err, `server\(serverConfig\) Validator: prod 模式必须配置 url`)
+
+		_ = p.Set("server.url", "http://localhost:8080")
+		assert.Nil(t, p.Bind(&c, conf.Key("server")))
+	})
+
+	t.Run("bean", func(t *testing.T) {
+		c := ioc.New()
+		c.Property("server.host", "localhost")
+		c.Property("server.port", 0)
+		s := new(orderService)
+		c.Object(s)
+		err := c.Refresh()
+		assert.Error(t, err, `server.port\(orderService.Server.Port\) min=1: 0 小于 1`)
+		assert.False(t, s.called)
+
+		c = ioc.New()
+		c.Property("server.host", "localhost")
+		c.Property("server.port", 8080)
+		c.Property("server.mode", "prod")
+		s = new(orderService)
+		c.Object(s)
+		err = c.Refresh()
+		assert.Error(t, err, "prod 模式必须配置 url")
+		assert.False(t, s.called)
+
+		c = ioc.New()
+		c.Property("server.host", "localhost")
+		c.Property("server.port", 8080)
+		s = new(orderService)
+		c.Object(s)
+		assert.Nil(t, c.Refresh())
+		assert.Equal(t, s.Server.Port, 8080)
+		assert.False(t, s.called)
+	})
+
+	t.Run("source", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "application.properties")
+		assert.Nil(t, ioutil.WriteFile(file, []byte("server.host=localhost\nserver.port=0\n"), 0644))
+
+		p := conf.New()
+		assert.Nil(t, p.Load(file))
+		assert.Equal(t, p.Source("server.port"), file)
+		var c serverConfig
+		err := p.Bind(&c, conf.Key("server"))
+		var e *conf.ValidationError
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Violations[0].Source, file)
+
+		// 应用从配置文件、环境变量加载的属性都记录了来源
+		t.Setenv("STARK_SPRING_CONFIG_LOCATIONS", dir)
+		app := ioc.NewApp()
+		app.Object(new(orderService))
+		err = app.Start()
+		assert.Error(t, err, `server.port\(orderService.Server.Port\) from .*application.properties min=1: 0 小于 1`)
+
+		t.Setenv("STARK_SERVER_PORT", "70000")
+		app = ioc.NewApp()
+		app.Object(new(orderService))
+		err = app.Start()
+		assert.Error(t, err, `server.port\(orderService.Server.Port\) from env max=65535: 70000 大于 65535`)
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		// 未知的校验规则在绑定之前报错，而不是等到属性有值时才发现
+		var c badRuleConfig
+		err := conf.New().Bind(&c)
+		assert.Error(t, err, `badRuleConfig.Port 未知的校验规则 "minimum"`)
+
+		c2 := ioc.New()
+		c2.Object(new(badRuleService))
+		err = c2.Refresh()
+		assert.Error(t, err, `未知的校验规则 "minimum"`)
+	})
+}
@@ -1,17 +1,21 @@
 package grpc
 
 import (
+	"context"
+
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpcRecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/ioc"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 )
 
 type GrpcServer struct {
-	Server   *grpc.Server
-	options  *ServerOptions `autowire:"?"`
-	traceUrl string         `value:"${trace.url:=}"`
+	Server  *grpc.Server
+	options *ServerOptions `autowire:"?"`
+	// 应用运行时状态，提供链路追踪的TracerProvider
+	runtime *stark.Runtime `autowire:""`
 }
 
 func (s *GrpcServer) OnInit(ctx ioc.Context) error {
@@ -20,19 +24,45 @@ func (s *GrpcServer) OnInit(ctx ioc.Context) error {
 		opts = append(opts, s.options.Options...)
 	}
 
-	// 如果链路追踪地址存在则配置链路追踪拦截
-	if s.traceUrl == "" {
-		opts = append(opts, grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(
-			grpcRecovery.UnaryServerInterceptor(),
-		)))
-	} else {
-		opts = append(opts, grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(
-			grpcRecovery.UnaryServerInterceptor(),
-			otelgrpc.UnaryServerInterceptor(),
-		)))
-		opts = append(opts, grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()))
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		requestScopeUnaryInterceptor,
+		grpcRecovery.UnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		requestScopeStreamInterceptor,
 	}
 
+	// 如果启用了链路追踪则配置链路追踪拦截
+	if s.runtime.IsEnableTrace {
+		traceOpts := traceOptions(s.runtime)
+		unaryInterceptors = append(unaryInterceptors, otelgrpc.UnaryServerInterceptor(traceOpts...))
+		streamInterceptors = append(streamInterceptors, otelgrpc.StreamServerInterceptor(traceOpts...))
+	}
+	opts = append(opts, grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(unaryInterceptors...)))
+	opts = append(opts, grpc.StreamInterceptor(grpcMiddleware.ChainStreamServer(streamInterceptors...)))
+
 	s.Server = grpc.NewServer(opts...)
 	return nil
 }
+
+// 每个请求开启一个请求作用域，请求结束时销毁作用域内的bean，没有请求作用域的bean时跳过
+func requestScopeUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !ioc.HasScopedBeans(ioc.RequestBeanScope) {
+		return handler(ctx, req)
+	}
+	ctx, end := ioc.BeginScope(ctx, ioc.RequestBeanScope)
+	defer end()
+	return handler(ctx, req)
+}
+
+// 每个流开启一个请求作用域，流结束时销毁作用域内的bean，没有请求作用域的bean时跳过
+func requestScopeStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !ioc.HasScopedBeans(ioc.RequestBeanScope) {
+		return handler(srv, ss)
+	}
+	ctx, end := ioc.BeginScope(ss.Context(), ioc.RequestBeanScope)
+	defer end()
+	stream := grpcMiddleware.WrapServerStream(ss)
+	stream.WrappedContext = ctx
+	return handler(srv, stream)
+}
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"google.golang.org/grpc"
+)
+
+type requestCounter struct {
+	ID int
+}
+
+type requestHandler struct {
+	Counter func(context.Context) (*requestCounter, error) `autowire:""`
+}
+
+// testServerStream 只提供 Context 方法的 grpc.ServerStream
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRequestScopeInterceptor(t *testing.T) {
+	var (
+		ids []int
+		h   = new(requestHandler)
+	)
+	// use 在请求中获取两次 bean ，同一个请求内只创建一次
+	use := func(ctx context.Context) {
+		r1, err := h.Counter(ctx)
+		assert.Nil(t, err)
+		r2, err := h.Counter(ctx)
+		assert.Nil(t, err)
+		assert.True(t, r1 == r2)
+		ids = append(ids, r1.ID)
+	}
+	unary := func(ctx context.Context) context.Context {
+		var got context.Context
+		_, err := requestScopeUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = ctx
+			if h.Counter != nil {
+				use(ctx)
+			}
+			return nil, nil
+		})
+		assert.Nil(t, err)
+		return got
+	}
+	stream := func(ctx context.Context) context.Context {
+		var got context.Context
+		err := requestScopeStreamInterceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+			got = ss.Context()
+			if h.Counter != nil {
+				use(ss.Context())
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		return got
+	}
+
+	// 没有请求作用域的 bean 时不开启作用域周期
+	ctx := context.Background()
+	assert.True(t, unary(ctx) == ctx)
+	assert.True(t, stream(ctx) == ctx)
+
+	count := 0
+	var destroyed []int
+	c := ioc.New()
+	b := c.Provide(func() *requestCounter {
+		count++
+		return &requestCounter{ID: count}
+	}).Destroy(func(r *requestCounter) {
+		destroyed = append(destroyed, r.ID)
+	})
+	b.SetScope(ioc.RequestBeanScope)
+	c.Object(h)
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+
+	// 每个请求或者流创建一次，结束时销毁
+	unary(ctx)
+	stream(ctx)
+	assert.Equal(t, ids, []int{1, 2})
+	assert.Equal(t, destroyed, []int{1, 2})
+}
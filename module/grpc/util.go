@@ -7,37 +7,50 @@ import (
 	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/base/log"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 )
 
-// 获取grpc链接
+// 获取grpc链接，只能用于默认应用，其他应用实例使用 GetRuntimeGrpcConn
 func GetGrpcConn(ctx context.Context, serviceName string) (context.Context, *grpc.ClientConn, error) {
+	return GetRuntimeGrpcConn(ctx, stark.DefaultRuntime, serviceName)
+}
+
+// 获取grpc链接，使用指定应用的服务发现地址和链路追踪配置
+func GetRuntimeGrpcConn(ctx context.Context, runtime *stark.Runtime, serviceName string) (context.Context, *grpc.ClientConn, error) {
 	opts := []grpc.DialOption{
 		grpc.WithBlock(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`),
 	}
-	if stark.IsEnableTrace {
+	if runtime.IsEnableTrace {
+		traceOpts := traceOptions(runtime)
 		opts = append(opts,
-			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
-			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor(traceOpts...)),
+			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor(traceOpts...)),
 		)
 	}
 
 	// 设置Grpc链路追踪meta信息
 	ctx = setGrpcTraceMeta(ctx)
 
-	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s/%s", stark.DiscoverySchemeUrl, serviceName), opts...)
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s/%s", runtime.DiscoverySchemeUrl, serviceName), opts...)
 	if err != nil {
 		log.Errorf(ctx, "NewGrpcConn %s 新建Grpc连接异常:%+v", serviceName, err)
 	}
 	return ctx, conn, err
 }
 
+// 链路追踪拦截器使用应用自己的TracerProvider
+func traceOptions(runtime *stark.Runtime) []otelgrpc.Option {
+	return []otelgrpc.Option{
+		otelgrpc.WithTracerProvider(runtime.TracerProvider),
+		otelgrpc.WithPropagators(stark.TracePropagator),
+	}
+}
+
 // 设置Grpc链路追踪meta信息
 func setGrpcTraceMeta(ctx context.Context) context.Context {
 	outgoingMd, ok := metadata.FromOutgoingContext(ctx)
@@ -51,7 +64,7 @@ func setGrpcTraceMeta(ctx context.Context) context.Context {
 	}
 
 	mapCarrier := make(propagation.MapCarrier)
-	otel.GetTextMapPropagator().Inject(ctx, mapCarrier)
+	stark.TracePropagator.Inject(ctx, mapCarrier)
 	for k, v := range mapCarrier {
 		outgoingMd.Set(k, v)
 	}
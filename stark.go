@@ -1,6 +1,7 @@
 package stark
 
 import (
+	"github.com/huazai2008101/stark/ioc"
 	"google.golang.org/grpc"
 )
 
@@ -20,9 +21,14 @@ type Application struct {
 	Environment string
 	IsDebug     bool
 	SetupVars   func() error
-	DbConns     []DbConnInfo
+	// 安装用户自定义组件，组件注册到参数 app 上，在 SetupVars 之后调用。
+	// 非默认应用实例必须使用该函数注册组件。
+	Setup   func(app *ioc.App) error
+	DbConns []DbConnInfo
 	// 服务发现配置
 	Discovery *DiscoveryConfig
+	// 配置中心配置
+	ConfigCenter *ConfigCenterConfig
 	// 链路追踪地址
 	TraceUrl string
 }
@@ -36,6 +42,18 @@ type DiscoveryConfig struct {
 	Strategy DiscoveryStrategy
 }
 
+// ConfigCenterConfig 配置中心配置，从配置中心加载的属性会在运行时自动更新
+type ConfigCenterConfig struct {
+	// 配置中心地址，为空时使用服务发现地址
+	Url string
+	// 配置的根路径，默认为config
+	Prefix string
+	// 配置格式，支持yaml、properties、toml以及keys(每个key对应一个属性)，默认为yaml，仅consul使用
+	Format string
+	// 配置中心类型，与服务发现使用相同的取值
+	Strategy DiscoveryStrategy
+}
+
 type ServerConfig struct {
 	// 服务端口号
 	Port int
@@ -47,6 +65,24 @@ type ServerConfig struct {
 	Strategy FrameworkStrategy
 	// 是否启用swagger
 	EnableSwagger bool
+	// 是否在服务端口上关闭pprof
+	DisablePprof bool
+	// 管理服务配置，为空时不启用管理服务
+	Management *ManagementConfig
+}
+
+// ManagementConfig 管理服务配置，管理服务使用独立端口提供pprof、健康检查、指标等端点
+type ManagementConfig struct {
+	// 管理服务端口号
+	Port int
+	// 是否在管理端口上关闭pprof，默认开启
+	DisablePprof bool
+	// basic认证用户名，为空时不进行认证
+	Username string
+	// basic认证密码
+	Password string
+	// 允许访问的IP或网段，为空时不限制
+	AllowIps []string
 }
 
 // WebApplication ...
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/base/log"
 	"github.com/huazai2008101/stark/base/util"
 	"github.com/huazai2008101/stark/ioc"
@@ -17,12 +18,6 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/ucarion/urlpath"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 )
 
 type EchoStarter struct {
@@ -30,8 +25,8 @@ type EchoStarter struct {
 	group       *echo.Group            `autowire:""`
 	routerInits []web.RouteInitializer `autowire:"*?"`
 	name        string                 `value:"${application.name}"`
-	// 链路追踪服务器地址
-	traceUrl    string                `value:"${trace.url:=}"`
+	// 应用运行时状态，提供链路追踪的TracerProvider
+	runtime     *stark.Runtime        `autowire:""`
 	middlewares []echo.MiddlewareFunc `autowire:"*?"`
 	// 不记录日志的路由
 	excludeLogPaths []string `value:"${application.log.excludePath:=}"`
@@ -45,6 +40,9 @@ func (s *EchoStarter) OnAppStart(ctx ioc.Context) {
 	// 设置健康检查
 	s.setHealthCheck()
 
+	// 开启请求作用域
+	s.group.Use(s.requestScope)
+
 	// 传递x-request-id参数
 	s.group.Use(s.setRequestId)
 
@@ -80,6 +78,19 @@ func (s *EchoStarter) setHealthCheck() {
 	})
 }
 
+// 每个请求开启一个请求作用域，请求结束时销毁作用域内的bean，没有请求作用域的bean时跳过
+func (s *EchoStarter) requestScope(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if !ioc.HasScopedBeans(ioc.RequestBeanScope) {
+			return next(ctx)
+		}
+		c, end := ioc.BeginScope(ctx.Request().Context(), ioc.RequestBeanScope)
+		defer end()
+		ctx.SetRequest(ctx.Request().WithContext(c))
+		return next(ctx)
+	}
+}
+
 func (s *EchoStarter) setRequestId(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
 		ctx.SetRequest(ctx.Request().WithContext(web.BuildEchoContext(ctx)))
@@ -167,30 +178,14 @@ func (s *EchoStarter) logHandle() echo.MiddlewareFunc {
 }
 
 func (s *EchoStarter) setTraceProvider() {
-	if s.traceUrl == "" {
+	if !s.runtime.IsEnableTrace {
 		return
 	}
-	ctx := context.Background()
-
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(s.traceUrl)))
-	if err != nil {
-		log.Errorf(ctx, "EchoStarter %s 初始化链路追踪异常:%+v", s.name, err)
-		return
-	}
-
-	batchSpanProcessor := trace.NewBatchSpanProcessor(exp)
-	tracerProvider := trace.NewTracerProvider(
-		trace.WithSpanProcessor(batchSpanProcessor),
-		trace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String("test"),
-		)),
-	)
-	otel.SetTracerProvider(tracerProvider)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	s.group.Use(otelecho.Middleware(s.name))
-	log.Infof(ctx, "EchoStarter %s 已启用链路追踪功能", s.name)
+	s.group.Use(otelecho.Middleware(s.name,
+		otelecho.WithTracerProvider(s.runtime.TracerProvider),
+		otelecho.WithPropagators(stark.TracePropagator),
+	))
+	log.Infof(context.Background(), "EchoStarter %s 已启用链路追踪功能", s.name)
 }
 
 // 允许跨域设置
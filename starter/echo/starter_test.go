@@ -0,0 +1,96 @@
+package echo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huazai2008101/stark"
+	"github.com/huazai2008101/stark/base/assert"
+	"github.com/huazai2008101/stark/ioc"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type requestCounter struct {
+	ID int
+}
+
+type requestHandler struct {
+	Counter func(context.Context) (*requestCounter, error) `autowire:""`
+}
+
+func TestEchoStarter_RequestScope(t *testing.T) {
+	e := echo.New()
+	e.Use((&EchoStarter{}).requestScope)
+
+	var (
+		req  *http.Request
+		same bool
+		ids  []int
+		h    = new(requestHandler)
+	)
+	e.GET("/", func(ctx echo.Context) error {
+		same = ctx.Request() == req
+		if h.Counter == nil {
+			return nil
+		}
+		// 同一个请求内只创建一次
+		r1, err := h.Counter(ctx.Request().Context())
+		assert.Nil(t, err)
+		r2, err := h.Counter(ctx.Request().Context())
+		assert.Nil(t, err)
+		assert.True(t, r1 == r2)
+		ids = append(ids, r1.ID)
+		return nil
+	})
+	serve := func() {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// 没有请求作用域的 bean 时不开启作用域周期
+	serve()
+	assert.True(t, same)
+
+	count := 0
+	var destroyed []int
+	c := ioc.New()
+	b := c.Provide(func() *requestCounter {
+		count++
+		return &requestCounter{ID: count}
+	}).Destroy(func(r *requestCounter) {
+		destroyed = append(destroyed, r.ID)
+	})
+	b.SetScope(ioc.RequestBeanScope)
+	c.Object(h)
+	assert.Nil(t, c.Refresh())
+	defer c.Close()
+
+	// 每个请求创建一次，请求结束时销毁
+	serve()
+	serve()
+	assert.False(t, same)
+	assert.Equal(t, ids, []int{1, 2})
+	assert.Equal(t, destroyed, []int{1, 2})
+}
+
+func TestEchoStarter_TraceProvider(t *testing.T) {
+	e := echo.New()
+	recorder := tracetest.NewSpanRecorder()
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	runtime := &stark.Runtime{}
+	runtime.EnableTrace(provider)
+
+	s := &EchoStarter{echo: e, group: e.Group(""), name: "order", runtime: runtime}
+	s.setTraceProvider()
+	s.group.GET("/", func(ctx echo.Context) error { return nil })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// 使用应用自己的 TracerProvider，不修改 otel 的全局设置
+	assert.Equal(t, len(recorder.Ended()), 1)
+	assert.True(t, otel.GetTracerProvider() != provider)
+}
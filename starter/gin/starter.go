@@ -11,18 +11,13 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/huazai2008101/stark"
 	"github.com/huazai2008101/stark/base/log"
 	"github.com/huazai2008101/stark/base/util"
 	"github.com/huazai2008101/stark/ioc"
 	"github.com/huazai2008101/stark/module/web"
 	"github.com/ucarion/urlpath"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 )
 
 type GinStarter struct {
@@ -30,8 +25,8 @@ type GinStarter struct {
 	group       *gin.RouterGroup       `autowire:""`
 	routerInits []web.RouteInitializer `autowire:"*?"`
 	name        string                 `value:"${application.name}"`
-	// 链路追踪服务器地址
-	traceUrl string `value:"${trace.url:=}"`
+	// 应用运行时状态，提供链路追踪的TracerProvider
+	runtime *stark.Runtime `autowire:""`
 	// 用户自定义中间件
 	middlewares []gin.HandlerFunc `autowire:"*?"`
 	// 不记录日志的路由
@@ -45,6 +40,9 @@ func NewGinStarter() ioc.AppEvent {
 func (s *GinStarter) OnAppStart(ctx ioc.Context) {
 	gin.SetMode(gin.ReleaseMode)
 
+	// 开启请求作用域
+	s.group.Use(s.requestScope)
+
 	// 传递x-request-id参数
 	s.group.Use(s.setRequestId)
 
@@ -74,6 +72,18 @@ func (s *GinStarter) OnAppStart(ctx ioc.Context) {
 	}
 }
 
+// 每个请求开启一个请求作用域，请求结束时销毁作用域内的bean，没有请求作用域的bean时跳过
+func (s *GinStarter) requestScope(c *gin.Context) {
+	if !ioc.HasScopedBeans(ioc.RequestBeanScope) {
+		c.Next()
+		return
+	}
+	ctx, end := ioc.BeginScope(c.Request.Context(), ioc.RequestBeanScope)
+	defer end()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
 func (s *GinStarter) setRequestId(c *gin.Context) {
 	c.Request = c.Request.WithContext(web.BuildGinContext(c))
 }
@@ -163,30 +173,14 @@ func (s *GinStarter) setHealthCheck() {
 }
 
 func (s *GinStarter) setTraceProvider() {
-	if s.traceUrl == "" {
+	if !s.runtime.IsEnableTrace {
 		return
 	}
-	ctx := context.Background()
-
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(s.traceUrl)))
-	if err != nil {
-		log.Errorf(ctx, "GinStarter %s 初始化链路追踪异常:%+v", s.name, err)
-		return
-	}
-
-	batchSpanProcessor := trace.NewBatchSpanProcessor(exp)
-	tracerProvider := trace.NewTracerProvider(
-		trace.WithSpanProcessor(batchSpanProcessor),
-		trace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String("test"),
-		)),
-	)
-	otel.SetTracerProvider(tracerProvider)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	s.group.Use(otelgin.Middleware(s.name))
-	log.Infof(ctx, "GinStarter %s 已启用链路追踪功能", s.name)
+	s.group.Use(otelgin.Middleware(s.name,
+		otelgin.WithTracerProvider(s.runtime.TracerProvider),
+		otelgin.WithPropagators(stark.TracePropagator),
+	))
+	log.Infof(context.Background(), "GinStarter %s 已启用链路追踪功能", s.name)
 }
 
 // 允许跨域设置
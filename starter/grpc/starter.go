@@ -2,7 +2,9 @@ package grpc
 
 import (
 	"context"
+	"sync/atomic"
 
+	"github.com/huazai2008101/stark/base/log"
 	"github.com/huazai2008101/stark/discovery"
 	"github.com/huazai2008101/stark/ioc"
 	grpcModule "github.com/huazai2008101/stark/module/grpc"
@@ -11,9 +13,13 @@ import (
 	"google.golang.org/grpc/resolver"
 )
 
+// GrpcStarter grpc服务的启动器。grpc服务与http服务共用同一个端口，由http启动器在
+// OnAppStart 中开始监听并分发grpc请求，因此只有停止是分阶段的：Start 只标记运行状态，
+// Stop 在停止阶段优雅停止grpc服务
 type GrpcStarter struct {
 	server    *grpcModule.GrpcServer     `autowire:""`
 	discovery discovery.ServiceDiscovery `autowire:"?"`
+	running   int32
 }
 
 func NewGrpcStarter() ioc.AppEvent {
@@ -29,6 +35,37 @@ func (s *GrpcStarter) OnAppStart(ctx ioc.Context) {
 	reflection.Register(s.server.Server)
 }
 
-func (s *GrpcStarter) OnAppStop(ctx context.Context) {
-	s.server.Server.GracefulStop()
+// 服务在 Stop 中停止
+func (s *GrpcStarter) OnAppStop(ctx context.Context) {}
+
+func (s *GrpcStarter) Phase() int {
+	return 0
+}
+
+// 只标记运行状态，请求由http启动器监听的端口转发给grpc服务
+func (s *GrpcStarter) Start(ctx context.Context) error {
+	atomic.StoreInt32(&s.running, 1)
+	return nil
+}
+
+// 优雅停止服务，超时后强制停止
+func (s *GrpcStarter) Stop(ctx context.Context) error {
+	defer atomic.StoreInt32(&s.running, 0)
+	done := make(chan struct{})
+	go func() {
+		s.server.Server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Warnf(ctx, "grpc服务优雅停止超时，强制停止")
+		s.server.Server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *GrpcStarter) IsRunning() bool {
+	return atomic.LoadInt32(&s.running) == 1
 }
@@ -1,5 +1,11 @@
 package stark
 
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
 // 数据库类型
 type DbType int32
 
@@ -53,10 +59,58 @@ const (
 )
 
 // WebInstance is *WebApplication instance May be nil
+// 只反映默认应用的状态，参考 Runtime 的解释。
 var WebInstance *WebApplication
 
+// 以下全局变量只反映默认应用的状态，参考 Runtime 的解释。
 var (
 	DiscoverySchemeUrl string
 	// 是否启用链路追踪功能
 	IsEnableTrace bool
 )
+
+// Runtime 应用运行时的框架状态，每个应用实例持有一份并注册为该应用的 bean ，
+// 一个进程中运行多个应用时应当注入 *Runtime 而不是读取全局变量。
+type Runtime struct {
+	// web应用配置，非web应用时为nil
+	WebInstance *WebApplication
+	// 服务发现地址，注册服务后设置
+	DiscoverySchemeUrl string
+	// 是否启用链路追踪功能
+	IsEnableTrace bool
+	// 链路追踪的TracerProvider，启用链路追踪后设置
+	TracerProvider trace.TracerProvider
+}
+
+// DefaultRuntime 默认应用的框架状态，通过 Set 方法设置时同步更新对应的全局变量。
+var DefaultRuntime = &Runtime{}
+
+// SetWebInstance 设置web应用配置
+func (r *Runtime) SetWebInstance(application *WebApplication) {
+	r.WebInstance = application
+	if r == DefaultRuntime {
+		WebInstance = application
+	}
+}
+
+// SetDiscoverySchemeUrl 设置服务发现地址
+func (r *Runtime) SetDiscoverySchemeUrl(url string) {
+	r.DiscoverySchemeUrl = url
+	if r == DefaultRuntime {
+		DiscoverySchemeUrl = url
+	}
+}
+
+// TracePropagator 链路追踪信息在进程间传递使用的格式
+var TracePropagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// EnableTrace 启用链路追踪功能，默认应用同时将 provider 设置为 otel 的全局 TracerProvider
+func (r *Runtime) EnableTrace(provider trace.TracerProvider) {
+	r.IsEnableTrace = true
+	r.TracerProvider = provider
+	if r == DefaultRuntime {
+		IsEnableTrace = true
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(TracePropagator)
+	}
+}